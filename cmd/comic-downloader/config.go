@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+
+	"github.com/NorkzYT/comic-downloader/internal/browserless"
+	"github.com/NorkzYT/comic-downloader/internal/config"
+	"github.com/NorkzYT/comic-downloader/internal/http"
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// configPath is the configuration file path (empty for the default path).
+	configPath string
+	// profile is the configuration profile to use (empty for the file default).
+	profile string
+	// browser holds the browser flags, overriding the configuration file and the environment.
+	browser browserless.Config
+	// browserHeadless is the --browser-headless flag.
+	browserHeadless bool
+)
+
+// loadConfig loads the configuration file and applies it to the command flags and to the
+// browserless and http packages, for the given comic URL. Flags set on the command line
+// take precedence over environment variables, which take precedence over the file.
+// The resolved section is returned for the settings that are not flags, such as hooks.
+func loadConfig(cmd *cobra.Command, url string) (config.Section, error) {
+	section, err := resolveConfig(cmd, url)
+	if err != nil {
+		return section, err
+	}
+	return section, section.ApplyFlags(cmd.Flags())
+}
+
+// resolveConfig loads the configuration file, applies it to the browserless and http packages
+// and returns the settings resolved for the given comic URL.
+func resolveConfig(cmd *cobra.Command, url string) (config.Section, error) {
+	if !cmd.Flags().Changed("config") {
+		configPath = os.Getenv(config.EnvName("config"))
+	}
+	if !cmd.Flags().Changed("profile") {
+		profile = os.Getenv(config.EnvName("profile"))
+	}
+
+	f, err := config.Load(configPath)
+	if err != nil {
+		return config.Section{}, err
+	}
+	section, err := f.Resolve(profile, url)
+	if err != nil {
+		return section, err
+	}
+	logger.Debug("resolveConfig: Resolved settings for %s: %v", url, section.Flags)
+
+	bc, err := browserConfig(cmd, section)
+	if err != nil {
+		return section, err
+	}
+	browserless.Configure(bc)
+
+	hc, err := section.HTTPConfig()
+	if err != nil {
+		return section, err
+	}
+	http.Configure(hc)
+	return section, nil
+}
+
+// browserConfig returns the browser settings of the section, overridden by the browser flags
+// set on the command line.
+func browserConfig(cmd *cobra.Command, section config.Section) (browserless.Config, error) {
+	bc, err := section.BrowserlessConfig()
+	if err != nil {
+		return bc, err
+	}
+	flags := cmd.Flags()
+	if flags.Changed("browser") {
+		bc.Browser = browser.Browser
+		if err := bc.Validate(); err != nil {
+			return bc, err
+		}
+	}
+	if flags.Changed("browser-path") {
+		bc.Local.Path = browser.Local.Path
+	}
+	if flags.Changed("browser-headless") {
+		bc.Local.Headful = !browserHeadless
+	}
+	if flags.Changed("browser-user-data-dir") {
+		bc.Local.UserDataDir = browser.Local.UserDataDir
+	}
+	if flags.Changed("browser-flag") {
+		bc.Local.Flags = browser.Local.Flags
+	}
+	return bc, nil
+}
+
+// solveChallenge is the http.Solver solving the anti-bot challenges in the browser.
+func solveChallenge(url string) (http.Clearance, error) {
+	userAgent, cookies, err := browserless.SolveChallenge(url)
+	return http.Clearance{UserAgent: userAgent, Cookies: cookies}, err
+}
+
+func init() {
+	http.SetSolver(solveChallenge)
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "configuration file (default "+config.DefaultPath()+")")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "configuration profile to use")
+	rootCmd.PersistentFlags().StringVar(&browser.Browser, "browser", browserless.BrowserRemote, "browser running the scraping scripts: remote (Browserless) or local (Chrome or Chromium installed locally)")
+	rootCmd.PersistentFlags().StringVar(&browser.Local.Path, "browser-path", "", "local browser executable (default: found in PATH)")
+	rootCmd.PersistentFlags().BoolVar(&browserHeadless, "browser-headless", true, "run the local browser without a window")
+	rootCmd.PersistentFlags().StringVar(&browser.Local.UserDataDir, "browser-user-data-dir", "", "local browser profile directory, keeping cookies between runs (default: a temporary directory)")
+	rootCmd.PersistentFlags().StringSliceVar(&browser.Local.Flags, "browser-flag", nil, "extra local browser command line switch, such as no-sandbox or proxy-server=host:port (repeatable)")
+}
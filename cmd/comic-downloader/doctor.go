@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/browserless"
+	"github.com/NorkzYT/comic-downloader/internal/doctor"
+	"github.com/NorkzYT/comic-downloader/internal/grabber"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// doctorSettings are the settings of the checked sites.
+	doctorSettings grabber.Settings
+	// doctorJSON prints the reports as JSON.
+	doctorJSON bool
+)
+
+var doctorCmd = &cobra.Command{
+	Use:     "doctor [url...]",
+	Aliases: []string{"check-site"},
+	Short:   "Checks that the supported sites still work",
+	Long: `Checks that the supported sites still work by fetching the title, the chapter list and the
+pages of the first chapter of a known comic of each site (or of the given comic URLs), then
+downloading its first page. For each failure, it reports the failed step with the CSS selector
+or API endpoint it depends on, to tell what changed on the site.`,
+	Example: `  comic-downloader doctor
+  comic-downloader check-site https://mangamonk.com/infinite-mage --json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		section, err := resolveConfig(cmd, "")
+		cerr(err, "Error loading configuration: ")
+		cerr(section.Known(cmd.Flags()).ApplyFlags(cmd.Flags()), "Error loading configuration: ")
+
+		urls := args
+		if len(urls) == 0 {
+			urls = grabber.Canaries
+		}
+		reports := []doctor.Report{}
+		failed := 0
+		for _, u := range urls {
+			r := checkSite(cmd, u)
+			if !r.OK() {
+				failed++
+			}
+			reports = append(reports, r)
+		}
+		browserless.Close()
+		grabber.FlushReports()
+
+		if doctorJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			cerr(enc.Encode(reports), "Error encoding reports: ")
+		} else {
+			fmt.Printf("\n%d of %d sites working\n", len(reports)-failed, len(reports))
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// checkSite checks the site of the comic URL, printing the steps as they complete unless
+// the output is JSON.
+func checkSite(cmd *cobra.Command, u string) doctor.Report {
+	s := doctorSettings
+	site, errs := grabber.NewSite(u, &s)
+	if site == nil {
+		msg := "site not recognised"
+		if len(errs) > 0 {
+			msg = fmt.Sprintf("%s: %v", msg, errs)
+		}
+		r := doctor.Report{URL: u, Site: u, Steps: []doctor.Step{{Name: "site", Error: msg}}}
+		if !doctorJSON {
+			fmt.Printf("%s %s\n", color.RedString("✗"), u)
+			printStep(r.Steps[0])
+		}
+		return r
+	}
+	site.InitFlags(cmd)
+
+	if !doctorJSON {
+		fmt.Println(color.HiBlueString(u))
+	}
+	return doctor.Check(site, u, func(step doctor.Step) {
+		if !doctorJSON {
+			printStep(step)
+		}
+	})
+}
+
+// printStep prints the outcome of a check step.
+func printStep(step doctor.Step) {
+	if step.OK() {
+		fmt.Printf("  %s %-8s %s %s\n", color.GreenString("✓"), step.Name, step.Result, color.HiBlackString("(%s)", step.Duration.Round(10*time.Millisecond)))
+		return
+	}
+	fmt.Printf("  %s %-8s %s\n", color.RedString("✗"), step.Name, color.RedString(step.Error))
+	if step.Selector != "" {
+		fmt.Printf("    %s %s\n", color.YellowString("depends on:"), step.Selector)
+	}
+}
+
+func init() {
+	bindSettingsFlags(doctorCmd.Flags(), &doctorSettings)
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "print the reports as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/NorkzYT/comic-downloader/internal/hooks"
+)
+
+var (
+	// onChapter is a shell command run when a chapter file is written.
+	onChapter string
+	// onSeries is a shell command run when the download completes.
+	onSeries string
+	// onFailure is a shell command run when a chapter or the download fails.
+	onFailure string
+	// webhooks are URLs every event is POSTed to.
+	webhooks []string
+)
+
+// flagHooks returns the hooks set with the command flags.
+func flagHooks() []hooks.Hook {
+	var hs []hooks.Hook
+	for _, h := range []struct{ typ, command string }{
+		{hooks.EventChapter, onChapter},
+		{hooks.EventSeries, onSeries},
+		{hooks.EventFailure, onFailure},
+	} {
+		if h.command != "" {
+			hs = append(hs, hooks.Hook{On: []string{h.typ}, Command: h.command})
+		}
+	}
+	for _, url := range webhooks {
+		hs = append(hs, hooks.Hook{Webhook: url})
+	}
+	return hs
+}
+
+func init() {
+	rootCmd.Flags().StringVar(&onChapter, "on-chapter", "", "shell command run when a chapter file is written, with COMIC_* environment variables")
+	rootCmd.Flags().StringVar(&onSeries, "on-series", "", "shell command run when the download completes, with COMIC_* environment variables")
+	rootCmd.Flags().StringVar(&onFailure, "on-failure", "", "shell command run when a chapter or the download fails, with COMIC_* environment variables")
+	rootCmd.Flags().StringSliceVar(&webhooks, "webhook", nil, "URL every download event is POSTed to as JSON")
+}
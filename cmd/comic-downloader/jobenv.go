@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/NorkzYT/comic-downloader/internal/browserless"
+	"github.com/NorkzYT/comic-downloader/internal/http"
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+	"github.com/NorkzYT/comic-downloader/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// jobEnv applies the browser and HTTP settings of the server jobs. Those settings are global
+// to the process: jobs with the settings in use run concurrently, while a job with other
+// settings (say, a per-domain User-Agent) waits for the running ones to finish before
+// applying its own.
+type jobEnv struct {
+	// cmd is the serve command, whose browser flags override the settings of every job
+	cmd *cobra.Command
+
+	mu      sync.Mutex
+	browser browserless.Config
+	http    http.Config
+	// running is the number of jobs using the current settings
+	running int
+	// idle is closed when running drops to 0
+	idle chan struct{}
+}
+
+// jobEnvironment is the environment of the server jobs.
+var jobEnvironment = &jobEnv{idle: make(chan struct{})}
+
+// acquire waits until the settings of req can be applied, applies them and returns the
+// function to call once the job no longer makes requests.
+func (e *jobEnv) acquire(ctx context.Context, req server.Request) (release func(), err error) {
+	section, err := jobConfig(req)
+	if err != nil {
+		return nil, err
+	}
+	bc, err := browserConfig(e.cmd, section)
+	if err != nil {
+		return nil, err
+	}
+	hc, err := section.HTTPConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		e.mu.Lock()
+		if e.running == 0 || (reflect.DeepEqual(e.browser, bc) && e.http == hc) {
+			if e.running == 0 {
+				browserless.Configure(bc)
+				http.Configure(hc)
+				e.browser, e.http = bc, hc
+			}
+			e.running++
+			e.mu.Unlock()
+			var once sync.Once
+			return func() { once.Do(e.release) }, nil
+		}
+		idle := e.idle
+		e.mu.Unlock()
+
+		logger.Debug("jobEnv.acquire: Waiting for the running jobs to apply the settings of %s", req.URL)
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release ends a job using the current settings.
+func (e *jobEnv) release() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.running--
+	if e.running == 0 {
+		close(e.idle)
+		e.idle = make(chan struct{})
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/NorkzYT/comic-downloader/internal/library"
+	"github.com/NorkzYT/comic-downloader/internal/server"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// libraryPath is the library database path (empty disables the library).
+	libraryPath string
+	// pruneOrphans removes the records of missing files.
+	pruneOrphans bool
+	// remoteMissing compares the library with the chapters available on the site.
+	remoteMissing bool
+)
+
+// openLibrary returns the library, or nil when it is disabled.
+func openLibrary() *library.Library {
+	if libraryPath == "" {
+		return nil
+	}
+	return library.New(libraryPath)
+}
+
+var libraryCmd = &cobra.Command{
+	Use:   "library",
+	Short: "Lists and checks the downloaded series and chapters",
+	Long: `Every downloaded chapter is recorded in the library database, with its source URL, site,
+title, number, language, output path, page count, checksum and download time.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		section, err := resolveConfig(cmd, "")
+		cerr(err, "Error loading configuration: ")
+		cerr(section.Known(cmd.Flags()).ApplyFlags(cmd.Flags()), "Error loading configuration: ")
+	},
+}
+
+var libraryListCmd = &cobra.Command{
+	Use:   "list [series url]",
+	Short: "Lists the downloaded series, or the chapters of a series",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		lib := requireLibrary()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		defer w.Flush()
+
+		if len(args) == 0 {
+			series, err := lib.Series()
+			cerr(err, "Error reading library: ")
+			chapters, err := lib.Chapters("")
+			cerr(err, "Error reading library: ")
+			counts := map[string]int{}
+			for _, c := range chapters {
+				counts[c.SeriesURL]++
+			}
+			fmt.Fprintln(w, "TITLE\tSITE\tCHAPTERS\tUPDATED\tURL")
+			for _, s := range series {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", s.Title, s.Site, counts[s.URL], s.Updated.Format("2006-01-02"), s.URL)
+			}
+			return
+		}
+
+		chapters, err := lib.Chapters(args[0])
+		cerr(err, "Error reading library: ")
+		printChapters(w, chapters, nil)
+	},
+}
+
+var librarySearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Searches the series and chapter titles",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		lib := requireLibrary()
+		chapters, err := lib.Search(args[0])
+		cerr(err, "Error reading library: ")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		defer w.Flush()
+		printChapters(w, chapters, seriesTitles(lib))
+	},
+}
+
+var libraryStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Shows library statistics",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		lib := requireLibrary()
+		series, err := lib.Series()
+		cerr(err, "Error reading library: ")
+		chapters, err := lib.Chapters("")
+		cerr(err, "Error reading library: ")
+
+		var pages, size int64
+		sizes := map[string]bool{}
+		sites := map[string]int{}
+		for _, s := range series {
+			sites[s.Site]++
+		}
+		for _, c := range chapters {
+			pages += c.Pages
+			if !sizes[c.Path] {
+				sizes[c.Path] = true
+				size += diskSize(c.Path)
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		defer w.Flush()
+		fmt.Fprintf(w, "Library\t%s\n", lib.Path())
+		fmt.Fprintf(w, "Series\t%d\n", len(series))
+		fmt.Fprintf(w, "Chapters\t%d\n", len(chapters))
+		fmt.Fprintf(w, "Pages\t%d\n", pages)
+		fmt.Fprintf(w, "Files\t%d\n", len(sizes))
+		fmt.Fprintf(w, "Size on disk\t%.1f MiB\n", float64(size)/(1<<20))
+		names := make([]string, 0, len(sites))
+		for site := range sites {
+			names = append(names, site)
+		}
+		sort.Strings(names)
+		for _, site := range names {
+			fmt.Fprintf(w, "  %s\t%d series\n", site, sites[site])
+		}
+	},
+}
+
+var libraryOrphansCmd = &cobra.Command{
+	Use:   "orphans",
+	Short: "Finds records whose files are gone and files of --output-dir missing from the library",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		lib := requireLibrary()
+		chapters, err := lib.Chapters("")
+		cerr(err, "Error reading library: ")
+
+		recorded := map[string]bool{}
+		gone := []library.Chapter{}
+		for _, c := range chapters {
+			abs, _ := filepath.Abs(c.Path)
+			recorded[abs] = true
+			if _, err := os.Stat(c.Path); os.IsNotExist(err) {
+				gone = append(gone, c)
+			}
+		}
+
+		for _, c := range gone {
+			fmt.Printf("%s %s (chapter %g)\n", color.RedString("missing file:"), c.Path, c.Number)
+		}
+		err = filepath.WalkDir(settings.OutputDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !isOutput(path, d) {
+				return err
+			}
+			if abs, _ := filepath.Abs(path); !recorded[abs] {
+				fmt.Printf("%s %s\n", color.YellowString("untracked:"), path)
+			}
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		cerr(err, "Error reading output directory: ")
+
+		if pruneOrphans && len(gone) > 0 {
+			cerr(lib.Delete(gone), "Error pruning library: ")
+			fmt.Printf("Removed %d records\n", len(gone))
+		}
+	},
+}
+
+var libraryMissingCmd = &cobra.Command{
+	Use:   "missing [series url]",
+	Short: "Finds gaps in the downloaded chapters of every series, or of the given series",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		lib := requireLibrary()
+		series, err := lib.Series()
+		cerr(err, "Error reading library: ")
+		if len(args) == 1 {
+			series = []library.Series{{URL: args[0], Title: args[0]}}
+		}
+		jobEnvironment.cmd = cmd
+
+		for _, s := range series {
+			chapters, err := lib.Chapters(s.URL)
+			cerr(err, "Error reading library: ")
+			have := map[float64]bool{}
+			for _, c := range chapters {
+				have[c.Number] = true
+			}
+
+			var missing []string
+			if remoteMissing {
+				available, err := listChapters(context.Background(), server.Request{URL: s.URL})
+				if err != nil {
+					fmt.Printf("%s: %s\n", s.Title, color.RedString(err.Error()))
+					continue
+				}
+				seen := map[float64]bool{}
+				for _, c := range available.Chapters {
+					if !have[c.Number] && !seen[c.Number] {
+						seen[c.Number] = true
+						missing = append(missing, strconv.FormatFloat(c.Number, 'f', -1, 64))
+					}
+				}
+			} else {
+				missing = gaps(have)
+			}
+			if len(missing) > 0 {
+				fmt.Printf("%s: %s\n", s.Title, strings.Join(missing, ", "))
+			}
+		}
+	},
+}
+
+// requireLibrary returns the library, exiting when it is disabled.
+func requireLibrary() *library.Library {
+	lib := openLibrary()
+	if lib == nil {
+		cerr(fmt.Errorf("the library is disabled"), "Error: ")
+	}
+	return lib
+}
+
+// printChapters prints a chapter table, with the series title when titles is set.
+func printChapters(w *tabwriter.Writer, chapters []library.Chapter, titles map[string]string) {
+	if titles != nil {
+		fmt.Fprint(w, "SERIES\t")
+	}
+	fmt.Fprintln(w, "NUMBER\tTITLE\tLANGUAGE\tPAGES\tDOWNLOADED\tPATH")
+	for _, c := range chapters {
+		if titles != nil {
+			fmt.Fprintf(w, "%s\t", titles[c.SeriesURL])
+		}
+		fmt.Fprintf(w, "%g\t%s\t%s\t%d\t%s\t%s\n", c.Number, c.Title, c.Language, c.Pages, c.Downloaded.Format("2006-01-02"), c.Path)
+	}
+}
+
+// seriesTitles returns the series titles by URL.
+func seriesTitles(lib *library.Library) map[string]string {
+	series, err := lib.Series()
+	cerr(err, "Error reading library: ")
+	titles := map[string]string{}
+	for _, s := range series {
+		titles[s.URL] = s.Title
+	}
+	return titles
+}
+
+// gaps returns the whole chapter numbers missing between the lowest and highest downloaded chapters.
+func gaps(have map[float64]bool) []string {
+	if len(have) == 0 {
+		return nil
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for n := range have {
+		lo, hi = math.Min(lo, n), math.Max(hi, n)
+	}
+	missing := []string{}
+	for n := math.Ceil(lo); n < hi; n++ {
+		if !have[n] {
+			missing = append(missing, strconv.FormatFloat(n, 'f', -1, 64))
+		}
+	}
+	return missing
+}
+
+// isOutput reports whether path looks like a file written by comic-downloader.
+func isOutput(path string, d fs.DirEntry) bool {
+	if d.IsDir() {
+		return strings.HasSuffix(path, "_raw") || strings.HasSuffix(path, "_bundle")
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cbz", ".zip", ".epub":
+		return true
+	}
+	return false
+}
+
+// diskSize returns the size of a file or folder, or 0 if it is gone.
+func diskSize(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&libraryPath, "library", library.DefaultPath(), "library database recording the downloads (empty to disable)")
+	libraryOrphansCmd.Flags().BoolVar(&pruneOrphans, "prune", false, "remove the records of missing files")
+	libraryMissingCmd.Flags().BoolVar(&remoteMissing, "remote", false, "list the chapters available on the site but not downloaded instead of the gaps")
+	libraryCmd.AddCommand(libraryListCmd, librarySearchCmd, libraryStatsCmd, libraryOrphansCmd, libraryMissingCmd)
+	rootCmd.AddCommand(libraryCmd)
+}
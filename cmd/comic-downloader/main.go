@@ -1,31 +1,38 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
-	"sort"
 	"strings"
-	"sync"
-	"syscall"
-	"time"
 
-	"github.com/NorkzYT/comic-downloader/internal/downloader"
+	"github.com/NorkzYT/comic-downloader/internal/browserless"
 	"github.com/NorkzYT/comic-downloader/internal/grabber"
+	"github.com/NorkzYT/comic-downloader/internal/hooks"
 	"github.com/NorkzYT/comic-downloader/internal/logger"
 	"github.com/NorkzYT/comic-downloader/internal/packer"
+	"github.com/NorkzYT/comic-downloader/internal/pipeline"
 	"github.com/NorkzYT/comic-downloader/internal/ranges"
+	"github.com/NorkzYT/comic-downloader/internal/reporter"
 	"github.com/fatih/color"
-	"github.com/jedib0t/go-pretty/v6/progress"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
-	"golang.org/x/term"
+	"github.com/spf13/pflag"
 
 	cc "github.com/ivanpirog/coloredcobra"
 )
 
 var settings grabber.Settings
 
+var (
+	// assumeYes skips every confirmation prompt.
+	assumeYes bool
+	// progressMode selects how progress is reported (see reporter.New).
+	progressMode string
+)
+
 type BrowserlessUser interface {
 	UsesBrowser() bool
 }
@@ -59,6 +66,12 @@ Note the URL must be of the index of the comic, not a single chapter.`,
 
   comic-downloader --language en --bundle --format zip https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece 1-2
     -> Downloads, bundles, and archives the chapters in ZIP format.
+
+  comic-downloader https://mangamonk.com/infinite-mage 10-,!12
+    -> Downloads every chapter from 10 onwards except chapter 12.
+
+  comic-downloader https://mangamonk.com/infinite-mage -5
+    -> Downloads the last five chapters.
   
 Note: Arguments are not positional; you may specify them in any order:
   comic-downloader --language en 1-2 https://mangamonk.com/infinite-mage --bundle --format raw
@@ -70,6 +83,10 @@ Note: Arguments are not positional; you may specify them in any order:
 
 func run(cmd *cobra.Command, args []string) {
 	logger.Debug("rootCmd.Run: Starting execution with args: %v", args)
+	section, err := loadConfig(cmd, getUrlArg(args))
+	cerr(err, "Error loading configuration: ")
+	runner, err := hooks.NewRunner(append(section.Hooks, flagHooks()...))
+	cerr(err, "Error loading configuration: ")
 	s, errs := grabber.NewSite(getUrlArg(args), &settings)
 	if len(errs) > 0 {
 		logger.Error("rootCmd.Run: Errors testing site:")
@@ -79,13 +96,15 @@ func run(cmd *cobra.Command, args []string) {
 	}
 	if s == nil {
 		logger.Info("rootCmd.Run: Site not recognised")
-		fmt.Println(color.YellowString("Site not recognised"))
+		fmt.Fprintln(statusOut(), color.YellowString("Site not recognised"))
 		os.Exit(1)
 	}
 	s.InitFlags(cmd)
 
+	cerr(pipeline.Validate(&settings), "Error parsing ")
+
 	if bl, ok := s.(BrowserlessUser); ok && bl.UsesBrowser() {
-		fmt.Println("Initializing remote browser; please wait...")
+		fmt.Fprintln(statusOut(), "Initializing browser; please wait...")
 	}
 
 	title, err := s.FetchTitle()
@@ -104,14 +123,9 @@ func run(cmd *cobra.Command, args []string) {
 	var rngs []ranges.Range
 	if len(args) == 1 {
 		lastChapter := chapters[len(chapters)-1].GetNumber()
-		prompt := promptui.Prompt{
-			Label:     fmt.Sprintf("Do you want to download all %g chapters", lastChapter),
-			IsConfirm: true,
-		}
-		_, err := prompt.Run()
-		if err != nil {
+		if !confirmDownloadAll(lastChapter) {
 			logger.Info("rootCmd.Run: Download canceled by user")
-			fmt.Println(color.YellowString("Canceled by user"))
+			fmt.Fprintln(statusOut(), color.YellowString("Canceled by user"))
 			os.Exit(0)
 		}
 		rngs = []ranges.Range{{Begin: 1.0, End: lastChapter}}
@@ -120,161 +134,51 @@ func run(cmd *cobra.Command, args []string) {
 		rngs, err = ranges.Parse(settings.Range)
 		cerr(err, "Error parsing ranges: ")
 	}
-	chapters = chapters.FilterRanges(rngs)
+	chapters = chapters.FilterRanges(rngs).SelectCandidates(settings.SelectionPolicy())
+	lib := openLibrary()
+	if settings.SkipDownloaded && lib != nil {
+		chapters, err = lib.Filter(getUrlArg(args), chapters)
+		cerr(err, "Error reading library: ")
+	}
 	if err := os.MkdirAll(settings.OutputDir, 0755); err != nil {
 		logger.Error("rootCmd.Run: Error creating output directory: %v", err)
-		fmt.Println(color.RedString("Error creating output directory: " + err.Error()))
+		fmt.Fprintln(statusOut(), color.RedString("Error creating output directory: "+err.Error()))
 		os.Exit(1)
 	}
 	if len(chapters) == 0 {
 		logger.Info("rootCmd.Run: No chapters found for the specified ranges")
-		fmt.Println(color.YellowString("No chapters found for the specified ranges"))
+		fmt.Fprintln(statusOut(), color.YellowString("No chapters found for the specified ranges"))
 		os.Exit(1)
 	}
 
-	pw := progress.NewWriter()
-	pw.SetAutoStop(false)
-	pw.SetUpdateFrequency(100 * time.Millisecond)
-	pw.SetStyle(progress.StyleBlocks)
-	pw.Style().Colors = progress.StyleColorsExample
-	pw.Style().Visibility.ETA = true
-	pw.Style().Visibility.ETAOverall = true
-	pw.Style().Visibility.Percentage = true
-	pw.Style().Visibility.Speed = true
-	pw.Style().Visibility.SpeedOverall = true
-	pw.Style().Visibility.Time = true
-	pw.Style().Visibility.Tracker = true
-	pw.Style().Visibility.TrackerOverall = true
-	pw.Style().Visibility.Value = true
-
-	pw.SetSortBy(progress.SortByMessage)
-
-	go pw.Render()
-
-	wg := sync.WaitGroup{}
-	guard := make(chan struct{}, s.GetMaxConcurrency().Chapters)
-	termWidth := getTerminalWidth()
-	comicLen, chapterLen := calculateTitleLengths(termWidth)
-
-	trackers := make([]*progress.Tracker, len(chapters))
-	for i, chap := range chapters {
-		barTitle := fmt.Sprintf("%s - %s", truncateString(title, comicLen), truncateString(chap.GetTitle(), chapterLen))
-		tracker := &progress.Tracker{
-			Message:            barTitle + " [Fetching]",
-			Total:              80,
-			RemoveOnCompletion: false,
-		}
-		trackers[i] = tracker
-		pw.AppendTracker(tracker)
-	}
-
-	var mu sync.Mutex
-	var bundledChapters []*packer.DownloadedChapter
-
-	for i, chap := range chapters {
-		guard <- struct{}{}
-		wg.Add(1)
-		go func(chap grabber.Filterable, tracker *progress.Tracker, barTitle string) {
-			defer wg.Done()
-			var chapter *grabber.Chapter
-			var err error
-			if fetcher, ok := s.(interface {
-				FetchChapterWithProgress(grabber.Filterable, func()) (*grabber.Chapter, error)
-			}); ok {
-				chapter, err = fetcher.FetchChapterWithProgress(chap, func() {
-					tracker.Increment(1)
-				})
-			} else {
-				chapter, err = s.FetchChapter(chap)
+	rep, err := reporter.New(progressMode, os.Stdout)
+	cerr(err, "Error creating progress reporter: ")
+
+	comicLen, chapterLen := calculateTitleLengths(reporter.TerminalWidth())
+	p := &pipeline.Pipeline{
+		Site:     s,
+		Settings: &settings,
+		Reporter: rep,
+		URL:      getUrlArg(args),
+		Library:  lib,
+		Hooks:    runner,
+		Label: func(title string, chap grabber.Filterable) string {
+			label := fmt.Sprintf("%s - %s", truncateString(title, comicLen), truncateString(chap.GetTitle(), chapterLen))
+			if l, ok := chap.(interface{ GetLanguage() string }); ok && settings.MultiLanguage {
+				label += " [" + l.GetLanguage() + "]"
 			}
-			if err != nil {
-				logger.Error("rootCmd.Run: Error fetching chapter %s: %v", chap.GetTitle(), err)
-				tracker.UpdateMessage(barTitle + " [Download Failed]")
-				<-guard
-				return
-			}
-
-			downloadingTicks := chapter.PagesCount
-			archivingTicks := chapter.PagesCount
-			newTotal := int64(80) + downloadingTicks
-			if !settings.Bundle {
-				newTotal += archivingTicks
-			}
-			tracker.Total = newTotal
-
-			tracker.UpdateMessage(barTitle + " [Downloading]")
-			files, err := downloader.FetchChapter(s, chapter, func(page int, progressValue int, err error) {
-				if err != nil {
-					tracker.UpdateMessage(barTitle + " [Downloading: Error " + err.Error() + "]")
-				} else {
-					tracker.Increment(1)
-				}
-			})
-			if err != nil {
-				logger.Error("rootCmd.Run: Error downloading chapter %s: %v", chapter.GetTitle(), err)
-				tracker.UpdateMessage(barTitle + " [Download Failed]")
-				<-guard
-				return
-			}
-
-			if settings.Bundle {
-				mu.Lock()
-				bundledChapters = append(bundledChapters, &packer.DownloadedChapter{
-					Chapter: chapter,
-					Files:   files,
-				})
-				mu.Unlock()
-			} else {
-				tracker.UpdateMessage(barTitle + " [Archiving]")
-				d := &packer.DownloadedChapter{
-					Chapter: chapter,
-					Files:   files,
-				}
-				_, err := packer.PackSingle(settings.OutputDir, s, d, func(page, _ int) {
-					tracker.Increment(1)
-				})
-				if err != nil {
-					logger.Error("rootCmd.Run: Error archiving chapter: %v", err)
-				}
-			}
-			tracker.MarkAsDone()
-			<-guard
-		}(chap, trackers[i], fmt.Sprintf("%s - %s", truncateString(title, comicLen), truncateString(chap.GetTitle(), chapterLen)))
-	}
-	wg.Wait()
-
-	// If not bundling, stop progress writer, log and print the completion message, then exit.
-	if !settings.Bundle {
-		pw.Stop()
-		logger.Info("Download(s) completed.")
-		os.Exit(0)
-	}
-
-	sort.SliceStable(bundledChapters, func(i, j int) bool {
-		return bundledChapters[i].Chapter.Number < bundledChapters[j].Chapter.Number
-	})
-	totalPages := 0
-	for _, d := range bundledChapters {
-		totalPages += int(d.Chapter.PagesCount)
-	}
-	bundleTracker := progress.Tracker{
-		Message: "Bundle [Archiving All Chapters]",
-		Total:   int64(totalPages),
+			return label
+		},
 	}
-	pw.AppendTracker(&bundleTracker)
-
-	filename, err := packer.PackBundle(settings.OutputDir, s, bundledChapters, settings.Range, func(page, _ int) {
-		bundleTracker.Increment(1)
-	})
+	_, err = p.Run(context.Background(), title, chapters)
+	rep.Stop()
+	browserless.Close()
+	grabber.FlushReports()
 	if err != nil {
 		logger.Error("rootCmd.Run: Error bundling chapters: %v", err)
-		fmt.Println(color.RedString(err.Error()))
+		fmt.Fprintln(statusOut(), color.RedString(err.Error()))
 		os.Exit(1)
 	}
-	bundleTracker.MarkAsDone()
-	fmt.Printf("- %s %s\n", color.GreenString("saved file"), color.HiBlackString(filename))
-	pw.Stop()
-	// Log and print download completion message after bundling
 	logger.Info("Download(s) completed.")
 }
 
@@ -290,31 +194,62 @@ func Execute() {
 		FlagsDescr:    cc.HiMagenta,
 		FlagsDataType: cc.Italic,
 	})
+	rootCmd.SetArgs(escapeRangeArgs(os.Args[1:]))
 	if err := rootCmd.Execute(); err != nil {
 		logger.Error("rootCmd.Execute: %v", err)
-		fmt.Println(err)
+		fmt.Fprintln(statusOut(), err)
 		os.Exit(1)
 	}
 }
 
 func init() {
-	rootCmd.Flags().BoolVarP(&settings.Bundle, "bundle", "b", false, "bundle all specified chapters into a single file")
-	rootCmd.Flags().Uint8VarP(&settings.MaxConcurrency.Chapters, "concurrency", "c", 5, "number of concurrent chapter downloads, hard-limited to 5")
-	rootCmd.Flags().Uint8VarP(&settings.MaxConcurrency.Pages, "concurrency-pages", "C", 10, "number of concurrent page downloads, hard-limited to 10")
-	rootCmd.Flags().StringVarP(&settings.Language, "language", "l", "", "only download the specified language")
-	rootCmd.Flags().StringVarP(&settings.FilenameTemplate, "filename-template", "t", packer.FilenameTemplateDefault, "template for the resulting filename")
+	bindSettingsFlags(rootCmd.Flags(), &settings)
 	rootCmd.PersistentFlags().StringVarP(&settings.OutputDir, "output-dir", "o", "./", "output directory for the downloaded files")
-	rootCmd.Flags().StringVarP(&settings.Format, "format", "f", "cbz", "archive format: cbz, zip, raw")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "assume yes on every prompt")
+	rootCmd.Flags().BoolVar(&assumeYes, "non-interactive", false, "never prompt (alias of --yes)")
+	rootCmd.Flags().StringVar(&progressMode, "progress", reporter.ModeAuto, "progress output: auto, bars, plain, json")
+}
+
+// bindSettingsFlags defines the flags of the download settings, except the output directory.
+func bindSettingsFlags(flags *pflag.FlagSet, settings *grabber.Settings) {
+	flags.BoolVarP(&settings.Bundle, "bundle", "b", false, "bundle all specified chapters into a single file")
+	flags.StringVar(&settings.BundleBy, "bundle-by", packer.BundleByRange, "bundle grouping: range (one file) or volume (one file per volume); implies --bundle")
+	flags.Uint8VarP(&settings.MaxConcurrency.Chapters, "concurrency", "c", 5, "number of concurrent chapter downloads, hard-limited to 5")
+	flags.Uint8VarP(&settings.MaxConcurrency.Pages, "concurrency-pages", "C", 10, "number of concurrent page downloads across all chapters, hard-limited to 10")
+	flags.Uint8Var(&settings.MaxConcurrency.PerHost, "concurrency-host", 0, "number of concurrent page downloads from the same server, 0 for --concurrency-pages; lowered automatically while downloads fail")
+	flags.StringVarP(&settings.Language, "language", "l", "", "only download the specified languages, as an ordered fallback chain (i.e. es-la,es,en)")
+	flags.BoolVar(&settings.MultiLanguage, "multi-language", false, "download each chapter in every language given with --language, tagging each output with its language")
+	flags.StringSliceVar(&settings.Selection.PreferGroups, "prefer-group", nil, "scanlation groups to prefer when a chapter has several releases, in order of preference")
+	flags.StringSliceVar(&settings.Selection.ExcludeGroups, "exclude-group", nil, "scanlation groups whose releases are never downloaded")
+	flags.StringVar(&settings.Selection.Prefer, "prefer", grabber.PreferPages, "tie-breaker between releases of the same chapter: pages (most pages) or latest (latest upload)")
+	flags.StringVarP(&settings.FilenameTemplate, "filename-template", "t", packer.FilenameTemplateDefault, "template for the resulting filename; may contain / to create directories")
+	flags.StringVar(&settings.ChapterFolderTemplate, "chapter-folder-template", packer.ChapterFolderTemplateDefault, "template for the chapter folders inside bundles")
+	flags.IntVar(&settings.PadWidth, "pad-width", packer.PadWidthDefault, "number of digits chapter numbers are zero-padded to in {{.PaddedNumber}}")
+	flags.StringVarP(&settings.Format, "format", "f", "cbz", "archive format: cbz, zip, raw")
+	flags.BoolVar(&settings.SkipDownloaded, "skip-downloaded", false, "skip the chapters already recorded in the library")
+	flags.StringVar(&settings.Sanitize, "sanitize", packer.SanitizeDefault, "filename sanitization profile: posix, windows, portable (safe on every filesystem)")
+	flags.StringVar(&settings.Quality, "quality", grabber.QualityData, "MangaDex image quality: data (original) or data-saver (compressed)")
+	flags.BoolVar(&settings.ForceOrigin, "force-origin", false, "download the MangaDex images from uploads.mangadex.org instead of the MangaDex@Home servers")
+	flags.StringSliceVar(&settings.ContentRatings, "content-rating", nil, "MangaDex content ratings to list: safe, suggestive, erotica, pornographic (default: all but pornographic)")
 }
 
 func cerr(err error, prefix string) {
 	if err != nil {
 		logger.Error("rootCmd.cerr: %s %v", prefix, err)
-		fmt.Println(color.RedString(prefix + err.Error()))
+		fmt.Fprintln(statusOut(), color.RedString(prefix+err.Error()))
 		os.Exit(1)
 	}
 }
 
+// statusOut returns where the status messages are printed: stderr with --progress json, so
+// stdout only holds the JSON events, and stdout otherwise.
+func statusOut() io.Writer {
+	if progressMode == reporter.ModeJSON {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
 func colorizeHelp(help string) string {
 	yre := regexp.MustCompile(`comic-downloader|nada`)
 	help = yre.ReplaceAllStringFunc(help, func(s string) string {
@@ -331,6 +266,31 @@ func colorizeHelp(help string) string {
 	return help
 }
 
+// escapeRangeArgs moves range arguments starting with a dash (e.g. "-5" for the
+// last five chapters) after a "--" terminator so they are not parsed as flags.
+// Only the arguments of the download command are ranges; subcommands get theirs unchanged.
+func escapeRangeArgs(args []string) []string {
+	if cmd, _, err := rootCmd.Find(args); err == nil && cmd != rootCmd {
+		return args
+	}
+	re := regexp.MustCompile(`^-\d`)
+	var rest, rngs []string
+	for _, arg := range args {
+		if arg == "--" {
+			return args
+		}
+		if re.MatchString(arg) {
+			rngs = append(rngs, arg)
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	if len(rngs) == 0 {
+		return args
+	}
+	return append(append(rest, "--"), rngs...)
+}
+
 func getRangesArg(args []string) string {
 	if len(args) == 1 {
 		return ""
@@ -351,12 +311,20 @@ func getUrlArg(args []string) string {
 	return args[1]
 }
 
-func getTerminalWidth() int {
-	width, _, err := term.GetSize(int(syscall.Stdin))
-	if err != nil {
-		return 80
+// confirmDownloadAll asks the user to confirm downloading every chapter.
+// It never prompts with --yes or when stdin is not a terminal (cron, Docker),
+// so headless runs do not block.
+func confirmDownloadAll(lastChapter float64) bool {
+	if assumeYes || !reporter.IsTerminal(os.Stdin) {
+		logger.Info("rootCmd.Run: Non-interactive session, downloading all %g chapters", lastChapter)
+		return true
+	}
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Do you want to download all %g chapters", lastChapter),
+		IsConfirm: true,
 	}
-	return width
+	_, err := prompt.Run()
+	return err == nil
 }
 
 func calculateTitleLengths(termWidth int) (comicLen, chapterLen int) {
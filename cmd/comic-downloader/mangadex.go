@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/browserless"
+	"github.com/NorkzYT/comic-downloader/internal/config"
+	"github.com/NorkzYT/comic-downloader/internal/grabber"
+	"github.com/NorkzYT/comic-downloader/internal/hooks"
+	"github.com/NorkzYT/comic-downloader/internal/library"
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+	"github.com/NorkzYT/comic-downloader/internal/mangadex"
+	"github.com/NorkzYT/comic-downloader/internal/pipeline"
+	"github.com/NorkzYT/comic-downloader/internal/reporter"
+	"github.com/fatih/color"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// mangadexCredentials is the MangaDex credentials file path.
+	mangadexCredentials string
+	// mangadexLogin holds the personal API client given to the login command.
+	mangadexLogin mangadex.Credentials
+	// followsSettings holds the download settings of the follows command.
+	followsSettings grabber.Settings
+	// followsSince is how far back the feed is downloaded on the first run, or when set.
+	followsSince time.Duration
+	// followsStatus only downloads the manga with these reading statuses.
+	followsStatus []string
+	// followsMarkRead marks the downloaded chapters as read on MangaDex.
+	followsMarkRead bool
+	// followsDryRun lists the new chapters without downloading them.
+	followsDryRun bool
+)
+
+var mangadexCmd = &cobra.Command{
+	Use:   "mangadex",
+	Short: "Logs in to MangaDex and downloads the feed of the followed manga",
+}
+
+var mangadexLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Logs in to MangaDex with a personal API client",
+	Long: `Logs in to MangaDex with a personal API client, created in the API Clients section of the
+MangaDex settings. The tokens are saved to the credentials file, readable by the user only, and
+refreshed when they expire. The password is prompted for, or read from
+COMIC_DOWNLOADER_MANGADEX_PASSWORD.`,
+	Example: `  comic-downloader mangadex login --client-id personal-client-xxx --client-secret yyy --username me`,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, err := resolveConfig(cmd, "https://mangadex.org")
+		cerr(err, "Error loading configuration: ")
+		password, err := mangadexPassword()
+		cerr(err, "Error reading password: ")
+		_, err = mangadex.Login(context.Background(), mangadexCredentials, "", mangadexLogin, password)
+		cerr(err, "Error logging in to MangaDex: ")
+		fmt.Printf("Logged in to MangaDex as %s\n", color.HiBlueString(mangadexLogin.Username))
+	},
+}
+
+var mangadexLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Removes the MangaDex credentials",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := os.Remove(mangadexCredentials); err != nil && !errors.Is(err, os.ErrNotExist) {
+			cerr(err, "Error removing credentials: ")
+		}
+		fmt.Println("Logged out of MangaDex")
+	},
+}
+
+var mangadexFollowsCmd = &cobra.Command{
+	Use:   "follows",
+	Short: "Downloads the new chapters of the followed manga",
+	Long: `Downloads the chapters of the user's followed manga feed published since the last run
+(or within --since on the first run), in the languages given with --language. Each manga is
+downloaded like its title URL would be, so the configuration file settings for mangadex.org,
+the library and the hooks apply. With --status, only the manga of the user library with one
+of the reading statuses are downloaded; with --mark-read, the downloaded chapters are marked
+as read on MangaDex.`,
+	Example: `  comic-downloader mangadex follows --language en --skip-downloaded
+  comic-downloader mangadex follows --status reading,re_reading --mark-read --since 720h`,
+	Run: func(cmd *cobra.Command, args []string) {
+		section, err := resolveConfig(cmd, "https://mangadex.org")
+		cerr(err, "Error loading configuration: ")
+		cerr(section.Known(cmd.Flags()).ApplyFlags(cmd.Flags()), "Error loading configuration: ")
+		followsSettings.OutputDir = settings.OutputDir
+		cerr(pipeline.Validate(&followsSettings), "Error parsing ")
+		runner, err := hooks.NewRunner(section.Hooks)
+		cerr(err, "Error loading configuration: ")
+
+		ctx := context.Background()
+		client, err := mangadex.Open(mangadexCredentials)
+		cerr(err, "")
+		since := client.Credentials().LastSync
+		if since.IsZero() || cmd.Flags().Changed("since") {
+			since = time.Now().Add(-followsSince)
+		}
+		start := time.Now()
+		feed, err := client.FollowsFeed(ctx, mangadex.FeedOptions{
+			Since:          since,
+			Languages:      followsSettings.Languages(),
+			ContentRatings: followsSettings.ContentRatings,
+		})
+		cerr(err, "Error fetching the followed feed: ")
+		if len(followsStatus) > 0 {
+			statuses, err := client.Statuses(ctx)
+			cerr(err, "Error fetching the reading statuses: ")
+			feed = filterStatus(feed, statuses, followsStatus)
+		}
+		manga, ids := groupByManga(feed)
+		logger.Info("mangadexFollowsCmd: %d new chapters of %d manga since %s", len(feed), len(manga), since.Format(time.RFC3339))
+		if len(manga) == 0 {
+			fmt.Fprintln(statusOut(), color.YellowString("No new chapters since %s", since.Format("2006-01-02 15:04")))
+			if !followsDryRun {
+				cerr(client.SetLastSync(start), "Error saving credentials: ")
+			}
+			return
+		}
+		if followsDryRun {
+			for _, id := range manga {
+				fmt.Fprintf(statusOut(), "%s: %d new chapters\n", mangadexTitleURL(id), len(ids[id]))
+			}
+			return
+		}
+		cerr(os.MkdirAll(followsSettings.OutputDir, 0755), "Error creating output directory: ")
+
+		rep, err := reporter.New(progressMode, os.Stdout)
+		cerr(err, "Error creating progress reporter: ")
+		lib := openLibrary()
+		var failed []error
+		for _, id := range manga {
+			s := followsSettings
+			packed, err := downloadFollowed(ctx, cmd, &s, rep, lib, runner, mangadexTitleURL(id), ids[id])
+			if followsMarkRead && len(packed) > 0 {
+				err = errors.Join(err, client.MarkRead(ctx, id, packed))
+			}
+			if err != nil {
+				logger.Error("mangadexFollowsCmd: %s: %v", mangadexTitleURL(id), err)
+				failed = append(failed, fmt.Errorf("%s: %w", mangadexTitleURL(id), err))
+			}
+		}
+		rep.Stop()
+		browserless.Close()
+		grabber.FlushReports()
+		if len(failed) > 0 {
+			// The last sync is kept, so the failed chapters are downloaded again next time.
+			cerr(errors.Join(failed...), "Error downloading the followed feed: ")
+		}
+		cerr(client.SetLastSync(start), "Error saving credentials: ")
+		logger.Info("Download(s) completed.")
+	},
+}
+
+// downloadFollowed downloads the chapters with the given IDs of the MangaDex manga, returning
+// the IDs of the chapters written (not those left out by the selection, already in the library
+// or hosted elsewhere).
+func downloadFollowed(ctx context.Context, cmd *cobra.Command, s *grabber.Settings, rep reporter.Reporter, lib *library.Library, runner *hooks.Runner, mangaURL string, chapterIDs []string) ([]string, error) {
+	site, errs := grabber.NewSite(mangaURL, s)
+	if site == nil {
+		return nil, errors.Join(append([]error{errors.New("site not recognised")}, errs...)...)
+	}
+	site.InitFlags(cmd)
+	title, err := site.FetchTitle()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching title: %w", err)
+	}
+	chapters, errs := site.FetchChapters()
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("error fetching chapters: %w", errors.Join(errs...))
+	}
+
+	wanted := map[string]bool{}
+	for _, id := range chapterIDs {
+		wanted[id] = true
+	}
+	var selected grabber.Filterables
+	for _, c := range chapters {
+		if mc, ok := c.(*grabber.MangadexChapter); ok && wanted[mc.Id] {
+			selected = append(selected, c)
+		}
+	}
+	selected = selected.SortByNumber().SelectCandidates(s.SelectionPolicy())
+	if s.SkipDownloaded && lib != nil {
+		if selected, err = lib.Filter(mangaURL, selected); err != nil {
+			return nil, fmt.Errorf("error reading library: %w", err)
+		}
+	}
+	if len(selected) == 0 {
+		logger.Info("downloadFollowed: No chapters left to download for %s", mangaURL)
+		return nil, nil
+	}
+
+	p := &pipeline.Pipeline{Site: site, Settings: s, Reporter: rep, URL: mangaURL, Library: lib, Hooks: runner}
+	res, err := p.Run(ctx, title, selected)
+	if err == nil && len(res.Failed) > 0 {
+		err = errors.Join(res.Failed...)
+	}
+	var packed []string
+	for _, c := range res.Packed {
+		if mc, ok := c.(*grabber.MangadexChapter); ok {
+			packed = append(packed, mc.Id)
+		}
+	}
+	return packed, err
+}
+
+// filterStatus keeps the chapters of the manga with one of the reading statuses.
+func filterStatus(feed []mangadex.FeedChapter, statuses map[string]string, keep []string) []mangadex.FeedChapter {
+	wanted := map[string]bool{}
+	for _, s := range keep {
+		wanted[s] = true
+	}
+	var res []mangadex.FeedChapter
+	for _, c := range feed {
+		if wanted[statuses[c.MangaID]] {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// groupByManga returns the manga IDs of the feed, in the order of their first chapter, and
+// the chapter IDs of each.
+func groupByManga(feed []mangadex.FeedChapter) ([]string, map[string][]string) {
+	var manga []string
+	ids := map[string][]string{}
+	for _, c := range feed {
+		if c.MangaID == "" {
+			continue
+		}
+		if _, ok := ids[c.MangaID]; !ok {
+			manga = append(manga, c.MangaID)
+		}
+		ids[c.MangaID] = append(ids[c.MangaID], c.ID)
+	}
+	return manga, ids
+}
+
+// mangadexTitleURL returns the URL of the manga page.
+func mangadexTitleURL(id string) string {
+	return "https://mangadex.org/title/" + id
+}
+
+// mangadexPassword returns the MangaDex password from the environment, or prompts for it.
+func mangadexPassword() (string, error) {
+	if p := os.Getenv(config.EnvName("mangadex-password")); p != "" {
+		return p, nil
+	}
+	if !reporter.IsTerminal(os.Stdin) {
+		return "", fmt.Errorf("%s must be set when not running in a terminal", config.EnvName("mangadex-password"))
+	}
+	prompt := promptui.Prompt{Label: "MangaDex password", Mask: '*'}
+	return prompt.Run()
+}
+
+func init() {
+	mangadexCmd.PersistentFlags().StringVar(&mangadexCredentials, "credentials", mangadex.DefaultPath(), "MangaDex credentials file")
+
+	mangadexLoginCmd.Flags().StringVar(&mangadexLogin.ClientID, "client-id", "", "personal API client ID")
+	mangadexLoginCmd.Flags().StringVar(&mangadexLogin.ClientSecret, "client-secret", "", "personal API client secret")
+	mangadexLoginCmd.Flags().StringVar(&mangadexLogin.Username, "username", "", "MangaDex user name")
+	for _, f := range []string{"client-id", "client-secret", "username"} {
+		_ = mangadexLoginCmd.MarkFlagRequired(f)
+	}
+
+	bindSettingsFlags(mangadexFollowsCmd.Flags(), &followsSettings)
+	mangadexFollowsCmd.Flags().DurationVar(&followsSince, "since", 7*24*time.Hour, "how far back the feed is downloaded on the first run (or always, when set)")
+	mangadexFollowsCmd.Flags().StringSliceVar(&followsStatus, "status", nil, "only download the manga with these reading statuses: reading, on_hold, plan_to_read, dropped, re_reading, completed")
+	mangadexFollowsCmd.Flags().BoolVar(&followsMarkRead, "mark-read", false, "mark the downloaded chapters as read on MangaDex")
+	mangadexFollowsCmd.Flags().BoolVar(&followsDryRun, "dry-run", false, "list the manga with new chapters without downloading them")
+	mangadexFollowsCmd.Flags().StringVar(&progressMode, "progress", reporter.ModeAuto, "progress output: auto, bars, plain, json")
+
+	mangadexCmd.AddCommand(mangadexLoginCmd, mangadexLogoutCmd, mangadexFollowsCmd)
+	rootCmd.AddCommand(mangadexCmd)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/browserless"
+	"github.com/NorkzYT/comic-downloader/internal/config"
+	"github.com/NorkzYT/comic-downloader/internal/downloader"
+	"github.com/NorkzYT/comic-downloader/internal/grabber"
+	"github.com/NorkzYT/comic-downloader/internal/hooks"
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+	"github.com/NorkzYT/comic-downloader/internal/pipeline"
+	"github.com/NorkzYT/comic-downloader/internal/ranges"
+	"github.com/NorkzYT/comic-downloader/internal/reporter"
+	"github.com/NorkzYT/comic-downloader/internal/server"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// listenAddr is the address the server listens on.
+	listenAddr string
+	// workers is the number of jobs the server runs concurrently.
+	workers int
+	// queueFile is where the server persists its job queue.
+	queueFile string
+	// apiToken is the bearer token required by the server API (empty disables it).
+	apiToken string
+	// pageWorkers is the number of page downloads the server runs concurrently, across jobs.
+	pageWorkers int
+	// pageWorkersHost is the number of page downloads the server runs concurrently per host (0 for pageWorkers).
+	pageWorkersHost int
+	// jobScheduler downloads the pages of every job.
+	jobScheduler *downloader.Scheduler
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Runs a local server with a web UI and a REST API to queue downloads",
+	Long: `Runs a local HTTP server with a web UI and a REST API to queue download jobs, list and
+cancel them and stream their progress as server-sent events. The queue is persisted, so
+pending jobs survive restarts. Open the listen address in a browser to use the web UI.
+
+Jobs take the comic URL, an optional range and options named after the command flags:
+
+  curl -X POST localhost:8080/api/jobs -H 'Content-Type: application/json' -d '{"url": "https://mangadex.org/title/...", "range": "1-10", "options": {"format": "zip", "bundle": true}}'`,
+	Args: cobra.NoArgs,
+	Run:  serve,
+}
+
+func serve(cmd *cobra.Command, args []string) {
+	section, err := resolveConfig(cmd, "")
+	cerr(err, "Error loading configuration: ")
+	cerr(section.Known(cmd.Flags()).ApplyFlags(cmd.Flags()), "Error loading configuration: ")
+	jobScheduler = downloader.NewScheduler(pageWorkers, pageWorkersHost)
+	jobEnvironment.cmd = cmd
+
+	srv, err := server.New(server.Options{
+		Workers:   workers,
+		QueueFile: queueFile,
+		Token:     apiToken,
+		Run:       runJob,
+		Chapters:  listChapters,
+		Validate: func(req server.Request) error {
+			_, _, err := jobCommand(req)
+			return err
+		},
+	})
+	cerr(err, "Error loading job queue: ")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv.Start(ctx)
+
+	httpServer := &http.Server{Addr: listenAddr, Handler: srv.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("serveCmd: Listening on %s", listenAddr)
+	fmt.Printf("Listening on %s\n", color.HiBlueString("http://"+listenAddr))
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		cerr(err, "Error starting server: ")
+	}
+	srv.Wait()
+	browserless.Close()
+	grabber.FlushReports()
+	logger.Info("serveCmd: Server stopped")
+}
+
+// jobCommand returns a command whose flags hold the settings of a job: the built-in defaults,
+// overridden by the environment, the configuration file resolved for the job URL and the
+// job options. The output directory is always the server one.
+func jobCommand(req server.Request) (*cobra.Command, *grabber.Settings, error) {
+	s := &grabber.Settings{}
+	cmd := &cobra.Command{}
+	bindSettingsFlags(cmd.Flags(), s)
+	s.OutputDir = settings.OutputDir
+
+	section, err := jobConfig(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := section.Known(cmd.Flags()).ApplyFlags(cmd.Flags()); err != nil {
+		return nil, nil, err
+	}
+	if err := (config.Section{Flags: req.Options}).OverrideFlags(cmd.Flags()); err != nil {
+		return nil, nil, err
+	}
+	if req.Range != "" {
+		if _, err := ranges.Parse(req.Range); err != nil {
+			return nil, nil, err
+		}
+		s.Range = req.Range
+	}
+	if err := pipeline.Validate(s); err != nil {
+		return nil, nil, err
+	}
+	return cmd, s, nil
+}
+
+// jobConfig returns the configuration file settings resolved for the profile and the URL of a job.
+func jobConfig(req server.Request) (config.Section, error) {
+	p := req.Profile
+	if p == "" {
+		p = profile
+	}
+	f, err := config.Load(configPath)
+	if err != nil {
+		return config.Section{}, err
+	}
+	return f.Resolve(p, req.URL)
+}
+
+// jobSite returns the site of a job, initialized with the job settings.
+func jobSite(req server.Request) (grabber.Site, *grabber.Settings, error) {
+	cmd, s, err := jobCommand(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	site, errs := grabber.NewSite(req.URL, s)
+	if site == nil {
+		return nil, nil, errors.Join(append([]error{errors.New("site not recognised")}, errs...)...)
+	}
+	site.InitFlags(cmd)
+	return site, s, nil
+}
+
+// listChapters fetches the title and the chapters of the comic of a job request.
+func listChapters(ctx context.Context, req server.Request) (server.Series, error) {
+	release, err := jobEnvironment.acquire(ctx, req)
+	if err != nil {
+		return server.Series{}, err
+	}
+	defer release()
+	site, _, err := jobSite(req)
+	if err != nil {
+		return server.Series{}, err
+	}
+	title, err := site.FetchTitle()
+	if err != nil {
+		return server.Series{}, fmt.Errorf("error fetching title: %w", err)
+	}
+	chapters, errs := site.FetchChapters()
+	if len(errs) > 0 {
+		return server.Series{}, fmt.Errorf("error fetching chapters: %w", errors.Join(errs...))
+	}
+
+	series := server.Series{Title: title, Chapters: []server.Chapter{}}
+	for _, c := range chapters.SortByNumber() {
+		chapter := server.Chapter{
+			Number: c.GetNumber(),
+			Title:  c.GetTitle(),
+			Volume: c.GetVolume(),
+			Group:  c.GetGroup(),
+		}
+		if l, ok := c.(interface{ GetLanguage() string }); ok {
+			chapter.Language = l.GetLanguage()
+		}
+		series.Chapters = append(series.Chapters, chapter)
+	}
+	return series, nil
+}
+
+// runJob downloads the chapters of a job through the pipeline.
+func runJob(ctx context.Context, req server.Request, rep reporter.Reporter) error {
+	release, err := jobEnvironment.acquire(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer release()
+	site, s, err := jobSite(req)
+	if err != nil {
+		return err
+	}
+
+	title, err := site.FetchTitle()
+	if err != nil {
+		return fmt.Errorf("error fetching title: %w", err)
+	}
+	chapters, errs := site.FetchChapters()
+	if len(errs) > 0 {
+		return fmt.Errorf("error fetching chapters: %w", errors.Join(errs...))
+	}
+
+	rngs := []ranges.Range{{Begin: 0, End: math.Inf(1)}}
+	if s.Range != "" {
+		if rngs, err = ranges.Parse(s.Range); err != nil {
+			return err
+		}
+	}
+	chapters = chapters.SortByNumber().FilterRanges(rngs).SelectCandidates(s.SelectionPolicy())
+	lib := openLibrary()
+	if s.SkipDownloaded && lib != nil {
+		if chapters, err = lib.Filter(req.URL, chapters); err != nil {
+			return fmt.Errorf("error reading library: %w", err)
+		}
+	}
+	if len(chapters) == 0 {
+		return errors.New("no chapters found for the specified ranges")
+	}
+	if err := os.MkdirAll(s.OutputDir, 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	section, err := jobConfig(req)
+	if err != nil {
+		return err
+	}
+	runner, err := hooks.NewRunner(section.Hooks)
+	if err != nil {
+		return err
+	}
+
+	p := &pipeline.Pipeline{Site: site, Settings: s, Reporter: rep, URL: req.URL, Library: lib, Hooks: runner, Scheduler: jobScheduler}
+	res, err := p.Run(ctx, title, chapters)
+	if err == nil && len(res.Failed) > 0 {
+		err = errors.Join(res.Failed...)
+	}
+	return err
+}
+
+// defaultQueueFile returns the default job queue path, next to the configuration file.
+func defaultQueueFile() string {
+	if p := config.DefaultPath(); p != "" {
+		return filepath.Join(filepath.Dir(p), "jobs.json")
+	}
+	return "jobs.json"
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&listenAddr, "listen", "127.0.0.1:8080", "address the server listens on")
+	serveCmd.Flags().IntVar(&workers, "workers", 1, "number of jobs downloaded concurrently")
+	serveCmd.Flags().IntVar(&pageWorkers, "page-workers", 10, "number of pages downloaded concurrently, shared by all jobs")
+	serveCmd.Flags().IntVar(&pageWorkersHost, "page-workers-host", 0, "number of pages downloaded concurrently from the same server, shared by all jobs, 0 for --page-workers")
+	serveCmd.Flags().StringVar(&queueFile, "queue-file", defaultQueueFile(), "file the job queue is persisted to")
+	serveCmd.Flags().StringVar(&apiToken, "token", "", "bearer token required by the API (recommended when listening on a public address)")
+	rootCmd.AddCommand(serveCmd)
+}
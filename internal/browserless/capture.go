@@ -0,0 +1,176 @@
+package browserless
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Image is an image response captured during a page visit.
+type Image struct {
+	// URL is the requested URL, before any redirect
+	URL string
+	// MimeType is the response MIME type
+	MimeType string
+	// Data is the response body
+	Data []byte
+}
+
+// CapturePage is a loaded page capturing the image responses it receives.
+type CapturePage interface {
+	Page
+	// Images waits up to wait for the pending image responses, then returns the captured
+	// images in the order they were received
+	Images(wait time.Duration) []Image
+}
+
+// Capturer is a Runner able to capture the image responses of the pages it loads.
+type Capturer interface {
+	// LoadCapturing is Load, capturing the image responses of the page from the navigation on
+	LoadCapturing(url string, waitSelector string, sleepDuration time.Duration) (CapturePage, error)
+}
+
+// LoadCapturing is Load, capturing the image responses of the page: images loaded by the
+// page, lazily or with its cookies, can be used without being downloaded again.
+// When the Runner cannot capture, the page captures no image.
+func LoadCapturing(url string, waitSelector string, sleepDuration time.Duration) (CapturePage, error) {
+	if c, ok := runner.(Capturer); ok {
+		return c.LoadCapturing(url, waitSelector, sleepDuration)
+	}
+	page, err := runner.Load(url, waitSelector, sleepDuration)
+	if err != nil {
+		return nil, err
+	}
+	return noCapture{page}, nil
+}
+
+// noCapture is a page of a Runner unable to capture images.
+type noCapture struct {
+	Page
+}
+
+// Images implements CapturePage.
+func (noCapture) Images(time.Duration) []Image {
+	return nil
+}
+
+// captureQuiet is how long no image response must be received for the images to be complete.
+const captureQuiet = 500 * time.Millisecond
+
+// capture records the image responses of a tab from the CDP Network events.
+type capture struct {
+	mu      sync.Mutex
+	images  map[network.RequestID]*Image
+	urls    map[network.RequestID]string
+	order   []network.RequestID
+	pending int
+	last    time.Time
+}
+
+// newCapture starts capturing the image responses of the tab, until ctx is done.
+func newCapture(ctx context.Context) *capture {
+	c := &capture{
+		images: map[network.RequestID]*Image{},
+		urls:   map[network.RequestID]string{},
+		last:   time.Now(),
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		c.handle(ctx, ev)
+	})
+	return c
+}
+
+// handle records a CDP Network event of the tab.
+func (c *capture) handle(ctx context.Context, ev interface{}) {
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		c.mu.Lock()
+		if _, ok := c.urls[ev.RequestID]; !ok {
+			c.urls[ev.RequestID] = ev.Request.URL
+		}
+		c.mu.Unlock()
+	case *network.EventResponseReceived:
+		if ev.Type != network.ResourceTypeImage && !strings.HasPrefix(ev.Response.MimeType, "image/") {
+			return
+		}
+		c.mu.Lock()
+		u := c.urls[ev.RequestID]
+		if u == "" {
+			u = ev.Response.URL
+		}
+		if _, ok := c.images[ev.RequestID]; !ok && !strings.HasPrefix(u, "data:") {
+			c.images[ev.RequestID] = &Image{URL: u, MimeType: ev.Response.MimeType}
+			c.pending++
+		}
+		c.last = time.Now()
+		c.mu.Unlock()
+	case *network.EventLoadingFinished:
+		if c.tracked(ev.RequestID) {
+			// Fetching the body from the listener would block the events.
+			go c.fetch(ctx, ev.RequestID)
+		}
+	case *network.EventLoadingFailed:
+		if c.tracked(ev.RequestID) {
+			c.done(ev.RequestID, nil)
+		}
+	}
+}
+
+// tracked reports whether the request is a pending image response.
+func (c *capture) tracked(id network.RequestID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	img, ok := c.images[id]
+	return ok && img.Data == nil
+}
+
+// fetch gets the body of the image response.
+func (c *capture) fetch(ctx context.Context, id network.RequestID) {
+	data, err := network.GetResponseBody(id).Do(cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target))
+	if err != nil {
+		logger.Debug("browserless.capture: Error getting response body: %v", err)
+	}
+	c.done(id, data)
+}
+
+// done records the body of the image response, dropping the image when there is none.
+func (c *capture) done(id network.RequestID, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(data) == 0 {
+		delete(c.images, id)
+	} else {
+		c.images[id].Data = data
+		c.order = append(c.order, id)
+	}
+	c.pending--
+	c.last = time.Now()
+}
+
+// wait returns the captured images once no image is pending and none was received for a while,
+// or after wait.
+func (c *capture) wait(wait time.Duration) []Image {
+	deadline := time.Now().Add(wait)
+	for {
+		c.mu.Lock()
+		quiet := c.pending == 0 && time.Since(c.last) >= captureQuiet
+		c.mu.Unlock()
+		if quiet || !time.Now().Before(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	images := make([]Image, 0, len(c.order))
+	for _, id := range c.order {
+		images = append(images, *c.images[id])
+	}
+	return images
+}
@@ -0,0 +1,56 @@
+package browserless
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// respond feeds the capture the request and response events of an image request.
+func respond(c *capture, id network.RequestID, url string, typ network.ResourceType, mimeType string) {
+	ctx := context.Background()
+	c.handle(ctx, &network.EventRequestWillBeSent{RequestID: id, Request: &network.Request{URL: url}})
+	c.handle(ctx, &network.EventResponseReceived{RequestID: id, Type: typ, Response: &network.Response{URL: url, MimeType: mimeType}})
+}
+
+func TestCapture(t *testing.T) {
+	c := &capture{images: map[network.RequestID]*Image{}, urls: map[network.RequestID]string{}}
+	respond(c, "1", "https://gg.asuracomic.net/01.webp", network.ResourceTypeImage, "image/webp")
+	// Images fetched by scripts are captured by their MIME type.
+	respond(c, "2", "https://gg.asuracomic.net/02.jpg", network.ResourceTypeFetch, "image/jpeg")
+	respond(c, "3", "https://asuracomic.net/app.js", network.ResourceTypeScript, "text/javascript")
+	respond(c, "4", "https://gg.asuracomic.net/04.webp", network.ResourceTypeImage, "image/webp")
+	// Redirects keep the requested URL.
+	c.handle(context.Background(), &network.EventRequestWillBeSent{RequestID: "2", Request: &network.Request{URL: "https://cdn.test/02.jpg"}})
+
+	if c.pending != 3 {
+		t.Fatalf("pending = %d, want 3 images", c.pending)
+	}
+	c.done("2", []byte("jpeg"))
+	c.done("1", []byte("webp"))
+	c.handle(context.Background(), &network.EventLoadingFailed{RequestID: "4"})
+
+	start := time.Now()
+	images := c.wait(5 * time.Second)
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("wait() took %v with no image pending", time.Since(start))
+	}
+	if len(images) != 2 || images[0].URL != "https://gg.asuracomic.net/02.jpg" || string(images[0].Data) != "jpeg" ||
+		images[1].URL != "https://gg.asuracomic.net/01.webp" || images[1].MimeType != "image/webp" {
+		t.Errorf("wait() = %+v", images)
+	}
+}
+
+func TestCaptureWaitTimeout(t *testing.T) {
+	c := &capture{images: map[network.RequestID]*Image{}, urls: map[network.RequestID]string{}}
+	respond(c, "1", "https://gg.asuracomic.net/01.webp", network.ResourceTypeImage, "image/webp")
+	start := time.Now()
+	if images := c.wait(200 * time.Millisecond); len(images) != 0 {
+		t.Errorf("wait() = %+v, want no image", images)
+	}
+	if d := time.Since(start); d < 200*time.Millisecond || d > time.Second {
+		t.Errorf("wait() took %v, want the 200ms timeout", d)
+	}
+}
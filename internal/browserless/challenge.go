@@ -0,0 +1,85 @@
+package browserless
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// CookiePage is a loaded page giving access to the browser cookies, including the HttpOnly ones.
+type CookiePage interface {
+	Page
+	// Cookies returns the cookies the browser sends to the page URL
+	Cookies() ([]*nethttp.Cookie, error)
+}
+
+// challengeTimeout is how long a challenge is given to be solved.
+const challengeTimeout = 30 * time.Second
+
+// jsChallenged tells whether the page is still an anti-bot challenge page.
+const jsChallenged = `document.title.indexOf("Just a moment") >= 0 ||
+	!!document.querySelector('#challenge-form, #challenge-running, script[src*="/cdn-cgi/challenge-platform/"]')`
+
+// SolveChallenge loads the URL in the browser until its anti-bot challenge (such as the Cloudflare
+// one) is solved, then returns the browser User-Agent and the cookies of the site, cf_clearance
+// included. Since the browser keeps the cookies, the next loads of the site pass too.
+func SolveChallenge(pageURL string) (userAgent string, cookies []*nethttp.Cookie, err error) {
+	page, err := Load(pageURL, "", 0)
+	if err != nil {
+		return "", nil, err
+	}
+	defer page.Close()
+	cp, ok := page.(CookiePage)
+	if !ok {
+		return "", nil, errors.New("the browser cannot give the site cookies")
+	}
+
+	deadline := time.Now().Add(challengeTimeout)
+	for {
+		var challenged bool
+		if err := page.Eval(jsChallenged, &challenged); err != nil {
+			// The page navigates once the challenge is solved, which may interrupt the script.
+			logger.Debug("browserless.SolveChallenge: Error checking the challenge: %v", err)
+			challenged = true
+		}
+		if !challenged {
+			break
+		}
+		if time.Now().After(deadline) {
+			return "", nil, fmt.Errorf("challenge not solved after %v", challengeTimeout)
+		}
+		time.Sleep(time.Second)
+	}
+	if err := page.Eval("navigator.userAgent", &userAgent); err != nil {
+		return "", nil, err
+	}
+	if cookies, err = cp.Cookies(); err != nil {
+		return "", nil, err
+	}
+	logger.Debug("browserless.SolveChallenge: Challenge of %s solved with %d cookies", pageURL, len(cookies))
+	return userAgent, cookies, nil
+}
+
+// Cookies implements CookiePage.
+func (pp *poolPage) Cookies() ([]*nethttp.Cookie, error) {
+	var cookies []*network.Cookie
+	err := pp.run(chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		cookies, err = network.GetCookies().WithURLs([]string{pp.url}).Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*nethttp.Cookie, len(cookies))
+	for i, c := range cookies {
+		res[i] = &nethttp.Cookie{Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path, Secure: c.Secure, HttpOnly: c.HTTPOnly}
+	}
+	return res, nil
+}
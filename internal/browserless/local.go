@@ -0,0 +1,62 @@
+package browserless
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Local holds the options of a locally installed Chrome or Chromium.
+type Local struct {
+	// Path is the browser executable (default: chromium, chrome or google-chrome found in PATH)
+	Path string
+	// Headful shows the browser window instead of running headless
+	Headful bool
+	// UserDataDir is the browser profile directory, kept between runs (default: a temporary directory)
+	UserDataDir string
+	// Flags are extra command line switches, such as "no-sandbox" or "proxy-server=host:port"
+	Flags []string
+}
+
+// options returns the exec allocator options of the local browser.
+func (l Local) options() []chromedp.ExecAllocatorOption {
+	opts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	if l.Path != "" {
+		opts = append(opts, chromedp.ExecPath(l.Path))
+	}
+	if l.Headful {
+		opts = append(opts, chromedp.Flag("headless", false))
+	}
+	if l.UserDataDir != "" {
+		opts = append(opts, chromedp.UserDataDir(l.UserDataDir))
+	}
+	for _, f := range l.Flags {
+		name, value, ok := strings.Cut(strings.TrimLeft(f, "-"), "=")
+		if !ok {
+			opts = append(opts, chromedp.Flag(name, true))
+			continue
+		}
+		opts = append(opts, chromedp.Flag(name, value))
+	}
+	return opts
+}
+
+// allocator returns a function creating the allocator of the configured browser, and a key
+// identifying the browser options, to tell when they change.
+func allocator() (string, func(context.Context) (context.Context, context.CancelFunc), error) {
+	if config.Browser == BrowserLocal {
+		opts := config.Local.options()
+		return fmt.Sprintf("local %+v", config.Local), func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return chromedp.NewExecAllocator(ctx, opts...)
+		}, nil
+	}
+	wsURL, err := devtoolsURL()
+	if err != nil {
+		return "", nil, err
+	}
+	return wsURL, func(ctx context.Context) (context.Context, context.CancelFunc) {
+		return chromedp.NewRemoteAllocator(ctx, wsURL, chromedp.NoModifyURL)
+	}, nil
+}
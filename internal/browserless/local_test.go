@@ -0,0 +1,39 @@
+package browserless
+
+import "testing"
+
+func TestConfigValidate(t *testing.T) {
+	for _, b := range []string{"", BrowserRemote, BrowserLocal} {
+		if err := (Config{Browser: b}).Validate(); err != nil {
+			t.Errorf("Validate(%q) = %v", b, err)
+		}
+	}
+	if err := (Config{Browser: "firefox"}).Validate(); err == nil {
+		t.Error("Validate(firefox) succeeded")
+	}
+}
+
+func TestAllocatorLocal(t *testing.T) {
+	prev := config
+	t.Cleanup(func() { config = prev })
+	t.Setenv("BROWSERLESS_URL", "")
+	t.Setenv("BROWSERLESS_TOKEN", "")
+
+	// A local browser needs no Browserless token.
+	Configure(Config{Browser: BrowserLocal, Local: Local{Path: "/usr/bin/chromium"}})
+	key, newAllocator, err := allocator()
+	if err != nil || newAllocator == nil {
+		t.Fatalf("allocator() = %v", err)
+	}
+
+	// Changing the options changes the key, so the pool starts a new browser.
+	Configure(Config{Browser: BrowserLocal, Local: Local{Path: "/usr/bin/chromium", Headful: true}})
+	if other, _, _ := allocator(); other == key {
+		t.Error("allocator() key unchanged by the options")
+	}
+
+	Configure(Config{Browser: BrowserRemote})
+	if _, _, err := allocator(); err == nil {
+		t.Error("allocator() of a remote browser without a token succeeded")
+	}
+}
@@ -0,0 +1,245 @@
+package browserless
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Pool is a Runner sharing one browser connection between the page loads.
+// Released tabs are kept open per site, so later loads of the same site skip opening a tab.
+// All the tabs belong to the same browser, which keeps the cookies between navigations.
+// The zero value is ready to use; it connects on the first load.
+type Pool struct {
+	// MaxIdle is the number of released tabs kept open per site (default 2)
+	MaxIdle int
+
+	mu   sync.Mutex
+	conn *connection
+	idle map[string][]*tab
+}
+
+// connection is a connection to a browser.
+type connection struct {
+	key    string
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// tab is a browser tab of a connection.
+type tab struct {
+	conn   *connection
+	site   string
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// poolPage is a page loaded in a pool tab.
+type poolPage struct {
+	pool    *Pool
+	tab     *tab
+	url     string
+	broken  bool
+	capture *capture
+	// stop stops capturing the image responses, if they are
+	stop context.CancelFunc
+}
+
+// Load implements Runner by navigating a tab of the site of url.
+func (p *Pool) Load(pageURL string, waitSelector string, sleepDuration time.Duration) (Page, error) {
+	return p.load(pageURL, waitSelector, sleepDuration, false)
+}
+
+// LoadCapturing implements Capturer.
+func (p *Pool) LoadCapturing(pageURL string, waitSelector string, sleepDuration time.Duration) (CapturePage, error) {
+	return p.load(pageURL, waitSelector, sleepDuration, true)
+}
+
+// load navigates a tab of the site of url, capturing its image responses if requested.
+func (p *Pool) load(pageURL string, waitSelector string, sleepDuration time.Duration, capture bool) (*poolPage, error) {
+	t, err := p.acquire(siteOf(pageURL))
+	if err != nil {
+		logger.Error("browserless.Pool.Load: Error opening a tab: %v", err)
+		return nil, err
+	}
+	page := &poolPage{pool: p, tab: t, url: pageURL}
+	tasks := []chromedp.Action{
+		chromedp.Navigate(pageURL),
+	}
+	if capture {
+		var ctx context.Context
+		ctx, page.stop = context.WithCancel(t.ctx)
+		page.capture = newCapture(ctx)
+		tasks = append([]chromedp.Action{network.Enable()}, tasks...)
+	}
+	if waitSelector != "" {
+		tasks = append(tasks, chromedp.WaitVisible(waitSelector, chromedp.ByQuery))
+	}
+	if sleepDuration > 0 {
+		tasks = append(tasks, chromedp.Sleep(sleepDuration))
+	}
+	logger.Debug("browserless.Pool.Load: Loading %s", pageURL)
+	if err := page.run(tasks...); err != nil {
+		page.Close()
+		return nil, err
+	}
+	return page, nil
+}
+
+// Eval implements Page.
+func (pp *poolPage) Eval(js string, result interface{}) error {
+	logger.Debug("browserless.Page.Eval: Executing JS on URL: %s", pp.url)
+	return pp.run(chromedp.Evaluate(js, result))
+}
+
+// Images implements CapturePage.
+func (pp *poolPage) Images(wait time.Duration) []Image {
+	if pp.capture == nil {
+		return nil
+	}
+	return pp.capture.wait(wait)
+}
+
+// Close implements Page by releasing the tab to the pool, or closing it if a run failed.
+func (pp *poolPage) Close() {
+	if pp.tab == nil {
+		return
+	}
+	if pp.stop != nil {
+		pp.stop()
+	}
+	pp.pool.release(pp.tab, pp.broken)
+	pp.tab = nil
+}
+
+// run runs the actions in the page tab within the configured timeout.
+// A failure leaves the tab in an unknown state, so it is closed instead of being reused.
+func (pp *poolPage) run(actions ...chromedp.Action) error {
+	if pp.tab == nil {
+		return errors.New("page is closed")
+	}
+	ctx, cancel := context.WithTimeout(pp.tab.ctx, timeout())
+	defer cancel()
+	err := chromedp.Run(ctx, actions...)
+	if err != nil {
+		pp.broken = true
+	}
+	return err
+}
+
+// acquire returns an idle tab of the site, or opens one.
+func (p *Pool) acquire(site string) (*tab, error) {
+	conn, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if tabs := p.idle[site]; len(tabs) > 0 {
+		t := tabs[len(tabs)-1]
+		p.idle[site] = tabs[:len(tabs)-1]
+		p.mu.Unlock()
+		logger.Debug("browserless.Pool.acquire: Reusing a tab for %s", site)
+		return t, nil
+	}
+	p.mu.Unlock()
+
+	ctx, cancel := chromedp.NewContext(conn.ctx)
+	t := &tab{conn: conn, site: site, ctx: ctx, cancel: cancel}
+	// Open the tab now, so that the timeout of the first run does not close it.
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+	logger.Debug("browserless.Pool.acquire: Opened a tab for %s", site)
+	return t, nil
+}
+
+// release keeps the tab for later loads of its site, unless it is broken, its connection
+// was replaced or enough tabs of the site are already idle.
+func (p *Pool) release(t *tab, broken bool) {
+	maxIdle := p.MaxIdle
+	if maxIdle <= 0 {
+		maxIdle = 2
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if broken || t.conn != p.conn || t.ctx.Err() != nil || len(p.idle[t.site]) >= maxIdle {
+		t.cancel()
+		return
+	}
+	if p.idle == nil {
+		p.idle = map[string][]*tab{}
+	}
+	p.idle[t.site] = append(p.idle[t.site], t)
+}
+
+// connect returns the browser connection, connecting when there is none yet, when it was lost
+// or when the browser options changed.
+func (p *Pool) connect() (*connection, error) {
+	key, newAllocator, err := allocator()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil && p.conn.key == key && p.conn.ctx.Err() == nil {
+		return p.conn, nil
+	}
+	if p.conn != nil {
+		logger.Debug("browserless.Pool.connect: Connection lost or reconfigured, reconnecting")
+		p.closeLocked()
+	}
+
+	allocCtx, cancelAlloc := newAllocator(context.Background())
+	ctx, cancelCtx := chromedp.NewContext(allocCtx)
+	// Connect now without a timeout, which would close the browser once expired.
+	if err := chromedp.Run(ctx); err != nil {
+		cancelCtx()
+		cancelAlloc()
+		return nil, err
+	}
+	p.conn = &connection{key: key, ctx: ctx, cancel: func() {
+		cancelCtx()
+		cancelAlloc()
+	}}
+	logger.Debug("browserless.Pool.connect: Connected to the browser")
+	return p.conn, nil
+}
+
+// Close closes the idle tabs and the browser connection. Pages still loaded fail, and the
+// next load connects again.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeLocked()
+}
+
+// closeLocked closes the idle tabs and the connection, with p.mu held.
+func (p *Pool) closeLocked() {
+	for _, tabs := range p.idle {
+		for _, t := range tabs {
+			t.cancel()
+		}
+	}
+	p.idle = nil
+	if p.conn != nil {
+		p.conn.cancel()
+		p.conn = nil
+	}
+}
+
+// siteOf returns the host of the URL, the key tabs are reused by.
+func siteOf(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
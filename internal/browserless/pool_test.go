@@ -0,0 +1,92 @@
+package browserless
+
+import (
+	"context"
+	"testing"
+)
+
+// newTestPool returns a pool with a fake connection, on which no tab can be opened.
+func newTestPool(t *testing.T) *Pool {
+	prev := config
+	Configure(Config{URL: "ws://browser.test"})
+	t.Cleanup(func() { config = prev })
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Pool{MaxIdle: 2, conn: &connection{key: "ws://browser.test", ctx: ctx, cancel: cancel}}
+}
+
+// newTestTab returns a tab of the connection.
+func newTestTab(conn *connection, site string) *tab {
+	ctx, cancel := context.WithCancel(conn.ctx)
+	return &tab{conn: conn, site: site, ctx: ctx, cancel: cancel}
+}
+
+func TestPoolReusesTabsPerSite(t *testing.T) {
+	p := newTestPool(t)
+	asura := newTestTab(p.conn, "asuracomic.net")
+	p.release(asura, false)
+
+	got, err := p.acquire("asuracomic.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != asura {
+		t.Error("acquire() did not reuse the idle tab of the site")
+	}
+	if len(p.idle["asuracomic.net"]) != 0 {
+		t.Error("acquired tab still idle")
+	}
+}
+
+func TestPoolRelease(t *testing.T) {
+	p := newTestPool(t)
+	tabs := []*tab{newTestTab(p.conn, "mangamonk.com"), newTestTab(p.conn, "mangamonk.com"), newTestTab(p.conn, "mangamonk.com")}
+	for _, tb := range tabs {
+		p.release(tb, false)
+	}
+	if n := len(p.idle["mangamonk.com"]); n != 2 {
+		t.Errorf("%d idle tabs, want MaxIdle 2", n)
+	}
+	if tabs[2].ctx.Err() == nil {
+		t.Error("tab over MaxIdle not closed")
+	}
+
+	broken := newTestTab(p.conn, "asuracomic.net")
+	p.release(broken, true)
+	if broken.ctx.Err() == nil || len(p.idle["asuracomic.net"]) != 0 {
+		t.Error("broken tab kept")
+	}
+
+	old := &connection{key: "ws://old.test", ctx: context.Background()}
+	stale := newTestTab(old, "asuracomic.net")
+	p.release(stale, false)
+	if stale.ctx.Err() == nil || len(p.idle["asuracomic.net"]) != 0 {
+		t.Error("tab of a replaced connection kept")
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	p := newTestPool(t)
+	conn := p.conn
+	idle := newTestTab(conn, "asuracomic.net")
+	p.release(idle, false)
+	p.Close()
+	if idle.ctx.Err() == nil || conn.ctx.Err() == nil {
+		t.Error("Close() left the idle tab or the connection open")
+	}
+	if p.conn != nil || len(p.idle) != 0 {
+		t.Error("Close() kept the closed connection")
+	}
+}
+
+func TestSiteOf(t *testing.T) {
+	tests := map[string]string{
+		"https://asuracomic.net/series/foo/chapter/1": "asuracomic.net",
+		"https://mangamonk.com:8443/infinite-mage":    "mangamonk.com:8443",
+		"::": "",
+	}
+	for u, want := range tests {
+		if got := siteOf(u); got != want {
+			t.Errorf("siteOf(%q) = %q, want %q", u, got, want)
+		}
+	}
+}
@@ -15,6 +15,90 @@ type BrowserlessUser interface {
 	UsesBrowser() bool
 }
 
+// Browsers of Config.Browser.
+const (
+	// BrowserRemote connects to a Browserless instance
+	BrowserRemote = "remote"
+	// BrowserLocal runs a Chrome or Chromium installed locally
+	BrowserLocal = "local"
+)
+
+// Config holds the browser options: a remote Browserless connection by default, or a local browser.
+// Empty fields fall back to the BROWSERLESS_URL, BROWSERLESS_TOKEN, BROWSERLESS_HOST_IP and DOCKER
+// environment variables.
+type Config struct {
+	// Browser is BrowserRemote (default) or BrowserLocal
+	Browser string
+	// Local holds the options of the local browser
+	Local Local
+	// URL is the full devtools websocket URL, overriding Token, Host and Docker
+	URL string
+	// Token is the Browserless API token
+	Token string
+	// Host is the Browserless host IP or name (default "localhost")
+	Host string
+	// Docker connects to the comic-downloader-browserless container instead of Host
+	Docker bool
+	// Timeout is the timeout of each page load and script evaluation (default 30s)
+	Timeout time.Duration
+}
+
+// Validate checks the browser is known.
+func (c Config) Validate() error {
+	switch c.Browser {
+	case "", BrowserRemote, BrowserLocal:
+		return nil
+	}
+	return fmt.Errorf("invalid browser %q: must be %s or %s", c.Browser, BrowserLocal, BrowserRemote)
+}
+
+// config is the current Browserless configuration.
+var config Config
+
+// Configure sets the Browserless connection options.
+func Configure(c Config) {
+	config = c
+}
+
+// timeout returns the configured browser run timeout.
+func timeout() time.Duration {
+	if config.Timeout > 0 {
+		return config.Timeout
+	}
+	return 30 * time.Second
+}
+
+// devtoolsURL builds the devtools websocket URL from the configuration and the environment.
+func devtoolsURL() (string, error) {
+	if config.URL != "" {
+		return config.URL, nil
+	}
+	if u := os.Getenv("BROWSERLESS_URL"); u != "" {
+		return u, nil
+	}
+	token := config.Token
+	if token == "" {
+		token = os.Getenv("BROWSERLESS_TOKEN")
+	}
+	if token == "" {
+		logger.Error("BROWSERLESS_TOKEN must be set in .env")
+		return "", fmt.Errorf("BROWSERLESS_TOKEN must be set in .env")
+	}
+	if config.Docker || os.Getenv("DOCKER") == "true" {
+		// Use Docker container endpoint.
+		return fmt.Sprintf("ws://comic-downloader-browserless:3000?token=%s", token), nil
+	}
+	// Retrieve host IP from the configuration or environment variable, default to "localhost".
+	host := config.Host
+	if host == "" {
+		host = os.Getenv("BROWSERLESS_HOST_IP")
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("ws://%s:8454?token=%s", host, token), nil
+}
+
 func init() {
 	// Load environment variables from .env file.
 	if err := godotenv.Load(); err != nil {
@@ -23,28 +107,16 @@ func init() {
 }
 
 // NewRemoteContext creates a new chromedp context by connecting to a remote Browserless instance.
-// It requires that BROWSERLESS_TOKEN is set in your .env file.
-// When DOCKER is set to "true", it connects to the docker container endpoint;
-// otherwise, it connects to the host specified by BROWSERLESS_HOST_IP (default "localhost").
+// It requires a Browserless token, either configured or set as BROWSERLESS_TOKEN in your .env file.
+// When Docker is configured (or DOCKER is set to "true"), it connects to the docker container endpoint;
+// otherwise, it connects to the configured host or BROWSERLESS_HOST_IP (default "localhost").
 // This makes it flexible to work with hosts on different machines.
 func NewRemoteContext(devtoolsWsURL string, timeout time.Duration) (context.Context, context.CancelFunc, error) {
-	// If no URL is provided, build one from the environment.
+	// If no URL is provided, build one from the configuration and the environment.
 	if devtoolsWsURL == "" {
-		token := os.Getenv("BROWSERLESS_TOKEN")
-		if token == "" {
-			logger.Error("BROWSERLESS_TOKEN must be set in .env")
-			return nil, nil, fmt.Errorf("BROWSERLESS_TOKEN must be set in .env")
-		}
-		if os.Getenv("DOCKER") == "true" {
-			// Use Docker container endpoint.
-			devtoolsWsURL = fmt.Sprintf("ws://comic-downloader-browserless:3000?token=%s", token)
-		} else {
-			// Retrieve host IP from environment variable, default to "localhost".
-			host := os.Getenv("BROWSERLESS_HOST_IP")
-			if host == "" {
-				host = "localhost"
-			}
-			devtoolsWsURL = fmt.Sprintf("ws://%s:8454?token=%s", host, token)
+		var err error
+		if devtoolsWsURL, err = devtoolsURL(); err != nil {
+			return nil, nil, err
 		}
 	}
 	parentCtx, cancelParent := context.WithTimeout(context.Background(), timeout)
@@ -61,31 +133,58 @@ func NewRemoteContext(devtoolsWsURL string, timeout time.Duration) (context.Cont
 	return ctx, cancel, nil
 }
 
+// Page is a loaded browser page, on which several scripts can be evaluated.
+type Page interface {
+	// Eval evaluates js on the page into result
+	Eval(js string, result interface{}) error
+	// Close releases the page; it must be called once done with it
+	Close()
+}
+
+// Runner loads browser pages.
+type Runner interface {
+	// Load navigates to url, waits for waitSelector and sleepDuration (if set) and returns the loaded page
+	Load(url string, waitSelector string, sleepDuration time.Duration) (Page, error)
+}
+
+// runner is the Runner used by Load and RunJS.
+var runner Runner = &Pool{}
+
+// SetRunner replaces the Runner used by Load and RunJS and returns the previous one.
+// Tests use it to replace the browser with a fake: defer browserless.SetRunner(browserless.SetRunner(fake)).
+func SetRunner(r Runner) Runner {
+	prev := runner
+	runner = r
+	return prev
+}
+
+// Close closes the browser connection of the current Runner, if it has one.
+func Close() {
+	if c, ok := runner.(interface{ Close() }); ok {
+		c.Close()
+	}
+}
+
+// Load navigates to the given URL, optionally waits for a CSS selector to be visible and
+// sleeps for the specified duration (if any), and returns the page to evaluate scripts on.
+// The page must be closed once done with it.
+func Load(url string, waitSelector string, sleepDuration time.Duration) (Page, error) {
+	return runner.Load(url, waitSelector, sleepDuration)
+}
+
 // RunJS navigates to the given URL, optionally waits for a CSS selector to be visible,
 // sleeps for the specified duration (if any), and then evaluates the provided JavaScript snippet.
 func RunJS(url string, waitSelector string, sleepDuration time.Duration, js string, result interface{}) error {
-	ctx, cancel, err := NewRemoteContext("", 30*time.Second)
+	page, err := Load(url, waitSelector, sleepDuration)
 	if err != nil {
-		logger.Error("browserless.RunJS: Error creating remote context: %v", err)
 		return err
 	}
-	defer cancel()
-	tasks := []chromedp.Action{
-		chromedp.Navigate(url),
-	}
-	if waitSelector != "" {
-		tasks = append(tasks, chromedp.WaitVisible(waitSelector, chromedp.ByQuery))
-	}
-	if sleepDuration > 0 {
-		tasks = append(tasks, chromedp.Sleep(sleepDuration))
-	}
-	tasks = append(tasks, chromedp.Evaluate(js, result))
-	logger.Debug("browserless.RunJS: Executing JS on URL: %s", url)
-	return chromedp.Run(ctx, tasks...)
+	defer page.Close()
+	return page.Eval(js, result)
 }
 
-// FetchStringWithProgress wraps a RunJS call that returns a string.
-func FetchStringWithProgress(url, waitSelector, js string, timeout time.Duration, progressCallback func()) (string, error) {
+// WithProgress calls progressCallback every 250ms until the returned stop function is called.
+func WithProgress(progressCallback func()) (stop func()) {
 	done := make(chan struct{})
 	go func() {
 		ticker := time.NewTicker(250 * time.Millisecond)
@@ -101,10 +200,15 @@ func FetchStringWithProgress(url, waitSelector, js string, timeout time.Duration
 			}
 		}
 	}()
+	return func() { close(done) }
+}
 
+// FetchStringWithProgress wraps a RunJS call that returns a string.
+func FetchStringWithProgress(url, waitSelector, js string, timeout time.Duration, progressCallback func()) (string, error) {
+	stop := WithProgress(progressCallback)
 	var res string
 	err := RunJS(url, waitSelector, timeout, js, &res)
-	close(done)
+	stop()
 	if err != nil {
 		logger.Error("browserless.FetchStringWithProgress: Error: %v", err)
 	} else {
@@ -115,25 +219,10 @@ func FetchStringWithProgress(url, waitSelector, js string, timeout time.Duration
 
 // FetchStringSliceWithProgress wraps a RunJS call that returns a []string.
 func FetchStringSliceWithProgress(url, waitSelector, js string, timeout time.Duration, progressCallback func()) ([]string, error) {
-	done := make(chan struct{})
-	go func() {
-		ticker := time.NewTicker(250 * time.Millisecond)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				if progressCallback != nil {
-					progressCallback()
-				}
-			case <-done:
-				return
-			}
-		}
-	}()
-
+	stop := WithProgress(progressCallback)
 	var res []string
 	err := RunJS(url, waitSelector, timeout, js, &res)
-	close(done)
+	stop()
 	if err != nil {
 		logger.Error("browserless.FetchStringSliceWithProgress: Error: %v", err)
 	} else {
@@ -0,0 +1,404 @@
+// Package config loads the comic-downloader configuration file.
+//
+// The configuration file is a YAML document whose top-level keys are the long names of the
+// command flags (e.g. "format", "output-dir", "language"), plus "browserless" and "http"
+// sections for the connection options. Named profiles and per-domain overrides use the same keys:
+//
+//	format: cbz
+//	output-dir: ~/Comics
+//	profile: ereader
+//	browserless:
+//	  host: 192.168.1.10
+//	  token: xxx
+//	  # or browser: local, with local: {path, headless, user-data-dir, flags}
+//	http:
+//	  timeout: 30s
+//	profiles:
+//	  ereader:
+//	    format: cbz
+//	    bundle-by: volume
+//	  archive:
+//	    format: raw
+//	domains:
+//	  mangadex.org:
+//	    language: es-la,es,en
+//	hooks:
+//	  - on: [series]
+//	    ntfy: https://ntfy.sh/my-topic
+//
+// Values are resolved with the following precedence, from highest to lowest:
+// command flags, environment variables, per-domain overrides, the selected profile,
+// the top-level settings of the file and the built-in defaults. Hooks are cumulative:
+// the hooks of the top-level settings, the profile and the domains all fire.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/browserless"
+	"github.com/NorkzYT/comic-downloader/internal/hooks"
+	"github.com/NorkzYT/comic-downloader/internal/http"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding flags
+// (e.g. COMIC_DOWNLOADER_OUTPUT_DIR for --output-dir).
+const EnvPrefix = "COMIC_DOWNLOADER_"
+
+// File is a configuration file.
+type File struct {
+	// Section holds the top-level settings
+	Section
+	// Profile is the profile used when none is given on the command line
+	Profile string
+	// Profiles are the named profiles
+	Profiles map[string]Section
+	// Domains are the per-domain overrides, keyed by host (e.g. "mangadex.org")
+	Domains map[string]Section
+}
+
+// UnmarshalYAML decodes the file, collecting the top-level settings into its Section.
+func (f *File) UnmarshalYAML(node *yaml.Node) error {
+	meta := struct {
+		Profile  string             `yaml:"profile"`
+		Profiles map[string]Section `yaml:"profiles"`
+		Domains  map[string]Section `yaml:"domains"`
+	}{}
+	if err := node.Decode(&meta); err != nil {
+		return err
+	}
+	if err := node.Decode(&f.Section); err != nil {
+		return err
+	}
+	for _, k := range []string{"profile", "profiles", "domains"} {
+		delete(f.Section.Flags, k)
+	}
+	f.Profile, f.Profiles, f.Domains = meta.Profile, meta.Profiles, meta.Domains
+	return nil
+}
+
+// Section is a set of settings, used for the top-level settings, profiles and domains.
+type Section struct {
+	// Browserless holds the Browserless connection options
+	Browserless Browserless `yaml:"browserless"`
+	// HTTP holds the HTTP client options
+	HTTP HTTP `yaml:"http"`
+	// Hooks are run on download events
+	Hooks []hooks.Hook `yaml:"hooks"`
+	// Flags holds every other key, named after the long command flags
+	Flags map[string]interface{} `yaml:",inline"`
+}
+
+// Browserless holds the browser options: the Browserless connection options, or the local
+// browser ones when Browser is "local".
+type Browserless struct {
+	Browser string        `yaml:"browser"`
+	URL     string        `yaml:"url"`
+	Token   string        `yaml:"token"`
+	Host    string        `yaml:"host"`
+	Docker  *bool         `yaml:"docker"`
+	Timeout time.Duration `yaml:"timeout"`
+	Local   Local         `yaml:"local"`
+}
+
+// Local holds the local browser options.
+type Local struct {
+	Path        string   `yaml:"path"`
+	Headless    *bool    `yaml:"headless"`
+	UserDataDir string   `yaml:"user-data-dir"`
+	Flags       []string `yaml:"flags"`
+}
+
+// HTTP holds the HTTP client options.
+type HTTP struct {
+	Timeout   time.Duration `yaml:"timeout"`
+	UserAgent string        `yaml:"user-agent"`
+}
+
+// DefaultPath returns the default configuration file path
+// ($XDG_CONFIG_HOME/comic-downloader/config.yaml on Linux).
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "comic-downloader", "config.yaml")
+}
+
+// Load reads the configuration file at path. When path is empty the default path is used,
+// and a missing default file is not an error.
+func Load(path string) (*File, error) {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+	f := &File{}
+	if path == "" {
+		return f, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return f, nil
+		}
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Resolve merges the top-level settings, the given profile (or the file default profile)
+// and the overrides of the domain matching rawURL, in increasing order of precedence.
+func (f *File) Resolve(profile, rawURL string) (Section, error) {
+	s := Section{}.merge(f.Section)
+
+	if profile == "" {
+		profile = f.Profile
+	}
+	if profile != "" {
+		p, ok := f.Profiles[profile]
+		if !ok {
+			return s, fmt.Errorf("unknown profile %q", profile)
+		}
+		s = s.merge(p)
+	}
+
+	if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" {
+		// Apply the least specific domains first so "www.example.com" wins over "example.com".
+		domains := make([]string, 0, len(f.Domains))
+		for d := range f.Domains {
+			domains = append(domains, d)
+		}
+		sort.Slice(domains, func(i, j int) bool { return len(domains[i]) < len(domains[j]) })
+		for _, d := range domains {
+			if matchesDomain(u.Hostname(), d) {
+				s = s.merge(f.Domains[d])
+			}
+		}
+	}
+	return s, nil
+}
+
+// matchesDomain reports whether host is domain or one of its subdomains.
+func matchesDomain(host, domain string) bool {
+	host, domain = strings.ToLower(host), strings.ToLower(strings.TrimPrefix(domain, "."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// merge returns s overridden by the values set in o.
+func (s Section) merge(o Section) Section {
+	flags := map[string]interface{}{}
+	for k, v := range s.Flags {
+		flags[k] = v
+	}
+	for k, v := range o.Flags {
+		flags[k] = v
+	}
+	s.Flags = flags
+
+	if o.Browserless.Browser != "" {
+		s.Browserless.Browser = o.Browserless.Browser
+	}
+	if o.Browserless.URL != "" {
+		s.Browserless.URL = o.Browserless.URL
+	}
+	if o.Browserless.Token != "" {
+		s.Browserless.Token = o.Browserless.Token
+	}
+	if o.Browserless.Host != "" {
+		s.Browserless.Host = o.Browserless.Host
+	}
+	if o.Browserless.Docker != nil {
+		s.Browserless.Docker = o.Browserless.Docker
+	}
+	if o.Browserless.Timeout != 0 {
+		s.Browserless.Timeout = o.Browserless.Timeout
+	}
+	if o.Browserless.Local.Path != "" {
+		s.Browserless.Local.Path = o.Browserless.Local.Path
+	}
+	if o.Browserless.Local.Headless != nil {
+		s.Browserless.Local.Headless = o.Browserless.Local.Headless
+	}
+	if o.Browserless.Local.UserDataDir != "" {
+		s.Browserless.Local.UserDataDir = o.Browserless.Local.UserDataDir
+	}
+	if o.Browserless.Local.Flags != nil {
+		s.Browserless.Local.Flags = o.Browserless.Local.Flags
+	}
+	if o.HTTP.Timeout != 0 {
+		s.HTTP.Timeout = o.HTTP.Timeout
+	}
+	if o.HTTP.UserAgent != "" {
+		s.HTTP.UserAgent = o.HTTP.UserAgent
+	}
+	s.Hooks = append(append([]hooks.Hook(nil), s.Hooks...), o.Hooks...)
+	return s
+}
+
+// ApplyFlags sets every flag not changed on the command line, first from its environment
+// variable and then from the section. Unknown keys in the section are reported as errors.
+func (s Section) ApplyFlags(flags *pflag.FlagSet) error {
+	var errs []error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			return
+		}
+		if v, ok := os.LookupEnv(EnvName(f.Name)); ok {
+			if err := flags.Set(f.Name, v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %w", EnvName(f.Name), err))
+			}
+		}
+	})
+	errs = append(errs, s.setFlags(flags, false))
+	return errors.Join(errs...)
+}
+
+// OverrideFlags sets every flag from the section, even the ones already changed.
+// Unknown keys in the section are reported as errors.
+func (s Section) OverrideFlags(flags *pflag.FlagSet) error {
+	return s.setFlags(flags, true)
+}
+
+// setFlags sets the flags from the section; changed flags are skipped unless force is set.
+func (s Section) setFlags(flags *pflag.FlagSet, force bool) error {
+	var errs []error
+	keys := make([]string, 0, len(s.Flags))
+	for k := range s.Flags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f := flags.Lookup(k)
+		if f == nil {
+			errs = append(errs, fmt.Errorf("unknown setting %q", k))
+			continue
+		}
+		if f.Changed && !force {
+			continue
+		}
+		if err := flags.Set(k, toFlagValue(s.Flags[k])); err != nil {
+			errs = append(errs, fmt.Errorf("invalid setting %q: %w", k, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Known returns a copy of the section without the keys that are not flags of the flag set,
+// for commands only accepting a subset of the settings.
+func (s Section) Known(flags *pflag.FlagSet) Section {
+	known := map[string]interface{}{}
+	for k, v := range s.Flags {
+		if flags.Lookup(k) != nil {
+			known[k] = v
+		}
+	}
+	s.Flags = known
+	return s
+}
+
+// BrowserlessConfig returns the browser options of the section overridden by the
+// BROWSERLESS_URL, BROWSERLESS_TOKEN, BROWSERLESS_HOST_IP, BROWSERLESS_TIMEOUT and DOCKER
+// environment variables, and by the COMIC_DOWNLOADER_BROWSER, COMIC_DOWNLOADER_BROWSER_PATH,
+// COMIC_DOWNLOADER_BROWSER_HEADLESS and COMIC_DOWNLOADER_BROWSER_USER_DATA_DIR ones.
+func (s Section) BrowserlessConfig() (browserless.Config, error) {
+	b := s.Browserless
+	c := browserless.Config{
+		Browser: envOr(EnvName("browser"), b.Browser),
+		URL:     envOr("BROWSERLESS_URL", b.URL),
+		Token:   envOr("BROWSERLESS_TOKEN", b.Token),
+		Host:    envOr("BROWSERLESS_HOST_IP", b.Host),
+		Docker:  b.Docker != nil && *b.Docker,
+		Timeout: b.Timeout,
+		Local: browserless.Local{
+			Path:        envOr(EnvName("browser-path"), toFlagValue(b.Local.Path)),
+			Headful:     b.Local.Headless != nil && !*b.Local.Headless,
+			UserDataDir: envOr(EnvName("browser-user-data-dir"), toFlagValue(b.Local.UserDataDir)),
+			Flags:       b.Local.Flags,
+		},
+	}
+	if v, ok := os.LookupEnv(EnvName("browser-headless")); ok {
+		headless, err := strconv.ParseBool(v)
+		if err != nil {
+			return c, fmt.Errorf("invalid %s: %w", EnvName("browser-headless"), err)
+		}
+		c.Local.Headful = !headless
+	}
+	if v, ok := os.LookupEnv("DOCKER"); ok {
+		c.Docker = v == "true"
+	}
+	if v, ok := os.LookupEnv("BROWSERLESS_TIMEOUT"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return c, fmt.Errorf("invalid BROWSERLESS_TIMEOUT: %w", err)
+		}
+		c.Timeout = d
+	}
+	return c, c.Validate()
+}
+
+// HTTPConfig returns the HTTP options of the section overridden by the
+// COMIC_DOWNLOADER_HTTP_TIMEOUT and COMIC_DOWNLOADER_HTTP_USER_AGENT environment variables.
+func (s Section) HTTPConfig() (http.Config, error) {
+	c := http.Config{
+		Timeout:   s.HTTP.Timeout,
+		UserAgent: envOr(EnvPrefix+"HTTP_USER_AGENT", s.HTTP.UserAgent),
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "HTTP_TIMEOUT"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return c, fmt.Errorf("invalid %sHTTP_TIMEOUT: %w", EnvPrefix, err)
+		}
+		c.Timeout = d
+	}
+	return c, nil
+}
+
+// envOr returns the value of the environment variable, or def if it is unset or empty.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// EnvName returns the environment variable overriding the given flag.
+func EnvName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// toFlagValue converts a YAML value to its flag string representation.
+// Lists are joined with commas and "~/" is expanded in strings.
+func toFlagValue(v interface{}) string {
+	switch val := v.(type) {
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, p := range val {
+			parts[i] = toFlagValue(p)
+		}
+		return strings.Join(parts, ",")
+	case string:
+		if strings.HasPrefix(val, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				return filepath.Join(home, val[2:])
+			}
+		}
+		return val
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(val)
+	}
+}
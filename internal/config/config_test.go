@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+const testConfig = `
+format: cbz
+language: en
+output-dir: /comics
+profile: archive
+http:
+  timeout: 10s
+  user-agent: top
+hooks:
+  - on: [series]
+    command: echo top
+profiles:
+  archive:
+    format: raw
+    bundle: true
+    hooks:
+      - on: [series]
+        command: echo archive
+  ereader:
+    format: zip
+domains:
+  mangadex.org:
+    language: es-la,es
+    http:
+      user-agent: mangadex
+  api.mangadex.org:
+    language: fr
+`
+
+// loadTestConfig writes the configuration to a temporary file and loads it.
+func loadTestConfig(t *testing.T, data string) *File {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// testFlags returns a flag set with a few of the command flags.
+func testFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("format", "cbz", "")
+	flags.String("language", "", "")
+	flags.String("output-dir", "./", "")
+	flags.Bool("bundle", false, "")
+	return flags
+}
+
+func TestResolve(t *testing.T) {
+	f := loadTestConfig(t, testConfig)
+
+	tests := []struct {
+		name, profile, url string
+		format, language   string
+		userAgent          string
+	}{
+		{"default profile", "", "https://example.com/manga", "raw", "en", "top"},
+		{"explicit profile", "ereader", "https://example.com/manga", "zip", "en", "top"},
+		{"domain over profile", "ereader", "https://mangadex.org/title/1", "zip", "es-la,es", "mangadex"},
+		{"subdomain", "", "https://www.mangadex.org/title/1", "raw", "es-la,es", "mangadex"},
+		{"most specific domain", "", "https://api.mangadex.org/manga", "raw", "fr", "mangadex"},
+		{"no URL", "", "", "raw", "en", "top"},
+	}
+	for _, tt := range tests {
+		s, err := f.Resolve(tt.profile, tt.url)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if s.Flags["format"] != tt.format || s.Flags["language"] != tt.language || s.HTTP.UserAgent != tt.userAgent {
+			t.Errorf("%s: format %v, language %v, user agent %q; want %s, %s, %q", tt.name, s.Flags["format"], s.Flags["language"], s.HTTP.UserAgent, tt.format, tt.language, tt.userAgent)
+		}
+		if s.HTTP.Timeout.String() != "10s" {
+			t.Errorf("%s: timeout %v, want the top-level 10s", tt.name, s.HTTP.Timeout)
+		}
+	}
+
+	if _, err := f.Resolve("kindle", ""); err == nil {
+		t.Error("Resolve accepted an unknown profile")
+	}
+}
+
+func TestResolveHooks(t *testing.T) {
+	f := loadTestConfig(t, testConfig)
+	s, err := f.Resolve("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Hooks) != 2 || s.Hooks[0].Command != "echo top" || s.Hooks[1].Command != "echo archive" {
+		t.Errorf("hooks = %+v, want the top-level and the profile ones", s.Hooks)
+	}
+	s, err = f.Resolve("ereader", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Hooks) != 1 {
+		t.Errorf("hooks = %+v, want the top-level one", s.Hooks)
+	}
+}
+
+func TestApplyFlags(t *testing.T) {
+	f := loadTestConfig(t, testConfig)
+	s, err := f.Resolve("", "https://mangadex.org/title/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Command flags win over the environment, which wins over the file.
+	flags := testFlags()
+	if err := flags.Parse([]string{"--format", "zip"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvName("format"), "raw")
+	t.Setenv(EnvName("output-dir"), "/env")
+	if err := s.ApplyFlags(flags); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"format": "zip", "output-dir": "/env", "language": "es-la,es", "bundle": "true"}
+	for name, v := range want {
+		if got := flags.Lookup(name).Value.String(); got != v {
+			t.Errorf("--%s = %q, want %q", name, got, v)
+		}
+	}
+}
+
+func TestApplyFlagsUnknownSetting(t *testing.T) {
+	f := loadTestConfig(t, "format: cbz\nlanguge: en\n")
+	if err := f.Section.ApplyFlags(testFlags()); err == nil {
+		t.Error("ApplyFlags accepted an unknown setting")
+	}
+	if err := f.Section.Known(testFlags()).ApplyFlags(testFlags()); err != nil {
+		t.Errorf("ApplyFlags of the known settings: %v", err)
+	}
+}
+
+func TestOverrideFlags(t *testing.T) {
+	flags := testFlags()
+	if err := flags.Parse([]string{"--format", "zip"}); err != nil {
+		t.Fatal(err)
+	}
+	s := Section{Flags: map[string]interface{}{"format": "raw"}}
+	if err := s.OverrideFlags(flags); err != nil {
+		t.Fatal(err)
+	}
+	if got := flags.Lookup("format").Value.String(); got != "raw" {
+		t.Errorf("--format = %q, want the overriding raw", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load accepted a missing explicit file")
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("format: [cbz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Load accepted an invalid file")
+	}
+}
@@ -0,0 +1,187 @@
+// Package doctor checks that the site grabbers still work, one scraping step after the other,
+// to detect site layout and API changes that would otherwise fail silently.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/downloader"
+	"github.com/NorkzYT/comic-downloader/internal/grabber"
+)
+
+// StepImage downloads the first page of the first chapter, after the grabber steps.
+const StepImage = "image"
+
+// Step is the outcome of a check step.
+type Step struct {
+	// Name is grabber.StepTitle, grabber.StepChapters, grabber.StepPages or StepImage
+	Name string `json:"name"`
+	// Selector is the CSS selector or API endpoint the step depends on, when the site reports it
+	Selector string `json:"selector,omitempty"`
+	// Result summarizes what the step found
+	Result string `json:"result,omitempty"`
+	// Error is the failure, empty when the step passed
+	Error string `json:"error,omitempty"`
+	// Duration is the time the step took
+	Duration time.Duration `json:"duration"`
+}
+
+// OK reports whether the step passed.
+func (s Step) OK() bool {
+	return s.Error == ""
+}
+
+// Report is the outcome of checking a site. Steps stop at the first failure.
+type Report struct {
+	// URL is the checked comic URL
+	URL string `json:"url"`
+	// Site is the site host
+	Site string `json:"site"`
+	// Steps are the steps run, in order
+	Steps []Step `json:"steps"`
+}
+
+// OK reports whether every step passed.
+func (r Report) OK() bool {
+	for _, s := range r.Steps {
+		if !s.OK() {
+			return false
+		}
+	}
+	return len(r.Steps) > 0
+}
+
+// Failed returns the failed step, or nil.
+func (r Report) Failed() *Step {
+	for i := range r.Steps {
+		if !r.Steps[i].OK() {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+// Check runs the title, chapters and pages steps of the site for the comic URL, then downloads
+// the first page. Steps are reported to progress, if set, as they complete.
+func Check(site grabber.Site, comicURL string, progress func(Step)) Report {
+	r := Report{URL: comicURL, Site: comicURL}
+	if u, err := url.Parse(comicURL); err == nil && u.Hostname() != "" {
+		r.Site = strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	selectors := map[string]string{}
+	if s, ok := site.(interface{ Selectors() map[string]string }); ok {
+		selectors = s.Selectors()
+	}
+	run := func(name string, fn func() (string, error)) bool {
+		start := time.Now()
+		res, err := fn()
+		step := Step{Name: name, Selector: selectors[name], Result: res, Duration: time.Since(start)}
+		if err != nil {
+			step.Error = err.Error()
+		}
+		r.Steps = append(r.Steps, step)
+		if progress != nil {
+			progress(step)
+		}
+		return err == nil
+	}
+
+	var (
+		chapters grabber.Filterables
+		chapter  *grabber.Chapter
+	)
+	ok := run(grabber.StepTitle, func() (string, error) {
+		title, err := site.FetchTitle()
+		if err == nil && strings.TrimSpace(title) == "" {
+			err = errors.New("empty title")
+		}
+		return title, err
+	}) && run(grabber.StepChapters, func() (string, error) {
+		var errs []error
+		chapters, errs = site.FetchChapters()
+		if len(errs) > 0 {
+			return "", errors.Join(errs...)
+		}
+		return checkChapters(chapters)
+	}) && run(grabber.StepPages, func() (string, error) {
+		first := firstHosted(chapters.SortByNumber())
+		if first == nil {
+			return "", errors.New("every chapter is hosted on an external site")
+		}
+		var err error
+		if chapter, err = site.FetchChapter(first); err != nil {
+			return "", fmt.Errorf("chapter %s: %w", first.GetTitle(), err)
+		}
+		return checkPages(chapter)
+	})
+	if ok {
+		run(StepImage, func() (string, error) {
+			return checkImage(site, chapter.Pages[0])
+		})
+	}
+	return r
+}
+
+// checkChapters checks chapters were found and their numbers parsed.
+func checkChapters(chapters grabber.Filterables) (string, error) {
+	if len(chapters) == 0 {
+		return "", errors.New("no chapters found")
+	}
+	numbered := 0
+	for _, c := range chapters {
+		if c.GetNumber() != 0 {
+			numbered++
+		}
+	}
+	res := fmt.Sprintf("%d chapters", len(chapters))
+	if len(chapters) > 1 && numbered == 0 {
+		return res, errors.New("no chapter number could be parsed")
+	}
+	return res, nil
+}
+
+// firstHosted returns the first chapter not hosted on an external site, nil if there is none.
+func firstHosted(chapters grabber.Filterables) grabber.Filterable {
+	for _, c := range chapters {
+		if grabber.ExternalURL(c) == "" {
+			return c
+		}
+	}
+	return nil
+}
+
+// checkPages checks the chapter has pages, all with a URL.
+func checkPages(chapter *grabber.Chapter) (string, error) {
+	if len(chapter.Pages) == 0 {
+		return "", fmt.Errorf("no pages found in chapter %s", chapter.GetTitle())
+	}
+	for _, p := range chapter.Pages {
+		if p.URL == "" {
+			return "", fmt.Errorf("page %d of chapter %s has no URL", p.Number, chapter.GetTitle())
+		}
+	}
+	return fmt.Sprintf("%d pages in chapter %s", len(chapter.Pages), chapter.GetTitle()), nil
+}
+
+// checkImage downloads the page, unless the site captured it, and checks it is not a text (i.e. an error or challenge page).
+// Formats the standard library does not detect, such as AVIF, pass.
+func checkImage(site grabber.Site, page grabber.Page) (string, error) {
+	file, err := downloader.FetchPage(context.Background(), site, page)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", page.URL, err)
+	}
+	if len(file.Data) == 0 {
+		return "", fmt.Errorf("%s: empty response", page.URL)
+	}
+	ct := nethttp.DetectContentType(file.Data)
+	if strings.HasPrefix(ct, "text/") {
+		return "", fmt.Errorf("%s: got %s instead of an image", page.URL, ct)
+	}
+	return fmt.Sprintf("%s, %d KiB", ct, len(file.Data)/1024), nil
+}
@@ -0,0 +1,94 @@
+package doctor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NorkzYT/comic-downloader/internal/grabber"
+	"github.com/spf13/cobra"
+)
+
+// fakeSite is a grabber.Site returning canned results.
+type fakeSite struct {
+	title    string
+	chapters grabber.Filterables
+	pages    []grabber.Page
+}
+
+func (f *fakeSite) InitFlags(*cobra.Command)    {}
+func (f *fakeSite) Test() (bool, error)         { return true, nil }
+func (f *fakeSite) FetchTitle() (string, error) { return f.title, nil }
+func (f *fakeSite) FetchChapters() (grabber.Filterables, []error) {
+	return f.chapters, nil
+}
+func (f *fakeSite) FetchChapter(c grabber.Filterable) (*grabber.Chapter, error) {
+	if f.pages == nil {
+		return nil, errors.New("timeout waiting for selector")
+	}
+	return &grabber.Chapter{Title: c.GetTitle(), Number: c.GetNumber(), Pages: f.pages}, nil
+}
+func (f *fakeSite) BaseUrl() string                           { return "https://example.com" }
+func (f *fakeSite) GetFilenameTemplate() string               { return "" }
+func (f *fakeSite) GetMaxConcurrency() grabber.MaxConcurrency { return grabber.MaxConcurrency{} }
+func (f *fakeSite) GetPreferredLanguage() string              { return "" }
+func (f *fakeSite) Selectors() map[string]string {
+	return map[string]string{grabber.StepTitle: "h1", grabber.StepChapters: "ul li", grabber.StepPages: "img.page"}
+}
+
+func TestCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/page.png" {
+			_, _ = w.Write([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+			return
+		}
+		_, _ = w.Write([]byte("<html><body>Just a moment...</body></html>"))
+	}))
+	defer srv.Close()
+
+	chapters := grabber.Filterables{
+		&grabber.Chapter{Title: "Chapter 2", Number: 2},
+		&grabber.Chapter{Title: "Chapter 1", Number: 1},
+	}
+	tests := []struct {
+		name   string
+		site   *fakeSite
+		failed string
+		error  string
+	}{
+		{"working", &fakeSite{title: "Comic", chapters: chapters, pages: []grabber.Page{{Number: 1, URL: srv.URL + "/page.png"}}}, "", ""},
+		{"empty title", &fakeSite{title: " "}, grabber.StepTitle, "empty title"},
+		{"no chapters", &fakeSite{title: "Comic"}, grabber.StepChapters, "no chapters found"},
+		{"unnumbered chapters", &fakeSite{title: "Comic", chapters: grabber.Filterables{&grabber.Chapter{}, &grabber.Chapter{}}}, grabber.StepChapters, "no chapter number could be parsed"},
+		{"pages error", &fakeSite{title: "Comic", chapters: chapters}, grabber.StepPages, "chapter Chapter 1: timeout waiting for selector"},
+		{"no pages", &fakeSite{title: "Comic", chapters: chapters, pages: []grabber.Page{}}, grabber.StepPages, "no pages found in chapter Chapter 1"},
+		{"challenge page", &fakeSite{title: "Comic", chapters: chapters, pages: []grabber.Page{{Number: 1, URL: srv.URL + "/challenge"}}}, StepImage, srv.URL + "/challenge: got text/html; charset=utf-8 instead of an image"},
+	}
+	for _, tt := range tests {
+		var steps []string
+		r := Check(tt.site, "https://www.example.com/comic", func(s Step) { steps = append(steps, s.Name) })
+		if r.Site != "example.com" {
+			t.Errorf("%s: Site = %q", tt.name, r.Site)
+		}
+		if len(steps) != len(r.Steps) {
+			t.Errorf("%s: reported %d steps, want %d", tt.name, len(steps), len(r.Steps))
+		}
+		failed := r.Failed()
+		if tt.failed == "" {
+			if !r.OK() || failed != nil || len(r.Steps) != 4 {
+				t.Errorf("%s: got %+v, want 4 passed steps", tt.name, r.Steps)
+			}
+			continue
+		}
+		if r.OK() || failed == nil {
+			t.Fatalf("%s: got %+v, want a failure", tt.name, r.Steps)
+		}
+		if failed.Name != tt.failed || failed.Error != tt.error || r.Steps[len(r.Steps)-1].Name != tt.failed {
+			t.Errorf("%s: failed step %s %q, want %s %q as last step", tt.name, failed.Name, failed.Error, tt.failed, tt.error)
+		}
+		if want := tt.site.Selectors()[tt.failed]; failed.Selector != want {
+			t.Errorf("%s: Selector = %q, want %q", tt.name, failed.Selector, want)
+		}
+	}
+}
@@ -1,17 +1,24 @@
 package downloader
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	nethttp "net/http"
 	"sort"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/NorkzYT/comic-downloader/internal/grabber"
 	"github.com/NorkzYT/comic-downloader/internal/http"
 	"github.com/NorkzYT/comic-downloader/internal/logger"
+	"golang.org/x/sync/errgroup"
 )
 
+// retryDelay is the wait before downloading a file again.
+var retryDelay = 500 * time.Millisecond
+
 // File represents a downloaded file.
 type File struct {
 	Data []byte
@@ -21,90 +28,161 @@ type File struct {
 // ProgressCallback is a function type for progress updates with optional error.
 type ProgressCallback func(page, progress int, err error)
 
-// FetchChapter downloads all the pages of a chapter.
+// FetchChapter downloads all the pages of a chapter, with the page concurrency of the site.
 func FetchChapter(site grabber.Site, chapter *grabber.Chapter, onprogress ProgressCallback) (files []*File, err error) {
+	mc := site.GetMaxConcurrency()
+	return NewScheduler(int(mc.Pages), int(mc.PerHost)).FetchChapter(context.Background(), site, chapter, 0, onprogress)
+}
+
+// FetchChapter downloads all the pages of a chapter on the scheduler workers. position is the
+// position of the chapter in the download, the pages of the first chapters being downloaded first.
+//
+// The first failed page cancels the other pages, whether waiting for a worker or downloading,
+// as does ctx. FetchChapter returns once every page download has stopped, with the errors of
+// all the failed pages.
+func (s *Scheduler) FetchChapter(ctx context.Context, site grabber.Site, chapter *grabber.Chapter, position int, onprogress ProgressCallback) ([]*File, error) {
 	logger.Debug("downloader.FetchChapter: Starting download for chapter %s", chapter.GetTitle())
-	wg := sync.WaitGroup{}
-	guard := make(chan struct{}, site.GetMaxConcurrency().Pages)
-	errChan := make(chan error, 1)
-	done := make(chan bool)
-	files = make([]*File, len(chapter.Pages)) // Pre-allocate slice.
+	g, gctx := errgroup.WithContext(ctx)
+	// errgroup cancels gctx once the failed page returns, after its worker went to a waiting
+	// page: cancel it as soon as the download fails instead.
+	gctx, cancel := context.WithCancel(gctx)
+	defer cancel()
+	files := make([]*File, len(chapter.Pages))
+	errs := make([]error, len(chapter.Pages))
 
 	for i, page := range chapter.Pages {
-		guard <- struct{}{}
-		wg.Add(1)
-		go func(page grabber.Page, idx int) {
-			defer wg.Done()
-			file, err := FetchFile(http.RequestParams{
-				URL:     page.URL,
-				Referer: site.BaseUrl(),
-			}, uint(page.Number))
+		g.Go(func() error {
+			var file *File
+			err := s.Do(gctx, Priority{Chapter: position, Page: page.Number}, page.URL, func() (err error) {
+				if file, err = fetchChapterPage(gctx, site, chapter, page); err != nil {
+					cancel()
+				}
+				return err
+			})
 
 			pn := int(page.Number)
 			cp := pn * 100 / len(chapter.Pages)
 
 			if err != nil {
-				select {
-				case errChan <- fmt.Errorf("page %d: %w", page.Number, err):
-					onprogress(pn, cp, err)
-				default:
+				if gctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+					// Stopped by the failure of another page or by ctx: not a failure of the page.
+					return nil
 				}
-				<-guard
-				return
+				errs[i] = fmt.Errorf("page %d: %w", page.Number, err)
+				onprogress(pn, cp, err)
+				return errs[i]
 			}
 
-			files[idx] = file
+			files[i] = file
 			onprogress(pn, cp, nil)
-			<-guard
-		}(page, i)
+			return nil
+		})
 	}
-
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
-
-	select {
-	case err := <-errChan:
-		close(guard)
+	if err := g.Wait(); err != nil {
+		err = errors.Join(errs...)
 		logger.Error("downloader.FetchChapter: Error downloading chapter: %v", err)
 		return nil, err
-	case <-done:
-		close(guard)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	sort.SliceStable(files, func(i, j int) bool {
 		return files[i].Page < files[j].Page
 	})
 	logger.Debug("downloader.FetchChapter: Successfully downloaded chapter %s", chapter.GetTitle())
-	return
+	return files, nil
+}
+
+// fetchChapterPage downloads a page of the chapter. Sites implementing grabber.PageResolver
+// resolve its URL first, and again if it is refused with a 403; then, while the download
+// fails and ctx is not done, the chapter Refresher is asked for a new URL to try.
+func fetchChapterPage(ctx context.Context, site grabber.Site, chapter *grabber.Chapter, page grabber.Page) (*File, error) {
+	resolver, _ := site.(grabber.PageResolver)
+	if resolver != nil {
+		resolved, err := resolver.ResolvePage(chapter, page, false)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving the page URL: %w", err)
+		}
+		page = resolved
+	}
+	file, err := FetchPage(ctx, site, page)
+	if resolver != nil && http.IsStatus(err, nethttp.StatusForbidden) {
+		logger.Debug("downloader.fetchChapterPage: Page %d refused, resolving it again", page.Number)
+		if resolved, rerr := resolver.ResolvePage(chapter, page, true); rerr == nil {
+			page = resolved
+			file, err = FetchPage(ctx, site, page)
+		}
+	}
+	for err != nil && chapter.Refresher != nil && ctx.Err() == nil {
+		refreshed, rerr := chapter.Refresher.RefreshPage(page)
+		if rerr != nil {
+			logger.Debug("downloader.fetchChapterPage: No new URL for page %d: %v", page.Number, rerr)
+			return nil, err
+		}
+		logger.Debug("downloader.fetchChapterPage: Retrying page %d at %s", page.Number, refreshed.URL)
+		page = refreshed
+		file, err = FetchPage(ctx, site, page)
+	}
+	return file, err
+}
+
+// FetchPage returns the image of the page, downloading it unless the site already did.
+// Sites implementing grabber.PageReporter get the outcome of every download attempt.
+func FetchPage(ctx context.Context, site grabber.Site, page grabber.Page) (*File, error) {
+	if len(page.Data) > 0 {
+		logger.Debug("downloader.FetchPage: Using the captured image of page %d", page.Number)
+		return &File{Data: page.Data, Page: uint(page.Number)}, nil
+	}
+	params := http.RequestParams{
+		URL:     page.URL,
+		Referer: site.BaseUrl(),
+	}
+	if r, ok := site.(grabber.PageReporter); ok {
+		return fetchFile(ctx, params, uint(page.Number), r.ReportPage)
+	}
+	return fetchFile(ctx, params, uint(page.Number), nil)
 }
 
 // FetchFile gets an online file returning a new *File with its contents.
 func FetchFile(params http.RequestParams, page uint) (file *File, err error) {
-	var body io.ReadCloser
+	return fetchFile(context.Background(), params, page, nil)
+}
+
+// fetchFile gets an online file, calling report (when not nil) after every attempt.
+// The attempts stop once ctx is done.
+func fetchFile(ctx context.Context, params http.RequestParams, page uint, report func(grabber.PageReport)) (file *File, err error) {
+	var data []byte
 	maxAttempts := 2
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		body, err = http.Get(params)
+		start := time.Now()
+		var cached bool
+		data, cached, err = get(ctx, params)
+		if report != nil {
+			report(grabber.PageReport{
+				URL:      params.URL,
+				Success:  err == nil,
+				Bytes:    len(data),
+				Duration: time.Since(start),
+				Cached:   cached,
+			})
+		}
 		if err == nil {
 			break
 		}
 		if attempt < maxAttempts {
-			time.Sleep(500 * time.Millisecond)
+			select {
+			case <-time.After(retryDelay):
+			case <-ctx.Done():
+				return nil, err
+			}
 		}
 	}
 	if err != nil {
 		logger.Error("downloader.FetchFile: Error fetching file from URL %s: %v", params.URL, err)
 		return nil, err
 	}
-	defer body.Close()
-
-	data, err := io.ReadAll(body)
-	if err != nil {
-		logger.Error("downloader.FetchFile: Error reading data from URL %s: %v", params.URL, err)
-		return nil, err
-	}
 
 	file = &File{
 		Data: data,
@@ -113,3 +191,17 @@ func FetchFile(params http.RequestParams, page uint) (file *File, err error) {
 	logger.Debug("downloader.FetchFile: Successfully fetched file for page %d", page)
 	return file, nil
 }
+
+// get downloads the file, telling whether the server had it in cache (X-Cache: HIT).
+func get(ctx context.Context, params http.RequestParams) (data []byte, cached bool, err error) {
+	resp, err := http.GetResponseContext(ctx, params)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	cached = strings.HasPrefix(resp.Header.Get("X-Cache"), "HIT")
+	if data, err = io.ReadAll(resp.Body); err != nil {
+		return nil, cached, fmt.Errorf("error reading data: %w", err)
+	}
+	return data, cached, nil
+}
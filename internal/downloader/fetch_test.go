@@ -0,0 +1,373 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/grabber"
+	ihttp "github.com/NorkzYT/comic-downloader/internal/http"
+)
+
+// fakeSite is a grabber.Site downloading its pages from a test server.
+type fakeSite struct {
+	grabber.Site
+	url string
+}
+
+// BaseUrl implements grabber.Site.
+func (s *fakeSite) BaseUrl() string {
+	return s.url
+}
+
+// GetMaxConcurrency implements grabber.Site.
+func (s *fakeSite) GetMaxConcurrency() grabber.MaxConcurrency {
+	return grabber.MaxConcurrency{Chapters: 1, Pages: 4}
+}
+
+// resolvingSite is a fakeSite signing its page URLs with a token, renewed when stale.
+type resolvingSite struct {
+	fakeSite
+	mu     sync.Mutex
+	token  string
+	calls  int
+	stales int
+}
+
+// ResolvePage implements grabber.PageResolver.
+func (s *resolvingSite) ResolvePage(chapter *grabber.Chapter, page grabber.Page, stale bool) (grabber.Page, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if stale {
+		s.stales++
+		s.token = "valid"
+	}
+	page.URL = strings.SplitN(page.URL, "?", 2)[0] + "?token=" + s.token
+	return page, nil
+}
+
+// refresher moves the pages to another path of the test server.
+type refresher struct {
+	mu    sync.Mutex
+	calls int
+	max   int
+}
+
+// RefreshPage implements grabber.PageRefresher.
+func (r *refresher) RefreshPage(page grabber.Page) (grabber.Page, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.calls >= r.max {
+		return page, http.ErrServerClosed
+	}
+	r.calls++
+	page.URL = strings.Replace(page.URL, "/down/", "/up/", 1)
+	return page, nil
+}
+
+// newImageServer serves images under /up/ and for the "valid" token, and fails under /down/.
+func newImageServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/down/"):
+			http.Error(w, "down", http.StatusBadGateway)
+		case r.URL.Query().Has("token") && r.URL.Query().Get("token") != "valid":
+			http.Error(w, "expired", http.StatusForbidden)
+		default:
+			_, _ = w.Write([]byte("image " + r.URL.Path))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newChapter returns a chapter of n pages under the path of the server.
+func newChapter(srv *httptest.Server, path string, n int) *grabber.Chapter {
+	chapter := &grabber.Chapter{Title: "Chapter 1", Number: 1, PagesCount: int64(n)}
+	for i := 1; i <= n; i++ {
+		chapter.Pages = append(chapter.Pages, grabber.Page{Number: int64(i), URL: srv.URL + path + string(rune('0'+i)) + ".png"})
+	}
+	return chapter
+}
+
+func TestFetchChapterResolvesPages(t *testing.T) {
+	srv := newImageServer(t)
+	site := &resolvingSite{fakeSite: fakeSite{url: srv.URL}, token: "expired"}
+	chapter := newChapter(srv, "/img/", 3)
+
+	files, err := FetchChapter(site, chapter, func(int, int, error) {})
+	if err != nil {
+		t.Fatalf("FetchChapter: %v", err)
+	}
+	if len(files) != 3 || string(files[2].Data) != "image /img/3.png" {
+		t.Errorf("got %d files, last %q", len(files), files[len(files)-1].Data)
+	}
+	// Every page is resolved before its download, and again once its token is refused.
+	if site.calls < 4 || site.stales < 1 || site.stales > 3 {
+		t.Errorf("ResolvePage called %d times, %d stale", site.calls, site.stales)
+	}
+}
+
+func TestFetchChapterRefreshesFailedPages(t *testing.T) {
+	srv := newImageServer(t)
+	site := &fakeSite{url: srv.URL}
+
+	chapter := newChapter(srv, "/down/", 2)
+	chapter.Refresher = &refresher{max: 2}
+	files, err := FetchChapter(site, chapter, func(int, int, error) {})
+	if err != nil {
+		t.Fatalf("FetchChapter: %v", err)
+	}
+	if string(files[0].Data) != "image /up/1.png" || string(files[1].Data) != "image /up/2.png" {
+		t.Errorf("got %q and %q", files[0].Data, files[1].Data)
+	}
+
+	// Once the refresher has no URL left, the download error is returned.
+	chapter = newChapter(srv, "/down/", 1)
+	chapter.Refresher = &refresher{}
+	if _, err := FetchChapter(site, chapter, func(int, int, error) {}); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("FetchChapter = %v, want the 502 error", err)
+	}
+}
+
+// failingServer serves the images of a chapter, failing the pages listed in fail and delaying
+// each response by delay; requests under /slow/ hang until canceled. It counts the requests
+// of every page.
+type failingServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	hits  map[string]int
+	fail  map[string]bool
+	delay time.Duration
+	// inflight is the number of requests being served
+	inflight atomic.Int32
+}
+
+func newFailingServer(t *testing.T, delay time.Duration, fail ...string) *failingServer {
+	s := &failingServer{hits: map[string]int{}, fail: map[string]bool{}, delay: delay}
+	for _, path := range fail {
+		s.fail[path] = true
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.inflight.Add(1)
+		defer s.inflight.Add(-1)
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		s.mu.Unlock()
+		time.Sleep(s.delay)
+		if strings.HasPrefix(r.URL.Path, "/slow/") {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Second):
+			}
+		}
+		if s.fail[r.URL.Path] {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("image " + r.URL.Path))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// requested returns the number of requests for path.
+func (s *failingServer) requested(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+// noRetryDelay removes the wait between download attempts for the test.
+func noRetryDelay(t *testing.T) {
+	delay := retryDelay
+	retryDelay = 0
+	t.Cleanup(func() { retryDelay = delay })
+}
+
+// failingResolver fails to resolve the pages of fail, once all of them are being resolved.
+type failingResolver struct {
+	fakeSite
+	fail    map[int64]bool
+	pending sync.WaitGroup
+}
+
+// ResolvePage implements grabber.PageResolver.
+func (s *failingResolver) ResolvePage(chapter *grabber.Chapter, page grabber.Page, stale bool) (grabber.Page, error) {
+	if !s.fail[page.Number] {
+		return page, nil
+	}
+	s.pending.Done()
+	s.pending.Wait()
+	return page, errors.New("page removed")
+}
+
+func TestFetchChapterAggregatesErrors(t *testing.T) {
+	srv := newFailingServer(t, 0)
+	chapter := newChapter(srv.Server, "/img/", 5)
+	site := &failingResolver{fakeSite: fakeSite{url: srv.URL}, fail: map[int64]bool{2: true, 4: true}}
+	site.pending.Add(2)
+
+	var (
+		mu     sync.Mutex
+		failed []int
+	)
+	files, err := NewScheduler(5, 0).FetchChapter(context.Background(), site, chapter, 0, func(page, _ int, err error) {
+		if err != nil {
+			mu.Lock()
+			failed = append(failed, page)
+			mu.Unlock()
+		}
+	})
+	if files != nil || err == nil {
+		t.Fatalf("FetchChapter = %d files, %v; want an error", len(files), err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "page 2: ") || !strings.Contains(msg, "page 4: ") || strings.Index(msg, "page 2") > strings.Index(msg, "page 4") {
+		t.Errorf("FetchChapter error = %q, want pages 2 then 4", msg)
+	}
+	if len(failed) != 2 {
+		t.Errorf("progress reported failed pages %v, want 2 and 4", failed)
+	}
+}
+
+func TestFetchChapterStopsRunningDownloads(t *testing.T) {
+	noRetryDelay(t)
+	srv := newFailingServer(t, 0, "/img/1.png")
+	chapter := newChapter(srv.Server, "/img/", 1)
+	// Page 2 hangs until its request is canceled.
+	chapter.Pages = append(chapter.Pages, grabber.Page{Number: 2, URL: srv.URL + "/slow/2.png"})
+	site := &fakeSite{url: srv.URL}
+	chapter.Refresher = &refresher{max: 10}
+
+	start := time.Now()
+	_, err := NewScheduler(2, 0).FetchChapter(context.Background(), site, chapter, 0, func(int, int, error) {})
+	if err == nil || !strings.Contains(err.Error(), "page 1: ") || strings.Contains(err.Error(), "page 2") {
+		t.Errorf("FetchChapter = %v, want only the error of page 1", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("FetchChapter took %v, the running download was not canceled", d)
+	}
+	// The server sees the canceled request shortly after.
+	for deadline := time.Now().Add(2 * time.Second); srv.inflight.Load() != 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the request of page 2 was not canceled")
+		}
+	}
+}
+
+func TestFetchFileStopsRetrying(t *testing.T) {
+	srv := newFailingServer(t, 0, "/img/1.png")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	if _, err := fetchFile(ctx, ihttp.RequestParams{URL: srv.URL + "/img/1.png"}, 1, nil); err == nil {
+		t.Fatal("fetchFile succeeded")
+	}
+	if d := time.Since(start); d >= retryDelay {
+		t.Errorf("fetchFile took %v, want no retry once ctx is done", d)
+	}
+}
+
+func TestFetchChapterCancelsQueuedPages(t *testing.T) {
+	noRetryDelay(t)
+	srv := newFailingServer(t, 0, "/img/1.png")
+	chapter := newChapter(srv.Server, "/img/", 6)
+	s := NewScheduler(1, 0)
+	release := blockScheduler(t, s, srv.URL)
+
+	errc := make(chan error)
+	go func() {
+		_, err := s.FetchChapter(context.Background(), &fakeSite{url: srv.URL}, chapter, 0, func(int, int, error) {})
+		errc <- err
+	}()
+	waitQueued(t, s, 6)
+	release()
+	err := <-errc
+	if err == nil || !strings.Contains(err.Error(), "page 1: ") {
+		t.Fatalf("FetchChapter = %v, want the error of page 1", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Errorf("FetchChapter = %v, the canceled pages are not failures", err)
+	}
+	// With a single worker, page 1 fails before any other page starts.
+	for i := 2; i <= 6; i++ {
+		if n := srv.requested("/img/" + string(rune('0'+i)) + ".png"); n != 0 {
+			t.Errorf("page %d requested %d times after the failure", i, n)
+		}
+	}
+}
+
+func TestFetchChapterWaitsForDownloads(t *testing.T) {
+	noRetryDelay(t)
+	srv := newFailingServer(t, 30*time.Millisecond, "/img/1.png")
+	chapter := newChapter(srv.Server, "/img/", 8)
+
+	var calls atomic.Int32
+	_, err := NewScheduler(4, 0).FetchChapter(context.Background(), &fakeSite{url: srv.URL}, chapter, 0, func(int, int, error) {
+		calls.Add(1)
+	})
+	if err == nil {
+		t.Fatal("FetchChapter succeeded, want the error of page 1")
+	}
+	// No download outlives the call: nothing is reported afterwards, and the server sees
+	// the canceled requests end shortly after (its handler finishes its delay first).
+	reported := calls.Load()
+	time.Sleep(100 * time.Millisecond)
+	if n := calls.Load(); n != reported {
+		t.Errorf("%d progress calls after FetchChapter returned", n-reported)
+	}
+	for deadline := time.Now().Add(2 * time.Second); srv.inflight.Load() != 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests in flight after FetchChapter returned", srv.inflight.Load())
+		}
+	}
+}
+
+func TestFetchChapterContextCanceled(t *testing.T) {
+	srv := newFailingServer(t, 0)
+	chapter := newChapter(srv.Server, "/img/", 3)
+	s := NewScheduler(1, 0)
+	release := blockScheduler(t, s, srv.URL)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := s.FetchChapter(ctx, &fakeSite{url: srv.URL}, chapter, 0, func(int, int, error) {})
+		errc <- err
+	}()
+	waitQueued(t, s, 3)
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("FetchChapter = %v, want context.Canceled", err)
+	}
+	for i := 1; i <= 3; i++ {
+		if n := srv.requested("/img/" + string(rune('0'+i)) + ".png"); n != 0 {
+			t.Errorf("page %d requested after the cancellation", i)
+		}
+	}
+}
+
+func TestFetchChapterKeepsPageOrder(t *testing.T) {
+	srv := newFailingServer(t, 0)
+	chapter := newChapter(srv.Server, "/img/", 9)
+
+	files, err := NewScheduler(9, 0).FetchChapter(context.Background(), &fakeSite{url: srv.URL}, chapter, 0, func(int, int, error) {})
+	if err != nil {
+		t.Fatalf("FetchChapter: %v", err)
+	}
+	for i, file := range files {
+		if want := "image /img/" + string(rune('1'+i)) + ".png"; file.Page != uint(i+1) || string(file.Data) != want {
+			t.Errorf("file %d = page %d %q, want %q", i, file.Page, file.Data, want)
+		}
+	}
+}
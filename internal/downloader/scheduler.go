@@ -0,0 +1,210 @@
+package downloader
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+)
+
+// Scheduler runs the page downloads of every chapter on a single pool of workers.
+//
+// Pages are downloaded in priority order, the pages of a chapter before those of the chapters
+// with a higher priority number, so that chapters complete one after the other instead of all
+// at once. Each host is limited to perHost concurrent downloads; the limit adapts, halving when
+// a download fails and growing back as downloads succeed.
+type Scheduler struct {
+	// workers is the number of downloads running at once, across every host
+	workers int
+	// perHost is the maximum number of downloads running at once per host
+	perHost int
+
+	mu      sync.Mutex
+	running int
+	queue   taskQueue
+	hosts   map[string]*hostLimit
+	seq     uint64
+}
+
+// NewScheduler returns a scheduler running at most workers downloads at once, and perHost
+// per host (0 for workers).
+func NewScheduler(workers, perHost int) *Scheduler {
+	workers = max(1, workers)
+	if perHost <= 0 || perHost > workers {
+		perHost = workers
+	}
+	return &Scheduler{workers: workers, perHost: perHost, hosts: map[string]*hostLimit{}}
+}
+
+// Priority orders the downloads: lower chapters first, then lower pages.
+type Priority struct {
+	// Chapter is the position of the chapter in the download
+	Chapter int
+	// Page is the page number
+	Page int64
+}
+
+// less reports whether p runs before o.
+func (p Priority) less(o Priority) bool {
+	if p.Chapter != o.Chapter {
+		return p.Chapter < o.Chapter
+	}
+	return p.Page < o.Page
+}
+
+// hostLimit is the adaptive concurrency limit of a host.
+type hostLimit struct {
+	// limit is the current limit, between 1 and the scheduler perHost
+	limit float64
+	// running is the number of downloads from the host
+	running int
+}
+
+// task is a download waiting for a worker.
+type task struct {
+	priority Priority
+	seq      uint64
+	host     string
+	// start is closed once the task may run
+	start chan struct{}
+	// index is the position of the task in the queue, -1 once removed
+	index int
+}
+
+// Do runs fn once a worker is free and the host of rawURL is under its limit, after the
+// waiting downloads of a higher priority. The error of fn lowers the host limit, unless fn
+// was canceled through ctx: the host is not to blame then. Do returns the error of fn, or
+// that of ctx if it is done before fn starts.
+func (s *Scheduler) Do(ctx context.Context, p Priority, rawURL string, fn func() error) error {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Host
+	}
+
+	s.mu.Lock()
+	s.seq++
+	t := &task{priority: p, seq: s.seq, host: host, start: make(chan struct{})}
+	heap.Push(&s.queue, t)
+	s.dispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-t.start:
+		if ctx.Err() != nil {
+			// Started as ctx was done: skip it.
+			s.release(host)
+			return ctx.Err()
+		}
+	case <-ctx.Done():
+		s.mu.Lock()
+		started := t.index < 0
+		if !started {
+			heap.Remove(&s.queue, t.index)
+		}
+		s.mu.Unlock()
+		if !started {
+			return ctx.Err()
+		}
+		// The task was started meanwhile: give its worker back.
+		s.release(host)
+		return ctx.Err()
+	}
+
+	err := fn()
+	if ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		s.release(host)
+		return err
+	}
+	s.done(host, err)
+	return err
+}
+
+// done releases the worker of a download from host, adapting the host limit to its outcome.
+func (s *Scheduler) done(host string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.hosts[host]
+	if err != nil {
+		if h.limit > 1 {
+			h.limit = max(1, h.limit/2)
+			logger.Debug("downloader.Scheduler: Download from %s failed, limiting it to %d at once", host, int(h.limit))
+		}
+	} else {
+		h.limit = min(float64(s.perHost), h.limit+1/h.limit)
+	}
+	s.free(host)
+}
+
+// release releases the worker of a download from host without adapting the host limit,
+// for the downloads canceled by their caller.
+func (s *Scheduler) release(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.free(host)
+}
+
+// free releases the worker of a download from host, with s.mu held.
+func (s *Scheduler) free(host string) {
+	s.running--
+	s.hosts[host].running--
+	s.dispatch()
+}
+
+// dispatch starts the waiting tasks the workers and host limits allow, in priority order,
+// with s.mu held.
+func (s *Scheduler) dispatch() {
+	var skipped []*task
+	for s.running < s.workers && s.queue.Len() > 0 {
+		t := heap.Pop(&s.queue).(*task)
+		h, ok := s.hosts[t.host]
+		if !ok {
+			h = &hostLimit{limit: float64(s.perHost)}
+			s.hosts[t.host] = h
+		}
+		if h.running >= int(h.limit) {
+			skipped = append(skipped, t)
+			continue
+		}
+		h.running++
+		s.running++
+		close(t.start)
+	}
+	for _, t := range skipped {
+		heap.Push(&s.queue, t)
+	}
+}
+
+// taskQueue is a heap of tasks ordered by priority, then submission order.
+type taskQueue []*task
+
+func (q taskQueue) Len() int { return len(q) }
+
+func (q taskQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority.less(q[j].priority)
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q taskQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+
+func (q *taskQueue) Push(x interface{}) {
+	t := x.(*task)
+	t.index = len(*q)
+	*q = append(*q, t)
+}
+
+func (q *taskQueue) Pop() interface{} {
+	old := *q
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*q = old[:len(old)-1]
+	return t
+}
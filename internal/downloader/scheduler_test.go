@@ -0,0 +1,197 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockScheduler occupies every worker of the scheduler until the returned function is called.
+func blockScheduler(t *testing.T, s *Scheduler, host string) (release func()) {
+	t.Helper()
+	unblock := make(chan struct{})
+	var started sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		started.Add(1)
+		go func() {
+			_ = s.Do(context.Background(), Priority{Chapter: -1}, host, func() error {
+				started.Done()
+				<-unblock
+				return nil
+			})
+		}()
+	}
+	started.Wait()
+	return func() { close(unblock) }
+}
+
+// waitQueued waits until n tasks are queued.
+func waitQueued(t *testing.T, s *Scheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		queued := s.queue.Len()
+		s.mu.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d tasks queued, want %d", queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	s := NewScheduler(1, 0)
+	release := blockScheduler(t, s, "https://a.example/")
+
+	var (
+		mu    sync.Mutex
+		order []Priority
+		wg    sync.WaitGroup
+	)
+	// Submitted in reverse: the first chapter must still be downloaded first, in page order.
+	for chapter := 2; chapter >= 0; chapter-- {
+		for page := int64(3); page >= 1; page-- {
+			wg.Add(1)
+			p := Priority{Chapter: chapter, Page: page}
+			go func() {
+				defer wg.Done()
+				_ = s.Do(context.Background(), p, "https://a.example/", func() error {
+					mu.Lock()
+					order = append(order, p)
+					mu.Unlock()
+					return nil
+				})
+			}()
+		}
+	}
+	waitQueued(t, s, 9)
+	release()
+	wg.Wait()
+
+	for i := 1; i < len(order); i++ {
+		if order[i].less(order[i-1]) {
+			t.Fatalf("downloaded in order %v", order)
+		}
+	}
+}
+
+func TestSchedulerLimits(t *testing.T) {
+	s := NewScheduler(4, 2)
+	var (
+		mu              sync.Mutex
+		running, maxAll int
+		perHost         = map[string]int{}
+		maxHost         = map[string]int{}
+		wg              sync.WaitGroup
+	)
+	for i := 0; i < 40; i++ {
+		host := []string{"https://a.example/1.png", "https://b.example/1.png", "https://c.example/1.png"}[i%3]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = s.Do(context.Background(), Priority{Page: int64(i)}, host, func() error {
+				mu.Lock()
+				running++
+				perHost[host]++
+				maxAll = max(maxAll, running)
+				maxHost[host] = max(maxHost[host], perHost[host])
+				mu.Unlock()
+				time.Sleep(2 * time.Millisecond)
+				mu.Lock()
+				running--
+				perHost[host]--
+				mu.Unlock()
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	if maxAll > 4 {
+		t.Errorf("%d downloads at once, want at most 4", maxAll)
+	}
+	for host, n := range maxHost {
+		if n > 2 {
+			t.Errorf("%d downloads at once from %s, want at most 2", n, host)
+		}
+	}
+}
+
+func TestSchedulerBacksOff(t *testing.T) {
+	s := NewScheduler(8, 0)
+	fail := errors.New("502")
+	for i := 0; i < 3; i++ {
+		if err := s.Do(context.Background(), Priority{}, "https://a.example/1.png", func() error { return fail }); err != fail {
+			t.Fatalf("Do = %v, want the download error", err)
+		}
+	}
+	if got := s.hosts["a.example"].limit; got != 1 {
+		t.Errorf("limit after 3 failures = %v, want 1", got)
+	}
+	for i := 0; i < 100; i++ {
+		_ = s.Do(context.Background(), Priority{}, "https://a.example/1.png", func() error { return nil })
+	}
+	if got := s.hosts["a.example"].limit; got != 8 {
+		t.Errorf("limit after 100 successes = %v, want 8", got)
+	}
+}
+
+func TestSchedulerCanceledDownloadsKeepLimit(t *testing.T) {
+	s := NewScheduler(8, 0)
+	for _, cause := range []error{context.Canceled, context.DeadlineExceeded} {
+		for i := 0; i < 3; i++ {
+			ctx, cancel := context.WithCancel(context.Background())
+			err := s.Do(ctx, Priority{}, "https://a.example/1.png", func() error {
+				// A sibling page failed, or the job was canceled, during the download.
+				cancel()
+				return fmt.Errorf("get: %w", cause)
+			})
+			if !errors.Is(err, cause) {
+				t.Fatalf("Do = %v, want %v", err, cause)
+			}
+		}
+	}
+	if got := s.hosts["a.example"].limit; got != 8 {
+		t.Errorf("limit after canceled downloads = %v, want 8", got)
+	}
+
+	// The same errors without the caller canceling are failures of the host.
+	if err := s.Do(context.Background(), Priority{}, "https://a.example/1.png", func() error { return context.DeadlineExceeded }); err == nil {
+		t.Fatal("Do succeeded, want the download error")
+	}
+	if got := s.hosts["a.example"].limit; got != 4 {
+		t.Errorf("limit after a timeout = %v, want 4", got)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running != 0 || s.hosts["a.example"].running != 0 {
+		t.Errorf("%d downloads running, want the workers released", s.running)
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := NewScheduler(1, 0)
+	release := blockScheduler(t, s, "https://a.example/")
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		errc <- s.Do(ctx, Priority{}, "https://a.example/1.png", func() error {
+			t.Error("canceled task ran")
+			return nil
+		})
+	}()
+	waitQueued(t, s, 1)
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Do = %v, want context.Canceled", err)
+	}
+	waitQueued(t, s, 0)
+}
@@ -11,6 +11,16 @@ import (
 	"github.com/NorkzYT/comic-downloader/internal/logger"
 )
 
+// AsuraScans selectors.
+const (
+	asuraTitleSelector    = `div.text-center.sm\:text-left span.text-xl.font-bold`
+	asuraChaptersSelector = "div.overflow-y-auto a"
+	asuraPagesSelector    = "div.w-full.mx-auto.center img"
+)
+
+// asuraImagesWait is how long the lazy-loaded images are waited for once the chapter is scrolled.
+const asuraImagesWait = 5 * time.Second
+
 // AsuraScans implements the Site interface for asuracomic.net using chromedp.
 type AsuraScans struct {
 	*Grabber
@@ -36,9 +46,15 @@ func (a *AsuraScans) UsesBrowser() bool {
 // FetchTitle navigates to the series URL and extracts the comic title.
 func (a *AsuraScans) FetchTitle() (string, error) {
 	var title string
-	jsTitle := `document.querySelector("div.text-center.sm\\:text-left span.text-xl.font-bold") ? document.querySelector("div.text-center.sm\\:text-left span.text-xl.font-bold").innerText : ""`
+	jsTitle := fmt.Sprintf(`document.querySelector(%[1]s) ? document.querySelector(%[1]s).innerText : ""`, jsString(asuraTitleSelector))
 	logger.Debug("AsuraScans.FetchTitle: Running JS for title extraction on %s", a.URL)
-	err := browserless.RunJS(a.URL, "body", 0, jsTitle, &title)
+	page, err := browserless.Load(a.URL, "body", 0)
+	if err != nil {
+		logger.Error("AsuraScans.FetchTitle: Error loading series page: %v", err)
+		return "", fmt.Errorf("error loading series page: %w", err)
+	}
+	defer page.Close()
+	err = page.Eval(jsTitle, &title)
 	if err != nil {
 		logger.Error("AsuraScans.FetchTitle: Error fetching title with selector: %v", err)
 		return "", fmt.Errorf("error fetching title with selector: %w", err)
@@ -47,7 +63,7 @@ func (a *AsuraScans) FetchTitle() (string, error) {
 	if title == "" {
 		jsDocTitle := `document.title`
 		logger.Debug("AsuraScans.FetchTitle: Title empty, falling back to document.title on %s", a.URL)
-		err = browserless.RunJS(a.URL, "body", 0, jsDocTitle, &title)
+		err = page.Eval(jsDocTitle, &title)
 		if err != nil {
 			logger.Error("AsuraScans.FetchTitle: Error fetching document.title: %v", err)
 			return "", fmt.Errorf("error fetching document.title: %w", err)
@@ -63,7 +79,7 @@ func (a *AsuraScans) FetchChapters() (Filterables, []error) {
 	var chaptersJSON string
 	jsChapters := `(function(){
 		var chapters = [];
-		var links = document.querySelectorAll("div.overflow-y-auto a");
+		var links = document.querySelectorAll(` + jsString(asuraChaptersSelector) + `);
 		for(var i = 0; i < links.length; i++){
 			var rawTitle = links[i].textContent.trim();
 			var match = rawTitle.match(/(Chapter\s*\d+(?:\.\d+)?)/i);
@@ -117,8 +133,8 @@ func (a *AsuraScans) FetchChapters() (Filterables, []error) {
 	return chapters, nil
 }
 
-// FetchChapterWithProgress navigates to a chapter URL and extracts image URLs,
-// calling the provided progressCallback during long-running evaluations.
+// FetchChapterWithProgress navigates to a chapter URL and extracts image URLs, keeping the images
+// the browser already downloaded, calling the provided progressCallback during long-running evaluations.
 func (a *AsuraScans) FetchChapterWithProgress(f Filterable, progressCallback func()) (*Chapter, error) {
 	ac, ok := f.(*AsuraChapter)
 	if !ok {
@@ -126,26 +142,40 @@ func (a *AsuraScans) FetchChapterWithProgress(f Filterable, progressCallback fun
 		return nil, fmt.Errorf("invalid chapter type")
 	}
 	logger.Debug("AsuraScans.FetchChapterWithProgress: Fetching chapter with URL: %s", ac.URL)
-	_, err := browserless.FetchStringWithProgress(ac.URL, "body", `document.documentElement.outerHTML`, 10*time.Second, progressCallback)
+	stop := browserless.WithProgress(progressCallback)
+	defer stop()
+	page, err := browserless.LoadCapturing(ac.URL, "body", 10*time.Second)
 	if err != nil {
 		logger.Error("AsuraScans.FetchChapterWithProgress: Failed to fetch chapter page: %v", err)
 		return nil, fmt.Errorf("failed to fetch chapter page: %w", err)
 	}
+	defer page.Close()
 
-	var imageSrcs []string
-	jsImages := `(function(){
+	// Remove the ad overlay and scroll down to trigger the lazy-loaded images.
+	jsScroll := `(function(){
 		var adOverlay = document.querySelector("div.fixed.inset-0.bg-gray-900");
 		if(adOverlay && adOverlay.parentNode) {
 			adOverlay.parentNode.removeChild(adOverlay);
 		}
 		window.scrollTo(0, document.body.scrollHeight);
-		var start = Date.now();
-		while(Date.now() - start < 1000) {}
-		return Array.from(document.querySelectorAll("div.w-full.mx-auto.center img"))
-			.map(img => img.src)
-			.filter(src => src && src.startsWith("http"));
+		return true;
 	})();`
-	imageSrcs, err = browserless.FetchStringSliceWithProgress(ac.URL, "body", jsImages, 10*time.Second, progressCallback)
+	var scrolled bool
+	if err = page.Eval(jsScroll, &scrolled); err != nil {
+		logger.Error("AsuraScans.FetchChapterWithProgress: Failed to scroll chapter page: %v", err)
+		return nil, fmt.Errorf("failed to scroll chapter page: %w", err)
+	}
+	captured := map[string][]byte{}
+	for _, img := range page.Images(asuraImagesWait) {
+		captured[img.URL] = img.Data
+	}
+	logger.Debug("AsuraScans.FetchChapterWithProgress: Captured %d images", len(captured))
+
+	var imageSrcs []string
+	jsImages := `Array.from(document.querySelectorAll(` + jsString(asuraPagesSelector) + `))
+		.map(img => img.src)
+		.filter(src => src && src.startsWith("http"));`
+	err = page.Eval(jsImages, &imageSrcs)
 	if err != nil {
 		logger.Error("AsuraScans.FetchChapterWithProgress: Failed to extract image URLs: %v", err)
 		return nil, fmt.Errorf("failed to extract image URLs: %w", err)
@@ -160,6 +190,7 @@ func (a *AsuraScans) FetchChapterWithProgress(f Filterable, progressCallback fun
 		pages[i] = Page{
 			Number: int64(i + 1),
 			URL:    src,
+			Data:   captured[src],
 		}
 	}
 	chapter := &Chapter{
@@ -178,6 +209,15 @@ func (a *AsuraScans) FetchChapter(f Filterable) (*Chapter, error) {
 	return a.FetchChapterWithProgress(f, func() {})
 }
 
+// Selectors returns the CSS selectors of each scraping step.
+func (a *AsuraScans) Selectors() map[string]string {
+	return map[string]string{
+		StepTitle:    asuraTitleSelector,
+		StepChapters: asuraChaptersSelector,
+		StepPages:    asuraPagesSelector,
+	}
+}
+
 // BaseUrl returns the base URL for asuracomic.net derived from the chapter URL.
 func (a *AsuraScans) BaseUrl() string {
 	u, err := url.Parse(a.URL)
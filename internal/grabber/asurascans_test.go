@@ -0,0 +1,83 @@
+package grabber
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/NorkzYT/comic-downloader/internal/browserless"
+)
+
+const asuraURL = "https://asuracomic.net/series/player-who-returned-10000-years-later-44b620ed"
+
+func TestAsuraScansFetchTitle(t *testing.T) {
+	a := &AsuraScans{Grabber: testGrabber(asuraURL, nil)}
+	useFakeBrowser(t, fakeScript{url: asuraURL, contains: "span.text-xl.font-bold", result: " Player Who Returned 10,000 Years Later\n"})
+	title, err := a.FetchTitle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Player Who Returned 10,000 Years Later"; title != want {
+		t.Errorf("FetchTitle() = %q, want %q", title, want)
+	}
+
+	// Without the title element, the document title of the same page is used.
+	browser := useFakeBrowser(t,
+		fakeScript{url: asuraURL, contains: "span.text-xl.font-bold", result: ""},
+		fakeScript{url: asuraURL, contains: "document.title", result: "Asura Scans"},
+	)
+	if title, err = a.FetchTitle(); err != nil || title != "Asura Scans" {
+		t.Errorf("FetchTitle() = %q, %v, want the document title", title, err)
+	}
+	if n := browser.loaded(asuraURL); n != 1 {
+		t.Errorf("series page loaded %d times, want 1", n)
+	}
+}
+
+func TestAsuraScansFetchChapters(t *testing.T) {
+	a := &AsuraScans{Grabber: testGrabber(asuraURL, nil)}
+	useFakeBrowser(t, fakeScript{url: asuraURL, contains: "div.overflow-y-auto a", result: fixture(t, "asurascans", "chapters.json")})
+	chapters, errs := a.FetchChapters()
+	assertChapters(t, chapters, errs, []Chapter{
+		{Number: 199, Title: "Chapter 199"},
+		{Number: 198.5, Title: "Chapter 198.5"},
+		{Number: 198, Title: "Chapter 198"},
+	})
+}
+
+func TestAsuraScansFetchChapter(t *testing.T) {
+	a := &AsuraScans{Grabber: testGrabber(asuraURL, nil)}
+	chapterURL := asuraURL + "/chapter/199"
+	pages := fixture(t, "asurascans", "pages.json")
+	browser := useFakeBrowser(t,
+		fakeScript{url: chapterURL, contains: "window.scrollTo", result: true},
+		fakeScript{url: chapterURL, contains: "div.w-full.mx-auto.center img", result: json.RawMessage(pages)},
+	)
+	var urls []string
+	if err := json.Unmarshal([]byte(pages), &urls); err != nil {
+		t.Fatal(err)
+	}
+	// The browser captured the first page only: the others are downloaded from their URL.
+	browser.images = []browserless.Image{{URL: urls[0], MimeType: "image/webp", Data: []byte("RIFF")}}
+	chapter, err := a.FetchChapter(&AsuraChapter{Chapter: Chapter{Title: "Chapter 199", Number: 199}, URL: chapterURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPages(t, chapter, urls...)
+	if string(chapter.Pages[0].Data) != "RIFF" || chapter.Pages[1].Data != nil {
+		t.Errorf("page data = %q, %q, want the captured image for the first page only", chapter.Pages[0].Data, chapter.Pages[1].Data)
+	}
+	if n := browser.loaded(chapterURL); n != 1 {
+		t.Errorf("chapter page loaded %d times, want 1", n)
+	}
+	if chapter.Language != "en" {
+		t.Errorf("Language = %q, want en", chapter.Language)
+	}
+
+	useFakeBrowser(t,
+		fakeScript{url: chapterURL, contains: "window.scrollTo", result: true},
+		fakeScript{url: chapterURL, contains: "div.w-full.mx-auto.center img", result: []string{}},
+	)
+	if _, err := a.FetchChapter(&AsuraChapter{URL: chapterURL}); err == nil {
+		t.Error("FetchChapter() of a chapter without images succeeded")
+	}
+}
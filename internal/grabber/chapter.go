@@ -1,6 +1,14 @@
 package grabber
 
-import "strings"
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrExternalChapter is returned for the chapters hosted on another site, which can not be
+// downloaded.
+var ErrExternalChapter = errors.New("chapter hosted on an external site")
 
 // Chapter represents a comic chapter
 type Chapter struct {
@@ -8,12 +16,22 @@ type Chapter struct {
 	Title string
 	// Number is the chapter number
 	Number float64
+	// Unnumbered tells the chapter has no number (oneshot, special or unparsable number), Number being 0
+	Unnumbered bool
+	// Volume is the volume the chapter belongs to (empty if unknown)
+	Volume string
 	// PagesCount is the number of pages in the chapter
 	PagesCount int64
 	// Pages is the list of pages in the chapter
 	Pages []Page
 	// Language is the chapter language
 	Language string
+	// Group is the scanlation group that released the chapter (empty if unknown)
+	Group string
+	// Date is the chapter upload date (zero if unknown)
+	Date time.Time
+	// Refresher, when set, gives new URLs to the pages whose download failed
+	Refresher PageRefresher
 }
 
 // Page represents a chapter page
@@ -22,6 +40,14 @@ type Page struct {
 	Number int64
 	// URL is the page URL
 	URL string
+	// Data is the page image when the browser already downloaded it, nil to download URL
+	Data []byte
+}
+
+// PageRefresher gives a new URL to a page whose download failed, such as on another image server.
+type PageRefresher interface {
+	// RefreshPage returns the page with a new URL, or an error when there is none left to try
+	RefreshPage(page Page) (Page, error)
 }
 
 // GetNumber returns the chapter number
@@ -29,9 +55,63 @@ func (c Chapter) GetNumber() float64 {
 	return c.Number
 }
 
+// IsUnnumbered reports whether the chapter has no number
+func (c Chapter) IsUnnumbered() bool {
+	return c.Unnumbered
+}
+
+// GetVolume returns the chapter volume
+func (c Chapter) GetVolume() string {
+	return c.Volume
+}
+
+// GetGroup returns the chapter scanlation group
+func (c Chapter) GetGroup() string {
+	return c.Group
+}
+
+// GetLanguage returns the chapter language
+func (c Chapter) GetLanguage() string {
+	return c.Language
+}
+
+// GetPagesCount returns the chapter pages count
+func (c Chapter) GetPagesCount() int64 {
+	return c.PagesCount
+}
+
+// GetDate returns the chapter upload date
+func (c Chapter) GetDate() time.Time {
+	return c.Date
+}
+
 // GetTitle returns the chapter title removing whitespace and newlines
 func (c Chapter) GetTitle() string {
 	title := strings.TrimSpace(c.Title)
 	title = strings.ReplaceAll(title, "\n", " ")
 	return title
 }
+
+// Unnumbered reports whether the Filterable has no number, as opposed to being numbered 0.
+func Unnumbered(c Filterable) bool {
+	if u, ok := c.(interface{ IsUnnumbered() bool }); ok {
+		return u.IsUnnumbered()
+	}
+	return false
+}
+
+// ChapterID returns the ID of the Filterable on its site, or "" when the site has none.
+func ChapterID(c Filterable) string {
+	if i, ok := c.(interface{ GetID() string }); ok {
+		return i.GetID()
+	}
+	return ""
+}
+
+// ExternalURL returns the URL of the Filterable when it is hosted on another site, or "".
+func ExternalURL(c Filterable) string {
+	if e, ok := c.(interface{ GetExternalURL() string }); ok {
+		return e.GetExternalURL()
+	}
+	return ""
+}
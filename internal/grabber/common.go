@@ -2,6 +2,7 @@
 package grabber
 
 import (
+	"encoding/json"
 	"regexp"
 	"strconv"
 
@@ -16,6 +17,12 @@ func getUuid(s string) string {
 	return re.FindString(s)
 }
 
+// jsString returns s as a JavaScript string literal, to embed selectors in scripts.
+func jsString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
 // maxUint8Flag returns the parsed flag value as uint8, capped at max.
 func maxUint8Flag(flag *pflag.Flag, max uint8) uint8 {
 	v, _ := strconv.ParseUint(flag.Value.String(), 10, 8)
@@ -13,6 +13,13 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// CypherScans selectors.
+const (
+	cypherscansTitleSelector    = "div#titledesktop h1.entry-title"
+	cypherscansChaptersSelector = "div.eplister#chapterlist ul li"
+	cypherscansPagesSelector    = "div#readerarea img.ts-main-image"
+)
+
 // CypherScans implements the Site interface for cypheroscans.xyz.
 type CypherScans struct {
 	*Grabber
@@ -53,7 +60,7 @@ func (c *CypherScans) FetchTitle() (string, error) {
 	}
 
 	// Extract title from: <div id="titledesktop"><div id="titlemove"><h1 class="entry-title" ...>...</h1>
-	c.title = strings.TrimSpace(doc.Find("div#titledesktop h1.entry-title").Text())
+	c.title = strings.TrimSpace(doc.Find(cypherscansTitleSelector).Text())
 	logger.Debug("CypherScans.FetchTitle: Fetched title: %s", c.title)
 	return c.title, nil
 }
@@ -65,12 +72,14 @@ type CypherScansChapter struct {
 }
 
 // newCypherScansChapter creates a new CypherScansChapter instance.
-func newCypherScansChapter(num float64, title, url string) *CypherScansChapter {
+// A chapter whose number could not be parsed is unnumbered.
+func newCypherScansChapter(num float64, unnumbered bool, title, url string) *CypherScansChapter {
 	logger.Debug("newCypherScansChapter: Creating chapter %s with URL: %s", title, url)
 	return &CypherScansChapter{
 		Chapter: Chapter{
-			Number: num,
-			Title:  title,
+			Number:     num,
+			Unnumbered: unnumbered,
+			Title:      title,
 		},
 		URL: url,
 	}
@@ -95,7 +104,7 @@ func (c *CypherScans) FetchChapters() (Filterables, []error) {
 	chapters := make(Filterables, 0)
 
 	// The chapter list is inside <div class="eplister" id="chapterlist"><ul>...
-	doc.Find("div.eplister#chapterlist ul li").Each(func(i int, s *goquery.Selection) {
+	doc.Find(cypherscansChaptersSelector).Each(func(i int, s *goquery.Selection) {
 		// Locate the anchor tag.
 		link := s.Find("a")
 		href, exists := link.Attr("href")
@@ -123,7 +132,7 @@ func (c *CypherScans) FetchChapters() (Filterables, []error) {
 		chapterTitle := chapText
 
 		// Create and append a new chapter.
-		chapter := newCypherScansChapter(num, chapterTitle, href)
+		chapter := newCypherScansChapter(num, err != nil, chapterTitle, href)
 		chapters = append(chapters, chapter)
 	})
 	logger.Debug("CypherScans.FetchChapters: Parsed %d chapters", len(chapters))
@@ -155,13 +164,14 @@ func (c *CypherScans) FetchChapter(f Filterable) (*Chapter, error) {
 	}
 
 	chapter := &Chapter{
-		Title:    csc.Title,
-		Number:   csc.Number,
-		Language: "en",
+		Title:      csc.Title,
+		Number:     csc.Number,
+		Unnumbered: csc.Unnumbered,
+		Language:   "en",
 	}
 
 	// Extract image URLs from <div id="readerarea"> and all <img class="ts-main-image">.
-	doc.Find("div#readerarea img.ts-main-image").Each(func(i int, s *goquery.Selection) {
+	doc.Find(cypherscansPagesSelector).Each(func(i int, s *goquery.Selection) {
 		src := strings.TrimSpace(s.AttrOr("src", ""))
 		if src != "" {
 			chapter.Pages = append(chapter.Pages, Page{
@@ -179,6 +189,15 @@ func (c *CypherScans) FetchChapter(f Filterable) (*Chapter, error) {
 	return chapter, nil
 }
 
+// Selectors returns the CSS selectors of each scraping step.
+func (c *CypherScans) Selectors() map[string]string {
+	return map[string]string{
+		StepTitle:    cypherscansTitleSelector,
+		StepChapters: cypherscansChaptersSelector,
+		StepPages:    cypherscansPagesSelector,
+	}
+}
+
 // BaseUrl returns the base URL of the website.
 func (c *CypherScans) BaseUrl() string {
 	u, err := url.Parse(c.URL)
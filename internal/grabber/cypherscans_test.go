@@ -0,0 +1,52 @@
+package grabber
+
+import "testing"
+
+// newTestCypherScans returns a CypherScans grabber requesting the fixture server, with a fake
+// browser rendering the chapter pages.
+func newTestCypherScans(t *testing.T) *CypherScans {
+	fs := newFixtureServer(t, "cypherscans", map[string]string{
+		"/manga/magic-emperor/": "series.html",
+	})
+	useFakeBrowser(t, fakeScript{
+		url:      "https://cypheroscans.xyz/magic-emperor-chapter-682/",
+		contains: "document.documentElement.outerHTML",
+		result:   fixture(t, "cypherscans", "chapter.html"),
+	})
+	return &CypherScans{Grabber: testGrabber(fs.URL+"/manga/magic-emperor/", nil)}
+}
+
+func TestCypherScansFetchTitle(t *testing.T) {
+	c := newTestCypherScans(t)
+	title, err := c.FetchTitle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Magic Emperor" {
+		t.Errorf("FetchTitle() = %q, want %q", title, "Magic Emperor")
+	}
+}
+
+func TestCypherScansFetchChapters(t *testing.T) {
+	c := newTestCypherScans(t)
+	chapters, errs := c.FetchChapters()
+	assertChapters(t, chapters, errs, []Chapter{
+		{Number: 682, Title: "Chapter 682"},
+		{Number: 681.5, Title: "Chapter 681.5"},
+		{Number: 681, Title: "Chapter 681"},
+	})
+}
+
+func TestCypherScansFetchChapter(t *testing.T) {
+	c := newTestCypherScans(t)
+	chapters, errs := c.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	chapter, err := c.FetchChapter(chapters[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "https://cdn.cypheroscans.xyz/wp-content/uploads/2025/03/"
+	assertPages(t, chapter, base+"ME-682-01.webp", base+"ME-682-02.webp", base+"ME-682-03.webp")
+}
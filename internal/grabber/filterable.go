@@ -2,6 +2,7 @@ package grabber
 
 import (
 	"sort"
+	"strconv"
 
 	"github.com/NorkzYT/comic-downloader/internal/ranges"
 )
@@ -16,10 +17,22 @@ type Titleable interface {
 	GetTitle() string
 }
 
+// Volumable represents an object that belongs to a volume
+type Volumable interface {
+	GetVolume() string
+}
+
+// Groupable represents an object released by a scanlation group
+type Groupable interface {
+	GetGroup() string
+}
+
 // Filterable represents a filterable object
 type Filterable interface {
 	Enumerable
 	Titleable
+	Volumable
+	Groupable
 }
 
 // Filterables represents a slice of Filterable
@@ -37,17 +50,72 @@ func (f Filterables) Filter(cond func(Filterable) bool) Filterables {
 	return filtered
 }
 
-// FilterRanges returns the specified ranges of Filterables sorted by their Number.
-// It now directly compares float64 values.
+// FilterRanges returns the Filterables matching the specified ranges, keeping their order.
+// A Filterable is kept when it matches any inclusion range and no exclusion range;
+// when only exclusions are given, every Filterable not excluded is kept.
 func (f Filterables) FilterRanges(rngs []ranges.Range) Filterables {
-	chaps := Filterables{}
-	for _, r := range rngs {
-		chaps = append(chaps, f.Filter(func(c Filterable) bool {
-			return c.GetNumber() >= r.Begin && c.GetNumber() <= r.End
-		})...)
+	hasIncludes := false
+	// lastFrom holds, for each "last N" range, the lowest chapter number it selects.
+	lastFrom := make([]float64, len(rngs))
+	for i, r := range rngs {
+		if !r.Exclude {
+			hasIncludes = true
+		}
+		if r.Last > 0 {
+			lastFrom[i] = f.lastNumbersFrom(r.Last)
+		}
+	}
+
+	return f.Filter(func(c Filterable) bool {
+		included := !hasIncludes
+		for i, r := range rngs {
+			if !matchesRange(c, r, lastFrom[i]) {
+				continue
+			}
+			if r.Exclude {
+				return false
+			}
+			included = true
+		}
+		return included
+	})
+}
+
+// matchesRange reports whether the Filterable falls within the range, ignoring Exclude.
+// lastFrom is the lowest chapter number selected when the range is a "last N" range.
+func matchesRange(c Filterable, r ranges.Range, lastFrom float64) bool {
+	switch {
+	case r.Last > 0:
+		return c.GetNumber() >= lastFrom
+	case r.Volume:
+		vol, err := strconv.ParseFloat(c.GetVolume(), 64)
+		if err != nil {
+			return false
+		}
+		return r.Contains(vol)
+	default:
+		return r.Contains(c.GetNumber())
 	}
+}
 
-	return chaps
+// lastNumbersFrom returns the lowest chapter number among the last n distinct chapter numbers.
+func (f Filterables) lastNumbersFrom(n int) float64 {
+	seen := map[float64]bool{}
+	numbers := []float64{}
+	for _, c := range f {
+		if !seen[c.GetNumber()] {
+			seen[c.GetNumber()] = true
+			numbers = append(numbers, c.GetNumber())
+		}
+	}
+	if len(numbers) == 0 {
+		return 0
+	}
+	sort.Float64s(numbers)
+	if n > len(numbers) {
+		n = len(numbers)
+	}
+	return numbers[len(numbers)-n]
 }
 
 // SortByNumber sorts Filterables by Number.
@@ -0,0 +1,41 @@
+package grabber
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/NorkzYT/comic-downloader/internal/ranges"
+)
+
+func TestFilterRanges(t *testing.T) {
+	chapters := Filterables{
+		&Chapter{Title: "1", Number: 1, Volume: "1"},
+		&Chapter{Title: "2", Number: 2, Volume: "1"},
+		&Chapter{Title: "3", Number: 3, Volume: "2"},
+		&Chapter{Title: "3.5", Number: 3.5, Volume: "2"},
+		&Chapter{Title: "4", Number: 4, Volume: "3"},
+		&Chapter{Title: "5", Number: 5},
+	}
+
+	tests := []struct {
+		rng  string
+		want []string
+	}{
+		{"2-3", []string{"2", "3"}},
+		{"4-", []string{"4", "5"}},
+		{"-2", []string{"4", "5"}},
+		{"v2", []string{"3", "3.5"}},
+		{"v1-2", []string{"1", "2", "3", "3.5"}},
+		{"v2-,!3.5", []string{"3", "4"}},
+		{"!v1", []string{"3", "3.5", "4", "5"}},
+	}
+	for _, tt := range tests {
+		rngs, err := ranges.Parse(tt.rng)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.rng, err)
+		}
+		if got := titles(chapters.FilterRanges(rngs)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterRanges(%q) = %v, want %v", tt.rng, got, tt.want)
+		}
+	}
+}
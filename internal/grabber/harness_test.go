@@ -0,0 +1,247 @@
+package grabber
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/browserless"
+)
+
+// fixture returns the content of testdata/<site>/<name>.
+func fixture(t *testing.T, site, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", site, name))
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	return string(data)
+}
+
+// fixtureServer replays the recorded responses of a site from testdata/<site>.
+type fixtureServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*url.URL
+}
+
+// newFixtureServer starts a server answering requests with the fixture files of the site.
+// Routes map "path" or "path?key=value&..." to a file name; a request matches a route when
+// its path is the same and it has every listed query value, the route with the most query
+// values winning. Requests matching no route fail the test.
+func newFixtureServer(t *testing.T, site string, routes map[string]string) *fixtureServer {
+	t.Helper()
+	fs := &fixtureServer{}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		fs.requests = append(fs.requests, r.URL)
+		fs.mu.Unlock()
+
+		file, best := "", -1
+		for route, name := range routes {
+			path, query, _ := strings.Cut(route, "?")
+			values, _ := url.ParseQuery(query)
+			if path != r.URL.Path || len(values) <= best {
+				continue
+			}
+			matches := true
+			for k := range values {
+				if r.URL.Query().Get(k) != values.Get(k) {
+					matches = false
+				}
+			}
+			if matches {
+				file, best = name, len(values)
+			}
+		}
+		if file == "" {
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", site, file))
+		if err != nil {
+			t.Errorf("reading fixture: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", mime.TypeByExtension(filepath.Ext(file)))
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// requested returns the URLs requested to the server with the given path.
+func (fs *fixtureServer) requested(path string) []*url.URL {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	var urls []*url.URL
+	for _, u := range fs.requests {
+		if u.Path == path {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// fakeScript is a canned result of the scripts containing a snippet, run on a page.
+type fakeScript struct {
+	// url is the page URL
+	url string
+	// contains is a snippet identifying the script
+	contains string
+	// result is the script result, converted to the caller result type through JSON like chromedp does
+	result interface{}
+}
+
+// fakeBrowser is a browserless.Runner and browserless.Capturer answering with canned results
+// instead of running scripts.
+type fakeBrowser struct {
+	t       *testing.T
+	scripts []fakeScript
+	// images are the images captured by the pages loaded with LoadCapturing
+	images []browserless.Image
+	mu     sync.Mutex
+	loads  []string
+	open   int
+}
+
+// useFakeBrowser replaces the browser with a fake for the duration of the test.
+// Pages left open fail the test.
+func useFakeBrowser(t *testing.T, scripts ...fakeScript) *fakeBrowser {
+	f := &fakeBrowser{t: t, scripts: scripts}
+	prev := browserless.SetRunner(f)
+	t.Cleanup(func() {
+		browserless.SetRunner(prev)
+		if f.open != 0 {
+			t.Errorf("%d pages left open", f.open)
+		}
+	})
+	return f
+}
+
+// Load implements browserless.Runner.
+func (f *fakeBrowser) Load(url string, waitSelector string, sleepDuration time.Duration) (browserless.Page, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.loads = append(f.loads, url)
+	f.open++
+	return &fakePage{browser: f, url: url}, nil
+}
+
+// LoadCapturing implements browserless.Capturer.
+func (f *fakeBrowser) LoadCapturing(url string, waitSelector string, sleepDuration time.Duration) (browserless.CapturePage, error) {
+	page, err := f.Load(url, waitSelector, sleepDuration)
+	if err != nil {
+		return nil, err
+	}
+	p := page.(*fakePage)
+	p.images = f.images
+	return p, nil
+}
+
+// loaded returns the number of loads of the URL.
+func (f *fakeBrowser) loaded(url string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, u := range f.loads {
+		if u == url {
+			n++
+		}
+	}
+	return n
+}
+
+// fakePage is a page loaded by a fakeBrowser.
+type fakePage struct {
+	browser *fakeBrowser
+	url     string
+	closed  bool
+	images  []browserless.Image
+}
+
+// Images implements browserless.CapturePage.
+func (p *fakePage) Images(time.Duration) []browserless.Image {
+	return p.images
+}
+
+// Close implements browserless.Page.
+func (p *fakePage) Close() {
+	p.browser.mu.Lock()
+	defer p.browser.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		p.browser.open--
+	}
+}
+
+// Eval implements browserless.Page.
+func (p *fakePage) Eval(js string, result interface{}) error {
+	f, url := p.browser, p.url
+	if p.closed {
+		f.t.Errorf("script on closed page %s", url)
+	}
+	for _, s := range f.scripts {
+		if s.url == url && strings.Contains(js, s.contains) {
+			data, err := json.Marshal(s.result)
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal(data, result)
+		}
+	}
+	f.t.Errorf("unexpected script on %s: %.60q", url, js)
+	return errors.New("no canned result")
+}
+
+// testGrabber returns a grabber for the URL with the default settings of the command line.
+func testGrabber(u string, endpoints map[string]string) *Grabber {
+	return &Grabber{
+		URL: u,
+		Settings: &Settings{
+			MaxConcurrency: MaxConcurrency{Chapters: 5, Pages: 10},
+			Format:         "cbz",
+		},
+		Endpoints: endpoints,
+	}
+}
+
+// assertChapters checks the numbers and titles of the chapters.
+func assertChapters(t *testing.T, chapters Filterables, errs []error, want []Chapter) {
+	t.Helper()
+	if len(errs) > 0 {
+		t.Fatalf("FetchChapters errors: %v", errs)
+	}
+	if len(chapters) != len(want) {
+		t.Fatalf("got %d chapters, want %d", len(chapters), len(want))
+	}
+	for i, w := range want {
+		if c := chapters[i]; c.GetNumber() != w.Number || c.GetTitle() != w.Title {
+			t.Errorf("chapter %d: got %g %q, want %g %q", i, c.GetNumber(), c.GetTitle(), w.Number, w.Title)
+		}
+	}
+}
+
+// assertPages checks the page numbers and URLs of the chapter.
+func assertPages(t *testing.T, chapter *Chapter, urls ...string) {
+	t.Helper()
+	if chapter.PagesCount != int64(len(urls)) || len(chapter.Pages) != len(urls) {
+		t.Fatalf("got %d pages (PagesCount %d), want %d", len(chapter.Pages), chapter.PagesCount, len(urls))
+	}
+	for i, u := range urls {
+		if p := chapter.Pages[i]; p.Number != int64(i+1) || p.URL != u {
+			t.Errorf("page %d: got %d %s, want %d %s", i, p.Number, p.URL, i+1, u)
+		}
+	}
+}
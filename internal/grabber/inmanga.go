@@ -11,6 +11,15 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// inmangaURL is the InManga base URL, also serving its chapter API.
+const inmangaURL = "https://inmanga.com"
+
+// Inmanga selectors.
+const (
+	inmangaTitleSelector = "h1"
+	inmangaPagesSelector = "select.PageListClass"
+)
+
 // Inmanga is a grabber for inmanga.com
 type Inmanga struct {
 	*Grabber
@@ -23,6 +32,11 @@ type InmangaChapter struct {
 	Id string
 }
 
+// GetID returns the Inmanga chapter ID
+func (c InmangaChapter) GetID() string {
+	return c.Id
+}
+
 // Test checks if the site is Inmanga
 func (i *Inmanga) Test() (bool, error) {
 	logger.Debug("Inmanga.Test: Checking if URL contains 'inmanga.com': %s", i.URL)
@@ -53,7 +67,7 @@ func (i *Inmanga) FetchTitle() (string, error) {
 		return "", err
 	}
 
-	i.title = doc.Find("h1").Text()
+	i.title = doc.Find(inmangaTitleSelector).Text()
 	logger.Debug("Inmanga.FetchTitle: Fetched title: %s", i.title)
 	return i.title, nil
 }
@@ -65,7 +79,7 @@ func (i Inmanga) FetchChapters() (Filterables, []error) {
 
 	// Retrieve chapters JSON list.
 	body, err := http.GetText(http.RequestParams{
-		URL: "https://inmanga.com/chapter/getall?mangaIdentification=" + id,
+		URL: i.endpoint(inmangaURL) + "/chapter/getall?mangaIdentification=" + id,
 	})
 	if err != nil {
 		logger.Error("Inmanga.FetchChapters: Error fetching chapters JSON: %v", err)
@@ -100,7 +114,7 @@ func (i Inmanga) FetchChapter(chap Filterable) (*Chapter, error) {
 	ichap := chap.(*InmangaChapter)
 	logger.Debug("Inmanga.FetchChapter: Fetching chapter with ID: %s", ichap.Id)
 	body, err := http.Get(http.RequestParams{
-		URL: "https://inmanga.com/chapter/chapterIndexControls?identification=" + ichap.Id,
+		URL: i.endpoint(inmangaURL) + "/chapter/chapterIndexControls?identification=" + ichap.Id,
 	})
 	if err != nil {
 		logger.Error("Inmanga.FetchChapter: Error fetching chapter page: %v", err)
@@ -122,7 +136,7 @@ func (i Inmanga) FetchChapter(chap Filterable) (*Chapter, error) {
 	}
 
 	// Get pages from select (skip duplicate).
-	doc.Find("select.PageListClass").First().Children().Each(func(i int, s *goquery.Selection) {
+	doc.Find(inmangaPagesSelector).First().Children().Each(func(i int, s *goquery.Selection) {
 		num, _ := strconv.ParseInt(s.Text(), 10, 64)
 		pageURL := "https://pack-yak.intomanga.com/images/manga/ms/chapter/ch/page/p/" + s.AttrOr("value", "")
 		logger.Debug("Inmanga.FetchChapter: Adding page %d with URL: %s", num, pageURL)
@@ -135,6 +149,15 @@ func (i Inmanga) FetchChapter(chap Filterable) (*Chapter, error) {
 	return chapter, nil
 }
 
+// Selectors returns the CSS selectors and API endpoints of each scraping step.
+func (i Inmanga) Selectors() map[string]string {
+	return map[string]string{
+		StepTitle:    inmangaTitleSelector,
+		StepChapters: "GET " + i.endpoint(inmangaURL) + "/chapter/getall",
+		StepPages:    inmangaPagesSelector + " (GET " + i.endpoint(inmangaURL) + "/chapter/chapterIndexControls)",
+	}
+}
+
 // newInmangaChapter creates an InmangaChapter from an InmangaChapterFeedResult.
 func newInmangaChapter(c inmangaChapterFeedResult) *InmangaChapter {
 	title := fmt.Sprintf("Capítulo %04d", int64(c.Number))
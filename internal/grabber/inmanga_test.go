@@ -0,0 +1,60 @@
+package grabber
+
+import "testing"
+
+const inmangaID = "646317fc-f37c-4686-b568-df8efc60285d"
+
+// newTestInmanga returns an Inmanga grabber requesting the fixture server.
+func newTestInmanga(t *testing.T) *Inmanga {
+	fs := newFixtureServer(t, "inmanga", map[string]string{
+		"/ver/manga/Kaiju-No-8/" + inmangaID:                                                "series.html",
+		"/chapter/getall?mangaIdentification=" + inmangaID:                                  "getall.json",
+		"/chapter/chapterIndexControls?identification=5b4d4e47-9a0a-4a0b-8a8e-2b0e7d2c1a01": "chapter.html",
+	})
+	return &Inmanga{Grabber: testGrabber(fs.URL+"/ver/manga/Kaiju-No-8/"+inmangaID, map[string]string{inmangaURL: fs.URL})}
+}
+
+func TestInmangaFetchTitle(t *testing.T) {
+	i := newTestInmanga(t)
+	title, err := i.FetchTitle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Kaiju No. 8" {
+		t.Errorf("FetchTitle() = %q, want %q", title, "Kaiju No. 8")
+	}
+}
+
+func TestInmangaFetchChapters(t *testing.T) {
+	i := newTestInmanga(t)
+	chapters, errs := i.FetchChapters()
+	assertChapters(t, chapters, errs, []Chapter{
+		{Number: 1, Title: "Capítulo 0001"},
+		{Number: 2, Title: "Capítulo 0002"},
+		{Number: 10.5, Title: "Capítulo 0010"},
+	})
+	if c := chapters[1].(*InmangaChapter); c.Id != "7c0a1f7e-4b1c-4c55-a6d1-6d1c5a2e0b02" || c.PagesCount != 21 {
+		t.Errorf("chapter 2 = %+v", c)
+	}
+}
+
+func TestInmangaFetchChapter(t *testing.T) {
+	i := newTestInmanga(t)
+	chapters, errs := i.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	chapter, err := i.FetchChapter(chapters[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chapter.Language != "es" {
+		t.Errorf("Language = %q, want es", chapter.Language)
+	}
+	base := "https://pack-yak.intomanga.com/images/manga/ms/chapter/ch/page/p/"
+	assertPages(t, chapter,
+		base+"0f3f5b84-6a7e-4d3c-9a29-4b4d1c9f0a01",
+		base+"1a6e8d2c-2b5f-4e7a-8c3d-5e6f7a8b9c02",
+		base+"2b7f9e3d-3c6a-4f8b-9d4e-6f7a8b9c0d03",
+	)
+}
@@ -6,12 +6,36 @@ import (
 	"net/url"
 	"path"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/NorkzYT/comic-downloader/internal/http"
 	"github.com/NorkzYT/comic-downloader/internal/logger"
 )
 
+const (
+	// mangadexAPI is the MangaDex API base URL.
+	mangadexAPI = "https://api.mangadex.org"
+	// mangadexNetwork is the MangaDex@Home network API base URL, where page downloads are reported.
+	mangadexNetwork = "https://api.mangadex.network"
+	// mangadexUploads is the MangaDex origin image server, whose downloads are not reported.
+	mangadexUploads = "uploads.mangadex.org"
+)
+
+// Image qualities of the MangaDex chapters.
+const (
+	// QualityData is the original quality
+	QualityData = "data"
+	// QualityDataSaver is the compressed quality
+	QualityDataSaver = "data-saver"
+)
+
+// ContentRatings are the MangaDex content ratings.
+var ContentRatings = []string{"safe", "suggestive", "erotica", "pornographic"}
+
 // Mangadex is a grabber for mangadex.org
 type Mangadex struct {
 	*Grabber
@@ -22,6 +46,18 @@ type Mangadex struct {
 type MangadexChapter struct {
 	Chapter
 	Id string
+	// ExternalURL is the URL of the chapter when it is hosted on another site (e.g. MANGA Plus)
+	ExternalURL string
+}
+
+// GetExternalURL returns the URL of the chapter when it is hosted on another site
+func (c MangadexChapter) GetExternalURL() string {
+	return c.ExternalURL
+}
+
+// GetID returns the MangaDex chapter ID
+func (c MangadexChapter) GetID() string {
+	return c.Id
 }
 
 // Test checks if the site is MangaDex
@@ -42,7 +78,7 @@ func (m *Mangadex) FetchTitle() (string, error) {
 	id := getUuid(m.URL)
 
 	rbody, err := http.Get(http.RequestParams{
-		URL:     "https://api.mangadex.org/manga/" + id,
+		URL:     m.endpoint(mangadexAPI) + "/manga/" + id,
 		Referer: m.BaseUrl(),
 	})
 	if err != nil {
@@ -57,17 +93,34 @@ func (m *Mangadex) FetchTitle() (string, error) {
 		return "", err
 	}
 
-	if m.Settings.Language != "" {
-		trans := body.Data.Attributes.AltTitles.GetTitleByLang(m.Settings.Language)
+	for _, lang := range m.Settings.Languages() {
+		trans := body.Data.Attributes.Title[lang]
+		if trans == "" {
+			trans = body.Data.Attributes.AltTitles.GetTitleByLang(lang)
+		}
 		if trans != "" {
 			m.title = trans
-			logger.Debug("Mangadex.FetchTitle: Found translated title: %s", m.title)
+			logger.Debug("Mangadex.FetchTitle: Found %s title: %s", lang, m.title)
 			return m.title, nil
 		}
 	}
 
 	m.title = body.Data.Attributes.Title["en"]
-	logger.Debug("Mangadex.FetchTitle: Using English title: %s", m.title)
+	if m.title == "" {
+		m.title = body.Data.Attributes.Title[body.Data.Attributes.OriginalLanguage]
+	}
+	if m.title == "" {
+		// Fall back to the main title, whatever its language, picking the same one every time.
+		langs := make([]string, 0, len(body.Data.Attributes.Title))
+		for lang := range body.Data.Attributes.Title {
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+		if len(langs) > 0 {
+			m.title = body.Data.Attributes.Title[langs[0]]
+		}
+	}
+	logger.Debug("Mangadex.FetchTitle: Using default title: %s", m.title)
 	return m.title, nil
 }
 
@@ -79,14 +132,18 @@ func (m Mangadex) FetchChapters() (chapters Filterables, errs []error) {
 	baseOffset := 500
 	var fetchChaps func(int)
 	fetchChaps = func(offset int) {
-		uri := fmt.Sprintf("https://api.mangadex.org/manga/%s/feed", id)
+		uri := fmt.Sprintf("%s/manga/%s/feed", m.endpoint(mangadexAPI), id)
 		params := url.Values{}
 		params.Add("limit", fmt.Sprint(baseOffset))
 		params.Add("order[volume]", "asc")
 		params.Add("order[chapter]", "asc")
 		params.Add("offset", fmt.Sprint(offset))
-		if m.Settings.Language != "" {
-			params.Add("translatedLanguage[]", m.Settings.Language)
+		params.Add("includes[]", "scanlation_group")
+		for _, lang := range m.Settings.Languages() {
+			params.Add("translatedLanguage[]", lang)
+		}
+		for _, rating := range m.Settings.ContentRatings {
+			params.Add("contentRating[]", rating)
 		}
 		uri = fmt.Sprintf("%s?%s", uri, params.Encode())
 		logger.Debug("Mangadex.FetchChapters: Fetching chapters with offset %d from URI: %s", offset, uri)
@@ -106,15 +163,21 @@ func (m Mangadex) FetchChapters() (chapters Filterables, errs []error) {
 		}
 
 		for _, c := range body.Data {
-			num, _ := strconv.ParseFloat(c.Attributes.Chapter, 64)
+			// Oneshots have a null chapter number.
+			num, err := strconv.ParseFloat(c.Attributes.Chapter, 64)
 			chapters = append(chapters, &MangadexChapter{
 				Chapter: Chapter{
 					Number:     num,
+					Unnumbered: err != nil,
+					Volume:     c.Attributes.Volume,
 					Title:      c.Attributes.Title,
 					Language:   c.Attributes.TranslatedLanguage,
 					PagesCount: c.Attributes.Pages,
+					Group:      c.Relationships.GroupName(),
+					Date:       c.Attributes.PublishAt,
 				},
-				Id: c.Id,
+				Id:          c.Id,
+				ExternalURL: c.Attributes.ExternalUrl,
 			})
 			logger.Debug("Mangadex.FetchChapters: Added chapter: %s", c.Attributes.Title)
 		}
@@ -128,48 +191,207 @@ func (m Mangadex) FetchChapters() (chapters Filterables, errs []error) {
 	return
 }
 
-// FetchChapter fetches a chapter and its pages.
+// FetchChapter fetches a chapter and its pages. Pages failing to download are retried on
+// another MangaDex@Home server, unless ForceOrigin is set.
 func (m Mangadex) FetchChapter(f Filterable) (*Chapter, error) {
 	logger.Debug("Mangadex.FetchChapter: Fetching chapter...")
 	chap := f.(*MangadexChapter)
-	rbody, err := http.Get(http.RequestParams{
-		URL: "https://api.mangadex.org/at-home/server/" + chap.Id,
-	})
-	if err != nil {
-		logger.Error("Mangadex.FetchChapter: Error fetching chapter page: %v", err)
-		return nil, err
+	if chap.ExternalURL != "" {
+		return nil, fmt.Errorf("%w: read it at %s", ErrExternalChapter, chap.ExternalURL)
 	}
-	body := mangadexPagesFeed{}
-	if err = json.NewDecoder(rbody).Decode(&body); err != nil {
-		logger.Error("Mangadex.FetchChapter: Error decoding pages JSON: %v", err)
+	server, err := m.atHome(chap.Id)
+	if err != nil {
 		return nil, err
 	}
-	pcount := len(body.Chapter.Data)
+	pcount := len(server.files)
 	chapter := &Chapter{
 		Title:      fmt.Sprintf("Chapter %04d %s", int64(f.GetNumber()), chap.Title),
 		Number:     f.GetNumber(),
+		Unnumbered: chap.Unnumbered,
+		Volume:     chap.Volume,
 		PagesCount: int64(pcount),
 		Language:   chap.Language,
+		Group:      chap.Group,
+		Date:       chap.Date,
+	}
+	if !m.Settings.ForceOrigin {
+		chapter.Refresher = server
 	}
-	for i, p := range body.Chapter.Data {
-		num := i + 1
-		pageURL := body.BaseUrl + path.Join("/data", body.Chapter.Hash, p)
+	for i := range server.files {
+		num := int64(i + 1)
+		pageURL := server.url(num)
 		logger.Debug("Mangadex.FetchChapter: Adding page %d with URL: %s", num, pageURL)
 		chapter.Pages = append(chapter.Pages, Page{
-			Number: int64(num),
+			Number: num,
 			URL:    pageURL,
 		})
 	}
 	return chapter, nil
 }
 
+const (
+	// mangadexRefreshes is how many times the MangaDex@Home server of a failing chapter is replaced.
+	mangadexRefreshes = 2
+	// mangadexServerTTL is how long a MangaDex@Home server URL is used; its token is valid
+	// for 15 minutes.
+	mangadexServerTTL = 14 * time.Minute
+)
+
+// mangadexAtHome is the image server of a chapter. It is the PageRefresher of the chapter,
+// replacing the server when pages fail to download from it.
+type mangadexAtHome struct {
+	m  Mangadex
+	id string
+
+	mu sync.Mutex
+	// base is the server URL
+	base string
+	// quality is the path of the image quality, QualityData or QualityDataSaver
+	quality string
+	// hash identifies the chapter on the server
+	hash string
+	// files are the image file names, in page order
+	files []string
+	// refreshes counts the servers requested after pages failed
+	refreshes int
+	// fetched is when the server was given, its token expiring after mangadexServerTTL
+	fetched time.Time
+}
+
+// atHome requests a MangaDex@Home server for the chapter.
+func (m Mangadex) atHome(id string) (*mangadexAtHome, error) {
+	rbody, err := http.Get(http.RequestParams{
+		URL: m.endpoint(mangadexAPI) + "/at-home/server/" + id,
+	})
+	if err != nil {
+		logger.Error("Mangadex.atHome: Error fetching chapter server: %v", err)
+		return nil, err
+	}
+	defer rbody.Close()
+	body := mangadexPagesFeed{}
+	if err = json.NewDecoder(rbody).Decode(&body); err != nil {
+		logger.Error("Mangadex.atHome: Error decoding pages JSON: %v", err)
+		return nil, err
+	}
+
+	a := &mangadexAtHome{m: m, id: id, base: body.BaseUrl, quality: QualityData, hash: body.Chapter.Hash, files: body.Chapter.Data, fetched: time.Now()}
+	if m.Settings.Quality == QualityDataSaver {
+		a.quality, a.files = QualityDataSaver, body.Chapter.DataSaver
+	}
+	if m.Settings.ForceOrigin {
+		a.base = "https://" + mangadexUploads
+	}
+	return a, nil
+}
+
+// url returns the URL of the page on the server.
+func (a *mangadexAtHome) url(page int64) string {
+	return a.base + path.Join("/", a.quality, a.hash, a.files[page-1])
+}
+
+// renew replaces the server with a new one, with a.mu held.
+func (a *mangadexAtHome) renew() error {
+	server, err := a.m.atHome(a.id)
+	if err != nil {
+		return err
+	}
+	a.base, a.hash, a.fetched = server.base, server.hash, server.fetched
+	if len(server.files) == len(a.files) {
+		a.files = server.files
+	}
+	return nil
+}
+
+// RefreshPage implements PageRefresher. The server is replaced unless another page failing
+// on it already did, in which case the page moves to the new server.
+func (a *mangadexAtHome) RefreshPage(p Page) (Page, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if p.Number < 1 || p.Number > int64(len(a.files)) {
+		return p, fmt.Errorf("page %d not in the chapter", p.Number)
+	}
+	if p.URL == a.url(p.Number) {
+		if a.refreshes >= mangadexRefreshes {
+			return p, fmt.Errorf("no other MangaDex@Home server after %d tries", a.refreshes)
+		}
+		a.refreshes++
+		logger.Info("Mangadex.RefreshPage: Requesting another server for chapter %s, %s failed", a.id, a.base)
+		if err := a.renew(); err != nil {
+			return p, err
+		}
+	}
+	p.URL = a.url(p.Number)
+	return p, nil
+}
+
+// ResolvePage implements PageResolver, renewing the MangaDex@Home server of the chapter once its
+// token expired or when the server refuses the page. The origin server URLs do not expire.
+func (m Mangadex) ResolvePage(chapter *Chapter, p Page, stale bool) (Page, error) {
+	a, ok := chapter.Refresher.(*mangadexAtHome)
+	if !ok {
+		return p, nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if p.Number < 1 || p.Number > int64(len(a.files)) {
+		return p, fmt.Errorf("page %d not in the chapter", p.Number)
+	}
+	// A page still on the current server renews it; others only move to it.
+	if time.Since(a.fetched) > mangadexServerTTL || (stale && p.URL == a.url(p.Number)) {
+		logger.Debug("Mangadex.ResolvePage: Renewing the server of chapter %s", a.id)
+		if err := a.renew(); err != nil {
+			return p, err
+		}
+	}
+	p.URL = a.url(p.Number)
+	return p, nil
+}
+
+// ReportPage reports the outcome of a page download to the MangaDex@Home network, as its
+// clients are required to. Downloads from the origin server are not reported. Reports are
+// sent in the background; FlushReports waits for them.
+func (m Mangadex) ReportPage(r PageReport) {
+	if u, err := url.Parse(r.URL); err != nil || u.Host == mangadexUploads {
+		return
+	}
+	endpoint := m.endpoint(mangadexNetwork) + "/report"
+	reports.send(func() {
+		body, err := http.PostJSON(http.JSONParams{
+			RequestParams: http.RequestParams{URL: endpoint},
+			Body: map[string]interface{}{
+				"url":      r.URL,
+				"success":  r.Success,
+				"bytes":    r.Bytes,
+				"duration": r.Duration.Milliseconds(),
+				"cached":   r.Cached,
+			},
+		})
+		if err != nil {
+			// Reports are best effort and never fail the download.
+			logger.Debug("Mangadex.ReportPage: Error reporting %s: %v", r.URL, err)
+			return
+		}
+		body.Close()
+	})
+}
+
+// Selectors returns the API endpoints of each scraping step.
+func (m Mangadex) Selectors() map[string]string {
+	return map[string]string{
+		StepTitle:    "GET " + m.endpoint(mangadexAPI) + "/manga/{id}",
+		StepChapters: "GET " + m.endpoint(mangadexAPI) + "/manga/{id}/feed",
+		StepPages:    "GET " + m.endpoint(mangadexAPI) + "/at-home/server/{chapter id}",
+	}
+}
+
 // mangadexManga represents the Manga JSON object.
 type mangadexManga struct {
 	Id   string
 	Data struct {
 		Attributes struct {
-			Title     map[string]string
-			AltTitles altTitles
+			Title            map[string]string
+			AltTitles        altTitles
+			OriginalLanguage string
 		}
 	}
 }
@@ -197,8 +419,32 @@ type mangadexFeed struct {
 			Title              string
 			TranslatedLanguage string
 			Pages              int64
+			PublishAt          time.Time
+			ExternalUrl        string
+		}
+		Relationships mangadexRelationships
+	}
+}
+
+// mangadexRelationships are the entities related to a MangaDex object.
+type mangadexRelationships []struct {
+	Id         string
+	Type       string
+	Attributes struct {
+		Name string
+	}
+}
+
+// GroupName returns the names of the related scanlation groups joined by " & ".
+// Attributes are only present when the request includes "includes[]=scanlation_group".
+func (r mangadexRelationships) GroupName() string {
+	names := []string{}
+	for _, rel := range r {
+		if rel.Type == "scanlation_group" && rel.Attributes.Name != "" {
+			names = append(names, rel.Attributes.Name)
 		}
 	}
+	return strings.Join(names, " & ")
 }
 
 // mangadexPagesFeed represents the JSON object returned by the pages endpoint.
@@ -0,0 +1,325 @@
+package grabber
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const mangadexID = "a1c7c817-4e59-43b7-9365-09675a149a6f"
+
+// newTestMangadex returns a Mangadex grabber requesting the fixture server.
+func newTestMangadex(t *testing.T, language string) (*Mangadex, *fixtureServer) {
+	fs := newFixtureServer(t, "mangadex", map[string]string{
+		"/manga/" + mangadexID:                                 "manga.json",
+		"/manga/" + mangadexID + "/feed?offset=0":              "feed.json",
+		"/manga/" + mangadexID + "/feed?offset=500":            "feed-end.json",
+		"/at-home/server/6310f6a1-17ee-4890-b837-2ec1b372905b": "at-home.json",
+	})
+	g := testGrabber("https://mangadex.org/title/"+mangadexID+"/one-piece", map[string]string{mangadexAPI: fs.URL})
+	g.Settings.Language = language
+	return &Mangadex{Grabber: g}, fs
+}
+
+func TestMangadexFetchTitle(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"", "One Piece"},
+		{"en", "One Piece"},
+		{"es-la,en", "One Piece: Edición Latina"},
+		{"fr,ja", "ワンピース"},
+	}
+	for _, tt := range tests {
+		m, _ := newTestMangadex(t, tt.language)
+		got, err := m.FetchTitle()
+		if err != nil {
+			t.Fatalf("FetchTitle(%q): %v", tt.language, err)
+		}
+		if got != tt.want {
+			t.Errorf("FetchTitle(%q) = %q, want %q", tt.language, got, tt.want)
+		}
+	}
+}
+
+func TestMangadexFetchChapters(t *testing.T) {
+	m, fs := newTestMangadex(t, "en,es-la")
+	chapters, errs := m.FetchChapters()
+	assertChapters(t, chapters, errs, []Chapter{
+		{Number: 1, Title: "Romance Dawn"},
+		{Number: 1, Title: "Romance Dawn"},
+		{Number: 2, Title: "They Call Him Straw Hat Luffy"},
+		{Number: 2.5, Title: ""},
+	})
+
+	if got := chapters[1].(*MangadexChapter).ExternalURL; got != "https://mangaplus.shueisha.co.jp/viewer/1000486" {
+		t.Errorf("chapter 1 external URL = %q", got)
+	}
+	second := chapters[2].(*MangadexChapter)
+	if second.Group != "TCB Scans & Mangastream" || second.Volume != "1" || second.Language != "en" || second.PagesCount != 23 || second.ExternalURL != "" {
+		t.Errorf("chapter 2 = %+v", second)
+	}
+	if chapters[3].(*MangadexChapter).Language != "es-la" {
+		t.Errorf("chapter 2.5 language = %q, want es-la", chapters[3].(*MangadexChapter).Language)
+	}
+
+	feeds := fs.requested("/manga/" + mangadexID + "/feed")
+	if len(feeds) != 2 {
+		t.Fatalf("got %d feed requests, want 2 (until an empty page)", len(feeds))
+	}
+	if got := feeds[0].Query()["translatedLanguage[]"]; !reflect.DeepEqual(got, []string{"en", "es-la"}) {
+		t.Errorf("translatedLanguage[] = %v, want [en es-la]", got)
+	}
+	if got := feeds[0].Query().Get("includes[]"); got != "scanlation_group" {
+		t.Errorf("includes[] = %q, want scanlation_group", got)
+	}
+	if got := feeds[0].Query()["contentRating[]"]; got != nil {
+		t.Errorf("contentRating[] = %v, want none (the API default)", got)
+	}
+}
+
+func TestMangadexContentRatings(t *testing.T) {
+	m, fs := newTestMangadex(t, "en")
+	m.Settings.ContentRatings = []string{"safe", "pornographic"}
+	if _, errs := m.FetchChapters(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	feed := fs.requested("/manga/" + mangadexID + "/feed")[0]
+	if got := feed.Query()["contentRating[]"]; !reflect.DeepEqual(got, []string{"safe", "pornographic"}) {
+		t.Errorf("contentRating[] = %v, want [safe pornographic]", got)
+	}
+}
+
+func TestMangadexExternalChapters(t *testing.T) {
+	m, _ := newTestMangadex(t, "en")
+	chapters, errs := m.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	// The hosted release wins over the more recent external one.
+	selected := chapters.SelectCandidates(SelectionPolicy{Prefer: PreferLatest})
+	if len(selected) != 3 || selected[0].(*MangadexChapter).ExternalURL != "" {
+		t.Errorf("selected %d chapters, first %+v", len(selected), selected[0])
+	}
+
+	_, err := m.FetchChapter(chapters[1])
+	if !errors.Is(err, ErrExternalChapter) || !strings.Contains(err.Error(), "mangaplus.shueisha.co.jp") {
+		t.Errorf("FetchChapter(external) = %v, want ErrExternalChapter with its URL", err)
+	}
+}
+
+func TestMangadexFetchChapter(t *testing.T) {
+	m, _ := newTestMangadex(t, "en")
+	chapters, errs := m.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	chapter, err := m.FetchChapter(chapters[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chapter.Title != "Chapter 0001 Romance Dawn" || chapter.Group != "TCB Scans" || chapter.Language != "en" {
+		t.Errorf("chapter = %+v", chapter)
+	}
+	base := "https://uploads.mangadex.org/data/3303dd03ac8d27452cce3f2a882e94b2/"
+	assertPages(t, chapter,
+		base+"1-f7a76de10d346de7ba01786762ebbedc666b412ad0d4b73baa330a2a392dbcdd.png",
+		base+"2-e1c8d5e6d9fa9fcbb5ea29d41e2b1a1a5f6e1a54e8d6c0ad8b3e3c3b6c2d5f71.png",
+		base+"3-1f4b0b4d8f0b8ab6e4b4c0c7b0d86a4ac2c5c2e1d0e6f7a8b9c0d1e2f3a4b5c6.png",
+	)
+
+	m.Settings.Quality = QualityDataSaver
+	chapter, err = m.FetchChapter(chapters[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	base = "https://uploads.mangadex.org/data-saver/3303dd03ac8d27452cce3f2a882e94b2/"
+	assertPages(t, chapter,
+		base+"1-27e8c8e1c5d9f3b0cbb8d3ac2a7f7cdd8cc9b0b6f1c4a0e7b1c2d3e4f5a6b7c8.jpg",
+		base+"2-9a4ff1d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5.jpg",
+		base+"3-0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c.jpg",
+	)
+}
+
+func TestMangadexRefreshPage(t *testing.T) {
+	var servers int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		servers++
+		fmt.Fprintf(w, `{"baseUrl": "https://node%d.mangadex.network/token", "chapter": {"hash": "hash", "data": ["1.png", "2.png"]}}`, servers)
+	}))
+	defer srv.Close()
+	m := &Mangadex{Grabber: testGrabber("https://mangadex.org/title/"+mangadexID, map[string]string{mangadexAPI: srv.URL})}
+
+	chapter, err := m.FetchChapter(&MangadexChapter{Chapter: Chapter{Number: 1}, Id: "chapter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chapter.Refresher == nil {
+		t.Fatal("no Refresher on the chapter")
+	}
+	assertPages(t, chapter, "https://node1.mangadex.network/token/data/hash/1.png", "https://node1.mangadex.network/token/data/hash/2.png")
+
+	// Both pages fail on the first server: the first one replaces it, the second one moves to the new server.
+	p1, err := chapter.Refresher.RefreshPage(chapter.Pages[0])
+	if err != nil || p1.URL != "https://node2.mangadex.network/token/data/hash/1.png" {
+		t.Fatalf("RefreshPage(1) = %s, %v", p1.URL, err)
+	}
+	p2, err := chapter.Refresher.RefreshPage(chapter.Pages[1])
+	if err != nil || p2.URL != "https://node2.mangadex.network/token/data/hash/2.png" || servers != 2 {
+		t.Fatalf("RefreshPage(2) = %s, %v after %d server requests", p2.URL, err, servers)
+	}
+
+	// The servers to try are limited.
+	for i := 0; i < mangadexRefreshes; i++ {
+		p1, err = chapter.Refresher.RefreshPage(p1)
+	}
+	if err == nil {
+		t.Errorf("RefreshPage succeeded after %d servers", servers)
+	}
+}
+
+func TestMangadexResolvePage(t *testing.T) {
+	var servers int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		servers++
+		fmt.Fprintf(w, `{"baseUrl": "https://node%d.mangadex.network/token", "chapter": {"hash": "hash", "data": ["1.png", "2.png"]}}`, servers)
+	}))
+	defer srv.Close()
+	m := &Mangadex{Grabber: testGrabber("https://mangadex.org/title/"+mangadexID, map[string]string{mangadexAPI: srv.URL})}
+	chapter, err := m.FetchChapter(&MangadexChapter{Chapter: Chapter{Number: 1}, Id: "chapter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh server is kept.
+	p, err := m.ResolvePage(chapter, chapter.Pages[0], false)
+	if err != nil || p.URL != chapter.Pages[0].URL || servers != 1 {
+		t.Fatalf("ResolvePage(fresh) = %s, %v after %d server requests", p.URL, err, servers)
+	}
+
+	// An expired one is renewed.
+	chapter.Refresher.(*mangadexAtHome).fetched = time.Now().Add(-mangadexServerTTL - time.Minute)
+	p, err = m.ResolvePage(chapter, chapter.Pages[0], false)
+	if err != nil || p.URL != "https://node2.mangadex.network/token/data/hash/1.png" {
+		t.Fatalf("ResolvePage(expired) = %s, %v", p.URL, err)
+	}
+
+	// A refused page renews it, unless another page already did.
+	p, err = m.ResolvePage(chapter, p, true)
+	if err != nil || p.URL != "https://node3.mangadex.network/token/data/hash/1.png" {
+		t.Fatalf("ResolvePage(stale) = %s, %v", p.URL, err)
+	}
+	p, err = m.ResolvePage(chapter, chapter.Pages[1], true)
+	if err != nil || p.URL != "https://node3.mangadex.network/token/data/hash/2.png" || servers != 3 {
+		t.Fatalf("ResolvePage(stale, old server) = %s, %v after %d server requests", p.URL, err, servers)
+	}
+}
+
+func TestMangadexForceOrigin(t *testing.T) {
+	m, _ := newTestMangadex(t, "en")
+	m.Settings.ForceOrigin = true
+	chapters, errs := m.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	chapter, err := m.FetchChapter(chapters[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chapter.Refresher != nil || !strings.HasPrefix(chapter.Pages[0].URL, "https://uploads.mangadex.org/data/") {
+		t.Errorf("got Refresher %v and page URL %s, want none and the origin", chapter.Refresher, chapter.Pages[0].URL)
+	}
+}
+
+func TestMangadexUnnumberedChapters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") != "0" {
+			fmt.Fprint(w, `{"data": []}`)
+			return
+		}
+		fmt.Fprint(w, `{"data": [
+			{"id": "a", "attributes": {"chapter": null, "title": "Oneshot"}},
+			{"id": "b", "attributes": {"chapter": "0", "title": "Prologue"}}
+		]}`)
+	}))
+	defer srv.Close()
+	m := &Mangadex{Grabber: testGrabber("https://mangadex.org/title/"+mangadexID, map[string]string{mangadexAPI: srv.URL})}
+
+	chapters, errs := m.FetchChapters()
+	if len(errs) > 0 || len(chapters) != 2 {
+		t.Fatalf("FetchChapters = %v, %v, want 2 chapters", chapters, errs)
+	}
+	if !Unnumbered(chapters[0]) || Unnumbered(chapters[1]) {
+		t.Errorf("unnumbered = %v, %v, want only the null chapter unnumbered", Unnumbered(chapters[0]), Unnumbered(chapters[1]))
+	}
+}
+
+func TestMangadexFetchTitleFallback(t *testing.T) {
+	tests := []struct {
+		attributes string
+		want       string
+	}{
+		{`{"title": {"ko": "Korean", "ja-ro": "Romaji"}, "originalLanguage": "ko"}`, "Korean"},
+		{`{"title": {"ko": "Korean", "ja-ro": "Romaji", "zh": "Chinese"}, "originalLanguage": "ja"}`, "Romaji"},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"data": {"attributes": %s}}`, tt.attributes)
+		}))
+		m := &Mangadex{Grabber: testGrabber("https://mangadex.org/title/"+mangadexID, map[string]string{mangadexAPI: srv.URL})}
+		m.Settings.Language = "fr"
+		got, err := m.FetchTitle()
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("FetchTitle() with %s = %q, want %q", tt.attributes, got, tt.want)
+		}
+	}
+}
+
+func TestMangadexReportPage(t *testing.T) {
+	var mu sync.Mutex
+	var reports []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var report map[string]interface{}
+		if r.Method != http.MethodPost || r.URL.Path != "/report" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("decoding report: %v", err)
+		}
+		reports = append(reports, report)
+	}))
+	defer srv.Close()
+	m := &Mangadex{Grabber: testGrabber("https://mangadex.org/title/"+mangadexID, map[string]string{mangadexNetwork: srv.URL})}
+
+	m.ReportPage(PageReport{URL: "https://uploads.mangadex.org/data/hash/1.png", Success: true, Bytes: 10})
+	FlushReports()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) != 0 {
+		t.Fatalf("got %d reports for the origin server, want 0", len(reports))
+	}
+	page := "https://abc.xyz.mangadex.network:443/token/data/hash/1.png"
+	mu.Unlock()
+	m.ReportPage(PageReport{URL: page, Success: true, Bytes: 1024, Duration: 250 * time.Millisecond, Cached: true})
+	FlushReports()
+	mu.Lock()
+	want := map[string]interface{}{"url": page, "success": true, "bytes": float64(1024), "duration": float64(250), "cached": true}
+	if len(reports) != 1 || !reflect.DeepEqual(reports[0], want) {
+		t.Errorf("reports = %v, want [%v]", reports, want)
+	}
+}
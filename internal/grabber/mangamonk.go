@@ -11,6 +11,13 @@ import (
 	"github.com/NorkzYT/comic-downloader/internal/logger"
 )
 
+// Mangamonk selectors.
+const (
+	mangamonkTitleSelector    = "div.name.box h1"
+	mangamonkChaptersSelector = "ul.chapter-list li"
+	mangamonkPagesSelector    = "#chapter-images .chapter-image img"
+)
+
 // Mangamonk implements the Site interface for mangamonk.com using browserless/chromedp.
 type Mangamonk struct {
 	*Grabber
@@ -36,9 +43,15 @@ func (a *Mangamonk) UsesBrowser() bool {
 // FetchTitle navigates to the series URL and extracts the comic title.
 func (m *Mangamonk) FetchTitle() (string, error) {
 	var title string
-	jsTitle := `document.querySelector("div.name.box h1") ? document.querySelector("div.name.box h1").innerText : ""`
+	jsTitle := fmt.Sprintf(`document.querySelector(%[1]s) ? document.querySelector(%[1]s).innerText : ""`, jsString(mangamonkTitleSelector))
 	logger.Debug("Mangamonk.FetchTitle: Running JS for title extraction on %s", m.URL)
-	err := browserless.RunJS(m.URL, "div.name.box h1", 0, jsTitle, &title)
+	page, err := browserless.Load(m.URL, mangamonkTitleSelector, 0)
+	if err != nil {
+		logger.Error("Mangamonk.FetchTitle: Error loading series page: %v", err)
+		return "", fmt.Errorf("error loading series page: %w", err)
+	}
+	defer page.Close()
+	err = page.Eval(jsTitle, &title)
 	if err != nil {
 		logger.Error("Mangamonk.FetchTitle: Error fetching title: %v", err)
 		return "", fmt.Errorf("error fetching title: %w", err)
@@ -48,7 +61,7 @@ func (m *Mangamonk) FetchTitle() (string, error) {
 		// Fallback to document.title if necessary.
 		jsDocTitle := `document.title`
 		logger.Debug("Mangamonk.FetchTitle: Title empty, falling back to document.title on %s", m.URL)
-		err = browserless.RunJS(m.URL, "body", 0, jsDocTitle, &title)
+		err = page.Eval(jsDocTitle, &title)
 		if err != nil {
 			logger.Error("Mangamonk.FetchTitle: Error fetching document.title: %v", err)
 			return "", fmt.Errorf("error fetching document.title: %w", err)
@@ -64,7 +77,7 @@ func (m *Mangamonk) FetchChapters() (Filterables, []error) {
 	var chaptersJSON string
 	jsChapters := `(function(){
 		var chapters = [];
-		var items = document.querySelectorAll("ul.chapter-list li");
+		var items = document.querySelectorAll(` + jsString(mangamonkChaptersSelector) + `);
 		for(var i = 0; i < items.length; i++){
 			var link = items[i].querySelector("a");
 			if(!link) continue;
@@ -126,12 +139,14 @@ func (m *Mangamonk) FetchChapterWithProgress(f Filterable, progressCallback func
 		return nil, fmt.Errorf("invalid chapter type")
 	}
 	logger.Debug("Mangamonk.FetchChapterWithProgress: Fetching chapter from URL: %s", mc.URL)
-	// Ensure the chapter page is loaded.
-	_, err := browserless.FetchStringWithProgress(mc.URL, "body", `document.documentElement.outerHTML`, 10*time.Second, progressCallback)
+	stop := browserless.WithProgress(progressCallback)
+	defer stop()
+	page, err := browserless.Load(mc.URL, "body", 10*time.Second)
 	if err != nil {
 		logger.Error("Mangamonk.FetchChapterWithProgress: Failed to fetch chapter page: %v", err)
 		return nil, fmt.Errorf("failed to fetch chapter page: %w", err)
 	}
+	defer page.Close()
 
 	var imageSrcs []string
 	jsImages := `(function(){
@@ -140,7 +155,7 @@ func (m *Mangamonk) FetchChapterWithProgress(f Filterable, progressCallback func
 		var start = Date.now();
 		while(Date.now() - start < 1000){}
 		// Only select images that are inside elements with the "chapter-image" class.
-		var imgs = document.querySelectorAll("#chapter-images .chapter-image img");
+		var imgs = document.querySelectorAll(` + jsString(mangamonkPagesSelector) + `);
 		var srcs = [];
 		for (var i = 0; i < imgs.length; i++){
 			var src = imgs[i].getAttribute("src");
@@ -150,7 +165,7 @@ func (m *Mangamonk) FetchChapterWithProgress(f Filterable, progressCallback func
 		}
 		return srcs;
 	})();`
-	imageSrcs, err = browserless.FetchStringSliceWithProgress(mc.URL, "body", jsImages, 10*time.Second, progressCallback)
+	err = page.Eval(jsImages, &imageSrcs)
 	if err != nil {
 		logger.Error("Mangamonk.FetchChapterWithProgress: Failed to extract image URLs: %v", err)
 		return nil, fmt.Errorf("failed to extract image URLs: %w", err)
@@ -183,6 +198,15 @@ func (m *Mangamonk) FetchChapter(f Filterable) (*Chapter, error) {
 	return m.FetchChapterWithProgress(f, func() {})
 }
 
+// Selectors returns the CSS selectors of each scraping step.
+func (m *Mangamonk) Selectors() map[string]string {
+	return map[string]string{
+		StepTitle:    mangamonkTitleSelector,
+		StepChapters: mangamonkChaptersSelector,
+		StepPages:    mangamonkPagesSelector,
+	}
+}
+
 // BaseUrl returns the base URL for mangamonk.com derived from the chapter URL.
 func (m *Mangamonk) BaseUrl() string {
 	u, err := url.Parse(m.URL)
@@ -0,0 +1,49 @@
+package grabber
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const mangamonkURL = "https://mangamonk.com/infinite-mage"
+
+func TestMangamonkFetchTitle(t *testing.T) {
+	m := &Mangamonk{Grabber: testGrabber(mangamonkURL, nil)}
+	useFakeBrowser(t, fakeScript{url: mangamonkURL, contains: "div.name.box h1", result: "Infinite Mage "})
+	title, err := m.FetchTitle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Infinite Mage" {
+		t.Errorf("FetchTitle() = %q, want %q", title, "Infinite Mage")
+	}
+}
+
+func TestMangamonkFetchChapters(t *testing.T) {
+	m := &Mangamonk{Grabber: testGrabber(mangamonkURL, nil)}
+	useFakeBrowser(t, fakeScript{url: mangamonkURL, contains: "ul.chapter-list li", result: fixture(t, "mangamonk", "chapters.json")})
+	chapters, errs := m.FetchChapters()
+	assertChapters(t, chapters, errs, []Chapter{
+		{Number: 212, Title: "Chapter 212"},
+		{Number: 211.5, Title: "Chapter 211.5 - Side Story"},
+		{Number: 211, Title: "Chapter 211"},
+	})
+}
+
+func TestMangamonkFetchChapter(t *testing.T) {
+	m := &Mangamonk{Grabber: testGrabber(mangamonkURL, nil)}
+	chapterURL := mangamonkURL + "/chapter-212"
+	pages := fixture(t, "mangamonk", "pages.json")
+	useFakeBrowser(t,
+		fakeScript{url: chapterURL, contains: "#chapter-images .chapter-image img", result: json.RawMessage(pages)},
+	)
+	chapter, err := m.FetchChapter(&MangamonkChapter{Chapter: Chapter{Title: "Chapter 212", Number: 212}, URL: chapterURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPages(t, chapter, "https://cdn.mangamonk.com/infinite-mage/chapter-212/1.jpg", "https://cdn.mangamonk.com/infinite-mage/chapter-212/2.jpg")
+
+	if _, err := m.FetchChapter(&AsuraChapter{}); err == nil {
+		t.Error("FetchChapter() of another site chapter succeeded")
+	}
+}
@@ -14,6 +14,15 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+const (
+	// reaperscansURL is the ReaperScans site URL.
+	reaperscansURL = "https://reaperscans.com"
+	// reaperscansAPI is the ReaperScans API base URL.
+	reaperscansAPI = "https://api.reaperscans.com"
+	// reaperscansPagesSelector selects the page images of a chapter page.
+	reaperscansPagesSelector = "div.container div.flex.flex-col.justify-center.items-center img"
+)
+
 // ReaperScans implements the Site interface for reaperscans.com.
 type ReaperScans struct {
 	*Grabber
@@ -83,11 +92,11 @@ func (r *ReaperScans) FetchTitle() (string, error) {
 // For example, calling https://api.reaperscans.com/series/the-100th-regression-of-the-max-level-player
 // returns a JSON object with an "id" field.
 func (r *ReaperScans) getSeriesID(slug string) (int, error) {
-	apiURL := fmt.Sprintf("https://api.reaperscans.com/series/%s", url.QueryEscape(slug))
+	apiURL := fmt.Sprintf("%s/series/%s", r.endpoint(reaperscansAPI), url.QueryEscape(slug))
 	logger.Debug("ReaperScans.getSeriesID: Fetching series data from URL: %s", apiURL)
 	rbody, err := http.Get(http.RequestParams{
 		URL:     apiURL,
-		Referer: "https://reaperscans.com/",
+		Referer: r.BaseUrl() + "/",
 	})
 	if err != nil {
 		logger.Error("ReaperScans.getSeriesID: Error fetching series data: %v", err)
@@ -126,11 +135,11 @@ func (r *ReaperScans) FetchChapters() (Filterables, []error) {
 	page := 1
 
 	for {
-		apiURL := fmt.Sprintf("https://api.reaperscans.com/chapters/%d?page=%d&perPage=%d&order=desc", seriesID, page, perPage)
+		apiURL := fmt.Sprintf("%s/chapters/%d?page=%d&perPage=%d&order=desc", r.endpoint(reaperscansAPI), seriesID, page, perPage)
 		logger.Debug("ReaperScans.FetchChapters: Fetching chapters with page %d from URI: %s", page, apiURL)
 		rbody, err := http.Get(http.RequestParams{
 			URL:     apiURL,
-			Referer: "https://reaperscans.com/",
+			Referer: r.BaseUrl() + "/",
 		})
 		if err != nil {
 			logger.Error("ReaperScans.FetchChapters: Error fetching chapters: %v", err)
@@ -152,7 +161,7 @@ func (r *ReaperScans) FetchChapters() (Filterables, []error) {
 		}
 
 		for _, ch := range feed.Data {
-			num, _ := strconv.ParseFloat(ch.Index, 64)
+			num, err := strconv.ParseFloat(ch.Index, 64)
 			title := ch.ChapterName
 			if ch.ChapterTitle != nil && *ch.ChapterTitle != "" {
 				title = *ch.ChapterTitle
@@ -162,6 +171,7 @@ func (r *ReaperScans) FetchChapters() (Filterables, []error) {
 			chapter := &ReaperScansChapter{
 				Chapter: Chapter{
 					Number:     num,
+					Unnumbered: err != nil,
 					Title:      title,
 					Language:   "en",
 					PagesCount: 0, // To be set when fetching chapter pages
@@ -209,13 +219,14 @@ func (r *ReaperScans) FetchChapter(f Filterable) (*Chapter, error) {
 
 	// Initialize the chapter.
 	chapter := &Chapter{
-		Title:    rsChap.GetTitle(),
-		Number:   rsChap.GetNumber(),
-		Language: "en",
+		Title:      rsChap.GetTitle(),
+		Number:     rsChap.GetNumber(),
+		Unnumbered: rsChap.Unnumbered,
+		Language:   "en",
 	}
 
 	// For each <img> element in the container, extract the src.
-	doc.Find("div.container div.flex.flex-col.justify-center.items-center img").Each(func(i int, s *goquery.Selection) {
+	doc.Find(reaperscansPagesSelector).Each(func(i int, s *goquery.Selection) {
 		src := strings.TrimSpace(s.AttrOr("src", ""))
 		if src == "" {
 			logger.Info("ReaperScans.FetchChapter: Image at index %d has empty src", i)
@@ -242,9 +253,18 @@ func (r *ReaperScans) FetchChapter(f Filterable) (*Chapter, error) {
 	return chapter, nil
 }
 
+// Selectors returns the CSS selectors and API endpoints of each scraping step.
+func (r *ReaperScans) Selectors() map[string]string {
+	return map[string]string{
+		StepTitle:    "URL slug",
+		StepChapters: "GET " + r.endpoint(reaperscansAPI) + "/series/{slug}, /chapters/{id}",
+		StepPages:    reaperscansPagesSelector,
+	}
+}
+
 // BaseUrl returns the official base URL for ReaperScans.
 func (r *ReaperScans) BaseUrl() string {
-	return "https://reaperscans.com"
+	return r.endpoint(reaperscansURL)
 }
 
 // GetFilenameTemplate returns the filename template from settings.
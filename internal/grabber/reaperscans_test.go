@@ -0,0 +1,71 @@
+package grabber
+
+import "testing"
+
+const reaperscansSlug = "the-100th-regression-of-the-max-level-player"
+
+// newTestReaperScans returns a ReaperScans grabber requesting the fixture server for both
+// the site and its API.
+func newTestReaperScans(t *testing.T) (*ReaperScans, *fixtureServer) {
+	fs := newFixtureServer(t, "reaperscans", map[string]string{
+		"/series/" + reaperscansSlug:                 "series.json",
+		"/chapters/74?page=1":                        "chapters-1.json",
+		"/chapters/74?page=2":                        "chapters-2.json",
+		"/series/" + reaperscansSlug + "/chapter-51": "chapter.html",
+	})
+	g := testGrabber("https://reaperscans.com/series/"+reaperscansSlug, map[string]string{
+		reaperscansURL: fs.URL,
+		reaperscansAPI: fs.URL,
+	})
+	return &ReaperScans{Grabber: g}, fs
+}
+
+func TestReaperScansFetchTitle(t *testing.T) {
+	r, _ := newTestReaperScans(t)
+	title, err := r.FetchTitle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "The 100th Regression Of The Max Level Player"; title != want {
+		t.Errorf("FetchTitle() = %q, want %q", title, want)
+	}
+
+	r.URL = "reaperscans"
+	if _, err := r.FetchTitle(); err == nil {
+		t.Error("FetchTitle() without a series slug succeeded")
+	}
+}
+
+func TestReaperScansFetchChapters(t *testing.T) {
+	r, fs := newTestReaperScans(t)
+	chapters, errs := r.FetchChapters()
+	assertChapters(t, chapters, errs, []Chapter{
+		{Number: 51, Title: "The Last Floor"},
+		{Number: 50.5, Title: "Chapter 50.5"},
+		{Number: 50, Title: "Chapter 50"},
+	})
+	if got, want := chapters[0].(*ReaperScansChapter).URL, fs.URL+"/series/"+reaperscansSlug+"/chapter-51"; got != want {
+		t.Errorf("chapter URL = %q, want %q", got, want)
+	}
+	if n := len(fs.requested("/chapters/74")); n != 2 {
+		t.Errorf("got %d chapter pages requested, want 2", n)
+	}
+}
+
+func TestReaperScansFetchChapter(t *testing.T) {
+	r, fs := newTestReaperScans(t)
+	chapters, errs := r.FetchChapters()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	chapter, err := r.FetchChapter(chapters[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "https://media.reaperscans.com/file/4SRBHm/comics/the-100th-regression/chapter-51/"
+	assertPages(t, chapter, base+"01.jpg", base+"02.jpg", fs.URL+"/uploads/chapter-51/03.jpg")
+
+	if _, err := r.FetchChapter(&MangadexChapter{}); err == nil {
+		t.Error("FetchChapter() of another site chapter succeeded")
+	}
+}
@@ -0,0 +1,48 @@
+package grabber
+
+import (
+	"sync"
+
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+)
+
+// reportQueueSize is the number of page reports waiting to be sent before new ones are dropped.
+const reportQueueSize = 256
+
+// reports sends the page reports in the background, so the page downloads never wait for them.
+var reports reportSender
+
+// reportSender sends reports one at a time from a single goroutine.
+type reportSender struct {
+	once    sync.Once
+	queue   chan func()
+	pending sync.WaitGroup
+}
+
+// send queues a report, dropping it when the queue is full.
+func (s *reportSender) send(report func()) {
+	s.once.Do(func() {
+		s.queue = make(chan func(), reportQueueSize)
+		go s.run()
+	})
+	s.pending.Add(1)
+	select {
+	case s.queue <- report:
+	default:
+		s.pending.Done()
+		logger.Debug("reportSender.send: Queue full, dropping report")
+	}
+}
+
+func (s *reportSender) run() {
+	for report := range s.queue {
+		report()
+		s.pending.Done()
+	}
+}
+
+// FlushReports waits for the queued page reports to be sent. It is called before exiting,
+// once no more pages are downloaded.
+func FlushReports() {
+	reports.pending.Wait()
+}
@@ -0,0 +1,167 @@
+package grabber
+
+import (
+	"strings"
+	"time"
+)
+
+// Tie-breakers used when several candidates share the same chapter number.
+const (
+	// PreferPages keeps the candidate with the most pages
+	PreferPages = "pages"
+	// PreferLatest keeps the most recently uploaded candidate
+	PreferLatest = "latest"
+)
+
+// SelectionPolicy decides which candidate is kept when several chapters share the same number
+// (e.g. several scanlation groups translating the same MangaDex chapter).
+type SelectionPolicy struct {
+	// Languages lists languages in order of preference
+	Languages []string
+	// PerLanguage keeps one candidate per chapter number and language instead of per chapter number
+	PerLanguage bool
+	// PreferGroups lists scanlation groups in order of preference
+	PreferGroups []string
+	// ExcludeGroups lists scanlation groups whose chapters are never downloaded
+	ExcludeGroups []string
+	// Prefer is the tie-breaker between equally preferred groups (PreferPages or PreferLatest)
+	Prefer string
+}
+
+// SelectCandidates drops chapters from excluded groups and keeps a single candidate
+// per chapter number according to the policy. Chapters without number (oneshots, specials
+// or numbers that could not be parsed) are all kept, while chapters numbered 0 compete
+// like any other number. The order of the kept chapters is preserved.
+func (f Filterables) SelectCandidates(p SelectionPolicy) Filterables {
+	best := map[candidateKey]int{}
+	for i, c := range f {
+		if p.excluded(c.GetGroup()) {
+			continue
+		}
+		key := p.key(c, i)
+		cur, ok := best[key]
+		if !ok || p.better(c, f[cur]) {
+			best[key] = i
+		}
+	}
+
+	selected := Filterables{}
+	for i, c := range f {
+		if cur, ok := best[p.key(c, i)]; ok && cur == i {
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+// candidateKey identifies the chapters competing with each other.
+type candidateKey struct {
+	number   float64
+	language string
+	// unnumbered is the position (from 1) of a chapter without number, which competes with no other
+	unnumbered int
+}
+
+// key returns the candidate key of the Filterable at position i.
+func (p SelectionPolicy) key(c Filterable, i int) candidateKey {
+	if Unnumbered(c) {
+		return candidateKey{unnumbered: i + 1}
+	}
+	if p.PerLanguage {
+		return candidateKey{number: c.GetNumber(), language: languageOf(c)}
+	}
+	return candidateKey{number: c.GetNumber()}
+}
+
+// better reports whether candidate a should be preferred over candidate b.
+// A candidate hosted on an external site can not be downloaded, so any other is preferred.
+func (p SelectionPolicy) better(a, b Filterable) bool {
+	if ea, eb := ExternalURL(a) != "", ExternalURL(b) != ""; ea != eb {
+		return eb
+	}
+	la, lb := rank(p.Languages, languageOf(a)), rank(p.Languages, languageOf(b))
+	if la != lb {
+		return la < lb
+	}
+	ra, rb := p.groupRank(a.GetGroup()), p.groupRank(b.GetGroup())
+	if ra != rb {
+		return ra < rb
+	}
+	switch p.Prefer {
+	case PreferLatest:
+		return dateOf(a).After(dateOf(b))
+	default:
+		return pagesOf(a) > pagesOf(b)
+	}
+}
+
+// excluded reports whether any of the chapter groups is excluded.
+func (p SelectionPolicy) excluded(group string) bool {
+	for _, name := range groupNames(group) {
+		if containsFold(p.ExcludeGroups, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupRank returns the best position of the chapter groups in PreferGroups,
+// or len(PreferGroups) if none of them is listed.
+func (p SelectionPolicy) groupRank(group string) int {
+	names := groupNames(group)
+	for i, g := range p.PreferGroups {
+		if containsFold(names, g) {
+			return i
+		}
+	}
+	return len(p.PreferGroups)
+}
+
+// rank returns the position of s in list ignoring case, or len(list) if absent.
+func rank(list []string, s string) int {
+	for i, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return i
+		}
+	}
+	return len(list)
+}
+
+// groupNames splits a joint release ("Group A & Group B") into its group names.
+func groupNames(group string) []string {
+	return strings.Split(group, " & ")
+}
+
+// languageOf returns the language of the Filterable, if known.
+func languageOf(c Filterable) string {
+	if l, ok := c.(interface{ GetLanguage() string }); ok {
+		return l.GetLanguage()
+	}
+	return ""
+}
+
+// pagesOf returns the pages count of the Filterable, if known.
+func pagesOf(c Filterable) int64 {
+	if p, ok := c.(interface{ GetPagesCount() int64 }); ok {
+		return p.GetPagesCount()
+	}
+	return 0
+}
+
+// dateOf returns the upload date of the Filterable, if known.
+func dateOf(c Filterable) time.Time {
+	if d, ok := c.(interface{ GetDate() time.Time }); ok {
+		return d.GetDate()
+	}
+	return time.Time{}
+}
+
+// containsFold reports whether list contains s, ignoring case and surrounding spaces.
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(s)) {
+			return true
+		}
+	}
+	return false
+}
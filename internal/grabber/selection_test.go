@@ -0,0 +1,58 @@
+package grabber
+
+import (
+	"reflect"
+	"testing"
+)
+
+// titles returns the titles of the chapters.
+func titles(f Filterables) []string {
+	var t []string
+	for _, c := range f {
+		t = append(t, c.GetTitle())
+	}
+	return t
+}
+
+func TestSelectCandidates(t *testing.T) {
+	chapters := Filterables{
+		&Chapter{Title: "Oneshot", Unnumbered: true, Group: "A", PagesCount: 10},
+		&Chapter{Title: "0 by A", Number: 0, Group: "A", PagesCount: 10},
+		&Chapter{Title: "0 by B", Number: 0, Group: "B", PagesCount: 20},
+		&Chapter{Title: "1 by A", Number: 1, Group: "A", PagesCount: 10},
+		&Chapter{Title: "1 by B", Number: 1, Group: "B", PagesCount: 20},
+		&Chapter{Title: "Special", Unnumbered: true, Group: "B", PagesCount: 30},
+		&Chapter{Title: "2 by C", Number: 2, Group: "C", PagesCount: 5},
+	}
+
+	tests := []struct {
+		name   string
+		policy SelectionPolicy
+		want   []string
+	}{
+		{"most pages", SelectionPolicy{Prefer: PreferPages}, []string{"Oneshot", "0 by B", "1 by B", "Special", "2 by C"}},
+		{"preferred group", SelectionPolicy{PreferGroups: []string{"a"}}, []string{"Oneshot", "0 by A", "1 by A", "Special", "2 by C"}},
+		{"excluded group", SelectionPolicy{ExcludeGroups: []string{"B"}}, []string{"Oneshot", "0 by A", "1 by A", "2 by C"}},
+	}
+	for _, tt := range tests {
+		if got := titles(chapters.SelectCandidates(tt.policy)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: selected %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectCandidatesPerLanguage(t *testing.T) {
+	chapters := Filterables{
+		&Chapter{Title: "1 en", Number: 1, Language: "en"},
+		&Chapter{Title: "1 es", Number: 1, Language: "es"},
+		&Chapter{Title: "1 fr", Number: 1, Language: "fr"},
+	}
+	got := titles(chapters.SelectCandidates(SelectionPolicy{Languages: []string{"es", "en"}}))
+	if !reflect.DeepEqual(got, []string{"1 es"}) {
+		t.Errorf("selected %v, want the preferred language", got)
+	}
+	got = titles(chapters.SelectCandidates(SelectionPolicy{Languages: []string{"es", "en"}, PerLanguage: true}))
+	if len(got) != 3 {
+		t.Errorf("selected %v, want one chapter per language", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -14,6 +15,9 @@ type Grabber struct {
 	URL string
 	// Settings are the grabber settings
 	Settings *Settings
+	// Endpoints overrides the base URLs the sites request, keyed by their default value
+	// (i.e. "https://api.mangadex.org"); tests point them to a fixture server
+	Endpoints map[string]string
 }
 
 // Settings are grabber settings
@@ -22,24 +26,69 @@ type Settings struct {
 	Bundle bool
 	// MaxConcurrency determines max download concurrency
 	MaxConcurrency MaxConcurrency
-	// Language is the preferred language for downloading chapters
+	// Language is the preferred language for downloading chapters; it may be an ordered,
+	// comma-separated fallback chain (i.e. "es-la,es,en")
 	Language string
+	// MultiLanguage downloads each chapter once per language in Language instead of
+	// only in the most preferred one available
+	MultiLanguage bool
 	// FilenameTemplate is the template for the filename
 	FilenameTemplate string
+	// ChapterFolderTemplate is the template for the chapter folders inside bundles
+	ChapterFolderTemplate string
+	// PadWidth is the width chapter numbers are zero-padded to in templates
+	PadWidth int
 	// Range is the range to be downloaded (in string, i.e. "1-10,23,45-50")
 	Range string
 	// OutputDir is the output directory for the downloaded files
 	OutputDir string
 	// Archive format ("cbz", "zip", "raw")
 	Format string
+	// BundleBy determines how bundled chapters are grouped ("range" or "volume")
+	BundleBy string
+	// Sanitize is the filename sanitization profile ("posix", "windows" or "portable")
+	Sanitize string
+	// SkipDownloaded skips the chapters already recorded in the library
+	SkipDownloaded bool
+	// Selection decides which candidate is kept when several chapters share the same number
+	Selection SelectionPolicy
+	// Quality is the image quality on the sites serving several (QualityData or QualityDataSaver)
+	Quality string
+	// ContentRatings only lists the chapters of comics with these content ratings on the sites
+	// rating them, empty for the site default
+	ContentRatings []string
+	// ForceOrigin downloads the images from the site origin server instead of its mirrors
+	// (the MangaDex@Home servers)
+	ForceOrigin bool
+}
+
+// Languages returns the preferred languages in order of preference
+func (s *Settings) Languages() []string {
+	langs := []string{}
+	for _, l := range strings.Split(s.Language, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			langs = append(langs, l)
+		}
+	}
+	return langs
+}
+
+// SelectionPolicy returns the candidate selection policy including the language preferences
+func (s *Settings) SelectionPolicy() SelectionPolicy {
+	p := s.Selection
+	p.Languages = s.Languages()
+	p.PerLanguage = s.MultiLanguage
+	return p
 }
 
 // MaxConcurrency is the max concurrency for a site
 type MaxConcurrency struct {
 	// Chapters is the max concurrency for chapters
 	Chapters uint8
-	// Pages is the max concurrency for pages
+	// Pages is the max concurrency for pages, across every chapter
 	Pages uint8
+	// PerHost is the max concurrency for pages from the same host, 0 for Pages
+	PerHost uint8
 }
 
 // Site is the handler interface, base of all comic sites grabbers
@@ -64,6 +113,57 @@ type Site interface {
 	GetPreferredLanguage() string
 }
 
+// PageReport is the outcome of a page download.
+type PageReport struct {
+	// URL is the page URL
+	URL string
+	// Success tells whether the page was downloaded
+	Success bool
+	// Bytes is the size of the downloaded image
+	Bytes int
+	// Duration is the time the download took
+	Duration time.Duration
+	// Cached tells whether the image server had the image in cache
+	Cached bool
+}
+
+// PageReporter is a Site wanting the outcome of every page download, like MangaDex@Home
+// asks its clients to.
+type PageReporter interface {
+	// ReportPage reports the outcome of a page download
+	ReportPage(r PageReport)
+}
+
+// PageResolver is a Site whose page URLs expire, such as signed URLs with short-lived tokens.
+// Pages are resolved just before being downloaded rather than when the chapter is fetched,
+// and again when their download is refused with a 403, without fetching the chapter again.
+type PageResolver interface {
+	// ResolvePage returns the page of the chapter with a URL valid now. When stale is set
+	// the page URL was refused, so a new one is needed.
+	ResolvePage(chapter *Chapter, page Page, stale bool) (Page, error)
+}
+
+// Scraping steps, the keys of the Selectors of a site.
+const (
+	// StepTitle fetches the comic title
+	StepTitle = "title"
+	// StepChapters fetches the chapter list
+	StepChapters = "chapters"
+	// StepPages fetches the pages of a chapter
+	StepPages = "pages"
+)
+
+// Canaries are comic index URLs known to work for each site, used to detect site changes
+// breaking the grabbers.
+var Canaries = []string{
+	"https://asuracomic.net/series/player-who-returned-10000-years-later-44b620ed",
+	"https://cypheroscans.xyz/manga/magic-emperor/",
+	"https://inmanga.com/ver/manga/Kaiju-No-8/646317fc-f37c-4686-b568-df8efc60285d",
+	"https://mangadex.org/title/a1c7c817-4e59-43b7-9365-09675a149a6f/one-piece",
+	"https://mangamonk.com/infinite-mage",
+	"https://reaperscans.com/series/the-100th-regression-of-the-max-level-player",
+}
+
 // IdentifySite returns the site passing the Test() for the specified url
 func (g *Grabber) IdentifySite() (Site, []error) {
 	sites := []Site{
@@ -90,10 +190,23 @@ func (g *Grabber) IdentifySite() (Site, []error) {
 	return nil, errs
 }
 
+// endpoint returns the base URL to request in place of the given default one.
+func (g *Grabber) endpoint(base string) string {
+	if e, ok := g.Endpoints[base]; ok {
+		return strings.TrimSuffix(e, "/")
+	}
+	return base
+}
+
 func (g *Grabber) GetFormat() string {
 	return g.Settings.Format
 }
 
+// GetSettings returns the grabber settings
+func (g *Grabber) GetSettings() *Settings {
+	return g.Settings
+}
+
 // BaseUrl returns the base url of the site
 func (g Grabber) BaseUrl() string {
 	u, _ := url.Parse(g.URL)
@@ -125,6 +238,7 @@ func (g *Grabber) InitFlags(cmd *cobra.Command) {
 	g.SetMaxConcurrency(MaxConcurrency{
 		Chapters: maxUint8Flag(cmd.Flag("concurrency"), 5),
 		Pages:    maxUint8Flag(cmd.Flag("concurrency-pages"), 10),
+		PerHost:  maxUint8Flag(cmd.Flag("concurrency-host"), 10),
 	})
 	g.Settings.Language = cmd.Flag("language").Value.String()
 	g.Settings.FilenameTemplate = cmd.Flag("filename-template").Value.String()
@@ -137,8 +251,8 @@ func NewSite(url string, settings *Settings) (Site, []error) {
 	}
 
 	g := &Grabber{
-		url,
-		settings,
+		URL:      url,
+		Settings: settings,
 	}
 
 	return g.IdentifySite()
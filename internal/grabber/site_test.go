@@ -0,0 +1,46 @@
+package grabber
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewSite(t *testing.T) {
+	tests := []struct {
+		url  string
+		want Site
+	}{
+		{asuraURL, &AsuraScans{}},
+		{"https://cypheroscans.xyz/manga/magic-emperor/", &CypherScans{}},
+		{"https://inmanga.com/ver/manga/Kaiju-No-8/" + inmangaID, &Inmanga{}},
+		{"https://mangadex.org/title/" + mangadexID + "/one-piece", &Mangadex{}},
+		{mangamonkURL, &Mangamonk{}},
+		{"https://reaperscans.com/series/" + reaperscansSlug, &ReaperScans{}},
+	}
+	for _, tt := range tests {
+		site, errs := NewSite(tt.url, &Settings{})
+		if len(errs) > 0 {
+			t.Errorf("NewSite(%q) errors: %v", tt.url, errs)
+		}
+		if reflect.TypeOf(site) != reflect.TypeOf(tt.want) {
+			t.Errorf("NewSite(%q) = %T, want %T", tt.url, site, tt.want)
+		}
+	}
+
+	if site, _ := NewSite("https://example.com/comic", &Settings{}); site != nil {
+		t.Errorf("NewSite() of an unsupported site = %T, want nil", site)
+	}
+	if _, errs := NewSite("example.com/comic", &Settings{}); len(errs) == 0 {
+		t.Error("NewSite() of an invalid URL succeeded")
+	}
+}
+
+func TestGrabberEndpoint(t *testing.T) {
+	g := &Grabber{Endpoints: map[string]string{mangadexAPI: "http://127.0.0.1:8080/"}}
+	if got := g.endpoint(mangadexAPI); got != "http://127.0.0.1:8080" {
+		t.Errorf("endpoint(overridden) = %q", got)
+	}
+	if got := g.endpoint(inmangaURL); got != inmangaURL {
+		t.Errorf("endpoint(default) = %q", got)
+	}
+}
@@ -0,0 +1,297 @@
+// Package hooks runs commands, webhooks, notifications and media server scans when chapters
+// and series complete or fail to download.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+	"github.com/NorkzYT/comic-downloader/internal/mediaserver"
+)
+
+// Event types.
+const (
+	// EventChapter fires when a chapter (or bundle) file is written
+	EventChapter = "chapter"
+	// EventSeries fires when a download run completes
+	EventSeries = "series"
+	// EventFailure fires when a chapter or a download run fails
+	EventFailure = "failure"
+)
+
+// Timeout is the maximum time a hook may run.
+const Timeout = 60 * time.Second
+
+// Event describes what happened.
+type Event struct {
+	// Type is EventChapter, EventSeries or EventFailure
+	Type string `json:"type"`
+	// Series is the series title
+	Series string `json:"series"`
+	// URL is the series index URL
+	URL string `json:"url"`
+	// Chapter is the chapter title, empty for bundles and series events
+	Chapter string `json:"chapter,omitempty"`
+	// Number is the chapter number, empty for bundles and series events
+	Number string `json:"number,omitempty"`
+	// Path is the written file of chapter events
+	Path string `json:"path,omitempty"`
+	// Paths are every file written during the run, for series events
+	Paths []string `json:"paths,omitempty"`
+	// Failed is the number of chapters that failed, for series events
+	Failed int `json:"failed,omitempty"`
+	// Error is the error message of failure events
+	Error string `json:"error,omitempty"`
+}
+
+// Message returns a human readable summary of the event, used by notifications.
+func (e Event) Message() string {
+	switch e.Type {
+	case EventChapter:
+		if e.Chapter != "" {
+			return fmt.Sprintf("New chapter of %s: %s", e.Series, e.Chapter)
+		}
+		return fmt.Sprintf("New bundle of %s: %s", e.Series, e.Path)
+	case EventSeries:
+		msg := fmt.Sprintf("%s: %d file(s) downloaded", e.Series, len(e.Paths))
+		if e.Failed > 0 {
+			msg += fmt.Sprintf(", %d chapter(s) failed", e.Failed)
+		}
+		return msg
+	default:
+		if e.Chapter != "" {
+			return fmt.Sprintf("%s - %s failed: %s", e.Series, e.Chapter, e.Error)
+		}
+		return fmt.Sprintf("%s failed: %s", e.Series, e.Error)
+	}
+}
+
+// written returns a file written by the event, or "" if there is none.
+func (e Event) written() string {
+	if e.Path != "" {
+		return e.Path
+	}
+	if len(e.Paths) > 0 {
+		return e.Paths[0]
+	}
+	return ""
+}
+
+// env returns the environment variables passed to command hooks.
+func (e Event) env() []string {
+	return []string{
+		"COMIC_EVENT=" + e.Type,
+		"COMIC_SERIES=" + e.Series,
+		"COMIC_URL=" + e.URL,
+		"COMIC_CHAPTER=" + e.Chapter,
+		"COMIC_NUMBER=" + e.Number,
+		"COMIC_PATH=" + e.Path,
+		"COMIC_PATHS=" + strings.Join(e.Paths, "\n"),
+		"COMIC_FAILED=" + strconv.Itoa(e.Failed),
+		"COMIC_ERROR=" + e.Error,
+	}
+}
+
+// Hook is an action run on some events. Exactly one of its actions should be set.
+type Hook struct {
+	// On lists the events firing the hook; empty means every event, except for media server
+	// scans which default to the series event (one scan per run)
+	On []string `yaml:"on"`
+	// Command is a shell command, receiving the event as COMIC_* environment variables
+	Command string `yaml:"command"`
+	// Webhook is a URL the event is POSTed to as JSON
+	Webhook string `yaml:"webhook"`
+	// Discord is a Discord webhook URL
+	Discord string `yaml:"discord"`
+	// Ntfy is an ntfy topic URL (e.g. https://ntfy.sh/my-topic)
+	Ntfy string `yaml:"ntfy"`
+	// Apprise is an Apprise API notify URL (e.g. http://apprise:8000/notify/my-key)
+	Apprise string `yaml:"apprise"`
+	// Komga scans the Komga library the files were written to
+	Komga *mediaserver.Komga `yaml:"komga"`
+	// Kavita scans the Kavita library the files were written to
+	Kavita *mediaserver.Kavita `yaml:"kavita"`
+}
+
+// Validate checks the hook events and that exactly one action is set.
+func (h Hook) Validate() error {
+	for _, on := range h.On {
+		switch on {
+		case EventChapter, EventSeries, EventFailure:
+		default:
+			return fmt.Errorf("unknown hook event %q", on)
+		}
+	}
+	n := 0
+	for _, a := range []string{h.Command, h.Webhook, h.Discord, h.Ntfy, h.Apprise} {
+		if a != "" {
+			n++
+		}
+	}
+	if h.Komga != nil {
+		n++
+	}
+	if h.Kavita != nil {
+		n++
+	}
+	if n != 1 {
+		return errors.New("a hook needs exactly one of command, webhook, discord, ntfy, apprise, komga or kavita")
+	}
+	switch {
+	case h.Komga != nil:
+		return h.Komga.Validate()
+	case h.Kavita != nil:
+		return h.Kavita.Validate()
+	}
+	return nil
+}
+
+// fires reports whether the hook runs on the event type.
+func (h Hook) fires(typ string) bool {
+	if len(h.On) == 0 {
+		if h.Komga != nil || h.Kavita != nil {
+			return typ == EventSeries
+		}
+		return true
+	}
+	for _, on := range h.On {
+		if on == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// run runs the hook action for the event.
+func (h Hook) run(ctx context.Context, e Event) error {
+	switch {
+	case h.Command != "":
+		return runCommand(ctx, h.Command, e)
+	case h.Webhook != "":
+		return post(ctx, h.Webhook, "application/json", e, nil)
+	case h.Discord != "":
+		return post(ctx, h.Discord, "application/json", map[string]string{"content": e.Message()}, nil)
+	case h.Ntfy != "":
+		return post(ctx, h.Ntfy, "text/plain", e.Message(), map[string]string{"Title": "comic-downloader: " + e.Series})
+	case h.Apprise != "":
+		return post(ctx, h.Apprise, "application/json", map[string]string{"title": "comic-downloader: " + e.Series, "body": e.Message()}, nil)
+	case h.Komga != nil:
+		if p := e.written(); p != "" {
+			return h.Komga.Scan(ctx, p)
+		}
+	case h.Kavita != nil:
+		if p := e.written(); p != "" {
+			return h.Kavita.Scan(ctx, p)
+		}
+	}
+	return nil
+}
+
+// Runner fires hooks in the background. A nil Runner fires nothing.
+type Runner struct {
+	hooks []Hook
+	wg    sync.WaitGroup
+}
+
+// NewRunner returns a Runner for the given hooks, or nil if there are none.
+func NewRunner(hooks []Hook) (*Runner, error) {
+	for i, h := range hooks {
+		if err := h.Validate(); err != nil {
+			return nil, fmt.Errorf("hook %d: %w", i+1, err)
+		}
+	}
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+	return &Runner{hooks: hooks}, nil
+}
+
+// Fire runs the hooks matching the event in the background. Failures are logged.
+func (r *Runner) Fire(e Event) {
+	if r == nil {
+		return
+	}
+	for _, h := range r.hooks {
+		if !h.fires(e.Type) {
+			continue
+		}
+		r.wg.Add(1)
+		go func(h Hook) {
+			defer r.wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+			defer cancel()
+			if err := h.run(ctx, e); err != nil {
+				logger.Error("Runner.Fire: Error running %s hook: %v", e.Type, err)
+			}
+		}(h)
+	}
+}
+
+// Wait waits for the fired hooks to finish.
+func (r *Runner) Wait() {
+	if r == nil {
+		return
+	}
+	r.wg.Wait()
+}
+
+// runCommand runs a shell command with the event in its environment.
+func runCommand(ctx context.Context, command string, e Event) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), e.env()...)
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		logger.Info("runCommand: %s: %s", command, strings.TrimSpace(string(out)))
+	}
+	if err != nil {
+		return fmt.Errorf("command %q: %w", command, err)
+	}
+	return nil
+}
+
+// post sends body to url, as is for strings and as JSON otherwise.
+func post(ctx context.Context, url, contentType string, body interface{}, headers map[string]string) error {
+	var data []byte
+	if s, ok := body.(string); ok {
+		data = []byte(s)
+	} else {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s", url, res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/NorkzYT/comic-downloader/internal/mediaserver"
+)
+
+func TestHookFires(t *testing.T) {
+	tests := []struct {
+		name string
+		hook Hook
+		want map[string]bool
+	}{
+		{"every event", Hook{Command: "true"}, map[string]bool{EventChapter: true, EventSeries: true, EventFailure: true}},
+		{"listed events", Hook{On: []string{EventChapter}, Command: "true"}, map[string]bool{EventChapter: true}},
+		{"komga default", Hook{Komga: &mediaserver.Komga{}}, map[string]bool{EventSeries: true}},
+		{"kavita default", Hook{Kavita: &mediaserver.Kavita{}}, map[string]bool{EventSeries: true}},
+		{"komga per chapter", Hook{On: []string{EventChapter}, Komga: &mediaserver.Komga{}}, map[string]bool{EventChapter: true}},
+	}
+	for _, tt := range tests {
+		for _, typ := range []string{EventChapter, EventSeries, EventFailure} {
+			if got := tt.hook.fires(typ); got != tt.want[typ] {
+				t.Errorf("%s: fires(%s) = %v, want %v", tt.name, typ, got, tt.want[typ])
+			}
+		}
+	}
+}
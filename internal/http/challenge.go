@@ -0,0 +1,150 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+)
+
+// ErrChallenge is returned when a site answers with an anti-bot challenge page that could not be solved.
+var ErrChallenge = errors.New("blocked by an anti-bot challenge")
+
+// Clearance is what solving a challenge grants: the cookies of the site and the User-Agent
+// they are bound to.
+type Clearance struct {
+	// UserAgent is the User-Agent of the browser that solved the challenge
+	UserAgent string
+	// Cookies are the site cookies, such as cf_clearance
+	Cookies []*http.Cookie
+}
+
+// Solver solves the anti-bot challenge of the URL, typically in a browser.
+type Solver func(url string) (Clearance, error)
+
+// solver is the Solver used when a challenge is detected, nil to fail with ErrChallenge.
+var solver Solver
+
+// SetSolver sets the Solver used when a request gets an anti-bot challenge.
+func SetSolver(s Solver) {
+	solver = s
+}
+
+// hostClearance is the clearance of a host.
+type hostClearance struct {
+	// mu is held while solving the challenge, so that concurrent requests solve it once
+	mu sync.Mutex
+	// clearance is the last clearance obtained, used by every request to the host
+	clearance Clearance
+	// generation counts the attempts to solve the challenge
+	generation int
+	// err is the error of the last attempt, nil if it succeeded
+	err error
+	// failed is the time of the last failed attempt
+	failed time.Time
+}
+
+// solveRetryDelay is the time after a failed attempt during which requests fail with its error
+// instead of solving the challenge again.
+var solveRetryDelay = time.Minute
+
+var (
+	clearancesMu sync.Mutex
+	// clearances holds the clearances of the hosts, for the rest of the session
+	clearances = map[string]*hostClearance{}
+)
+
+// clearanceOf returns the clearance of the URL host.
+func clearanceOf(rawURL string) *hostClearance {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Host
+	}
+	clearancesMu.Lock()
+	defer clearancesMu.Unlock()
+	c, ok := clearances[host]
+	if !ok {
+		c = &hostClearance{}
+		clearances[host] = c
+	}
+	return c
+}
+
+// get returns the current clearance and its generation.
+func (c *hostClearance) get() (Clearance, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clearance, c.generation
+}
+
+// solve solves the challenge of the URL, unless another request attempted it since generation,
+// in which case the outcome of that attempt is returned. After a failed attempt, the challenge
+// is not solved again for solveRetryDelay, so that queued requests do not each open a browser.
+func (c *hostClearance) solve(rawURL string, generation int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return c.err
+	}
+	if c.err != nil && time.Since(c.failed) < solveRetryDelay {
+		return c.err
+	}
+	logger.Info("Solving the anti-bot challenge of %s in the browser", rawURL)
+	clearance, err := solver(rawURL)
+	c.generation++
+	if err != nil {
+		c.err, c.failed = err, time.Now()
+		return err
+	}
+	c.clearance, c.err = clearance, nil
+	return nil
+}
+
+// apply sets the clearance User-Agent and cookies on the request.
+func (c Clearance) apply(req *http.Request) {
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	for _, cookie := range c.Cookies {
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+}
+
+// challengeMarkers are snippets of the challenge pages.
+var challengeMarkers = []string{
+	"<title>Just a moment...</title>",
+	"/cdn-cgi/challenge-platform/",
+	"cf-browser-verification",
+	"cf_chl_opt",
+}
+
+// isChallenge reports whether the response is an anti-bot challenge page instead of the content.
+// It reads the start of the body, so the body is replaced by one replaying it.
+func isChallenge(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+	default:
+		return false
+	}
+	if resp.Header.Get("cf-mitigated") == "challenge" {
+		return true
+	}
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	page := string(head)
+	for _, m := range challengeMarkers {
+		if strings.Contains(page, m) {
+			return true
+		}
+	}
+	return false
+}
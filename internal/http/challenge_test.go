@@ -0,0 +1,162 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// newChallengeServer starts a server answering with a Cloudflare challenge page unless the
+// request has the cf_clearance cookie and the User-Agent it was issued for.
+func newChallengeServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("cf_clearance"); err == nil && c.Value == "cleared" && r.UserAgent() == "Mozilla/5.0 Chrome" {
+			_, _ = io.WriteString(w, "chapter")
+			return
+		}
+		w.Header().Set("Server", "cloudflare")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, "<!DOCTYPE html><html><head><title>Just a moment...</title></head></html>")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// useSolver sets the Solver for the duration of the test.
+func useSolver(t *testing.T, s Solver) {
+	prev := solver
+	SetSolver(s)
+	t.Cleanup(func() { SetSolver(prev) })
+}
+
+func TestRequestSolvesChallengeOnce(t *testing.T) {
+	srv := newChallengeServer(t)
+	var solved atomic.Int32
+	useSolver(t, func(url string) (Clearance, error) {
+		solved.Add(1)
+		return Clearance{UserAgent: "Mozilla/5.0 Chrome", Cookies: []*http.Cookie{{Name: "cf_clearance", Value: "cleared"}}}, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			text, err := GetText(RequestParams{URL: srv.URL + "/chapter/1"})
+			if err != nil || text != "chapter" {
+				t.Errorf("GetText() = %q, %v", text, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := solved.Load(); n != 1 {
+		t.Errorf("challenge solved %d times, want 1", n)
+	}
+
+	// The clearance is kept for the next requests.
+	if _, err := GetText(RequestParams{URL: srv.URL + "/chapter/2"}); err != nil || solved.Load() != 1 {
+		t.Errorf("GetText() = %v after %d solves, want the kept clearance", err, solved.Load())
+	}
+}
+
+func TestRequestChallengeUnsolved(t *testing.T) {
+	srv := newChallengeServer(t)
+	useSolver(t, nil)
+	if _, err := Get(RequestParams{URL: srv.URL}); !errors.Is(err, ErrChallenge) {
+		t.Errorf("Get() without a solver = %v, want ErrChallenge", err)
+	}
+
+	srv = newChallengeServer(t)
+	useSolver(t, func(url string) (Clearance, error) {
+		return Clearance{UserAgent: "Mozilla/5.0 Chrome", Cookies: []*http.Cookie{{Name: "cf_clearance", Value: "expired"}}}, nil
+	})
+	if _, err := Get(RequestParams{URL: srv.URL}); !errors.Is(err, ErrChallenge) {
+		t.Errorf("Get() with a rejected clearance = %v, want ErrChallenge", err)
+	}
+}
+
+func TestRequestChallengeFailsOnce(t *testing.T) {
+	srv := newChallengeServer(t)
+	var solved atomic.Int32
+	release := make(chan struct{})
+	useSolver(t, func(url string) (Clearance, error) {
+		solved.Add(1)
+		<-release
+		return Clearance{}, errors.New("no browser configured")
+	})
+
+	// Every request challenged at the same time gets the error of the single attempt.
+	hc := clearanceOf(srv.URL)
+	_, generation := hc.get()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := hc.solve(srv.URL, generation); err == nil || err.Error() != "no browser configured" {
+				t.Errorf("solve() = %v, want the solver error", err)
+			}
+		}()
+	}
+	for solved.Load() == 0 {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+	if n := solved.Load(); n != 1 {
+		t.Errorf("challenge solved %d times, want 1", n)
+	}
+
+	// Later requests fail without solving it again for a while.
+	if _, err := Get(RequestParams{URL: srv.URL}); !errors.Is(err, ErrChallenge) || solved.Load() != 1 {
+		t.Errorf("Get() = %v after %d solves, want ErrChallenge without a new attempt", err, solved.Load())
+	}
+	prev := solveRetryDelay
+	solveRetryDelay = 0
+	defer func() { solveRetryDelay = prev }()
+	if _, err := Get(RequestParams{URL: srv.URL}); !errors.Is(err, ErrChallenge) || solved.Load() != 2 {
+		t.Errorf("Get() = %v after %d solves, want a new attempt once the delay is over", err, solved.Load())
+	}
+}
+
+func TestIsChallenge(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header string
+		body   string
+		want   bool
+	}{
+		{"challenge page", http.StatusServiceUnavailable, "", `<script src="/cdn-cgi/challenge-platform/h/b/orchestrate/chl_page/v1"></script>`, true},
+		{"mitigated header", http.StatusForbidden, "challenge", "", true},
+		{"plain forbidden", http.StatusForbidden, "", "Forbidden", false},
+		{"not found", http.StatusNotFound, "challenge", "<title>Just a moment...</title>", false},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.header != "" {
+				w.Header().Set("cf-mitigated", tt.header)
+			}
+			w.WriteHeader(tt.status)
+			_, _ = io.WriteString(w, tt.body)
+		}))
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isChallenge(resp); got != tt.want {
+			t.Errorf("%s: isChallenge() = %v, want %v", tt.name, got, tt.want)
+		}
+		// The body is still readable in full.
+		if body, _ := io.ReadAll(resp.Body); tt.header == "" && string(body) != tt.body {
+			t.Errorf("%s: body = %q after isChallenge(), want %q", tt.name, body, tt.body)
+		}
+		resp.Body.Close()
+		srv.Close()
+	}
+}
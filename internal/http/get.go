@@ -2,12 +2,29 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"net/http"
 )
 
 // Get is a helper method for obtaining online files via GET call
 func Get(params Params) (body io.ReadCloser, err error) {
-	return request("GET", params)
+	resp, err := request(context.Background(), "GET", params)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// GetResponse is Get returning the whole response, for the callers needing its headers.
+// The caller must close the response body.
+func GetResponse(params Params) (*http.Response, error) {
+	return GetResponseContext(context.Background(), params)
+}
+
+// GetResponseContext is GetResponse canceled with ctx.
+func GetResponseContext(ctx context.Context, params Params) (*http.Response, error) {
+	return request(ctx, "GET", params)
 }
 
 // GetText is a helper method for obtaining online files as string via GET call
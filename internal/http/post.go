@@ -1,8 +1,27 @@
 package http
 
-import "io"
+import (
+	"context"
+	"io"
+)
+
+// JSONParams are request parameters with a JSON body.
+type JSONParams struct {
+	RequestParams
+	// Body is encoded as the JSON request body
+	Body interface{}
+}
 
 // Post sends a POST request to the given URL
 func Post(params Params) (body io.ReadCloser, err error) {
-	return request("POST", params)
+	resp, err := request(context.Background(), "POST", params)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// PostJSON sends a POST request with a JSON body to the given URL
+func PostJSON(params JSONParams) (body io.ReadCloser, err error) {
+	return Post(params)
 }
@@ -1,12 +1,33 @@
 package http
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
+// Config holds the options shared by every request.
+type Config struct {
+	// Timeout is the request timeout (0 means no timeout)
+	Timeout time.Duration
+	// UserAgent overrides the default Go User-Agent header when set
+	UserAgent string
+}
+
+// config is the current request configuration.
+var config Config
+
+// Configure sets the options used by every subsequent request.
+func Configure(c Config) {
+	config = c
+}
+
 // Params is an interface for request parameters.
 type Params interface {
 	GetURL() string
@@ -29,10 +50,81 @@ func (r RequestParams) GetReferer() string {
 	return r.Referer
 }
 
-// request sends a request to the given URL.
+// StatusError is returned when a site answers with another status code than 200 OK.
+type StatusError struct {
+	// Code is the response status code
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("received %d response code", e.Code)
+}
+
+// IsStatus reports whether err is a StatusError with the code.
+func IsStatus(err error, code int) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code == code
+}
+
+// request sends a request to the given URL. When the site answers with an anti-bot challenge,
+// it is solved once with the Solver and the request sent again with the clearance, which is
+// kept for the next requests to the site.
+func request(ctx context.Context, t string, params Params) (resp *http.Response, err error) {
+	hc := clearanceOf(params.GetURL())
+	clearance, generation := hc.get()
+	resp, err = do(ctx, t, params, clearance)
+	if err != nil {
+		return
+	}
+
+	if isChallenge(resp) {
+		resp.Body.Close()
+		if solver == nil {
+			return nil, fmt.Errorf("%w (received %d response code)", ErrChallenge, resp.StatusCode)
+		}
+		if err = hc.solve(params.GetURL(), generation); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrChallenge, err)
+		}
+		clearance, _ = hc.get()
+		if resp, err = do(ctx, t, params, clearance); err != nil {
+			return
+		}
+		if isChallenge(resp) {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w (received %d response code after solving it)", ErrChallenge, resp.StatusCode)
+		}
+	}
+
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, &StatusError{Code: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// apiTimeout is the timeout of Do when no request timeout is configured, so that a stalled
+// API call does not hang forever.
+const apiTimeout = 30 * time.Second
+
+// Do sends a request built by the caller, such as an authenticated API call, with the configured
+// timeout (apiTimeout if none) and User-Agent. Unlike Get and Post, certificates are verified
+// and anti-bot challenges are not solved.
+func Do(req *http.Request) (*http.Response, error) {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = apiTimeout
+	}
+	if config.UserAgent != "" {
+		req.Header.Set("User-Agent", config.UserAgent)
+	}
+	client := &http.Client{Timeout: timeout}
+	return client.Do(req)
+}
+
+// do sends a request with the clearance of the site.
 // Note: Certificate validation are disabled since users downloading comics usually
 // have the site open and can verify its trustworthiness manually.
-func request(t string, params Params) (body io.ReadCloser, err error) {
+func do(ctx context.Context, t string, params Params, clearance Clearance) (*http.Response, error) {
 	// Create an HTTP transport that disables compression and skips certificate validation.
 	tr := &http.Transport{
 		DisableCompression: true,
@@ -40,23 +132,29 @@ func request(t string, params Params) (body io.ReadCloser, err error) {
 			InsecureSkipVerify: true,
 		},
 	}
-	client := &http.Client{Transport: tr}
+	client := &http.Client{Transport: tr, Timeout: config.Timeout}
 
-	req, _ := http.NewRequest(t, params.GetURL(), nil)
-	if params.GetReferer() != "" {
-		req.Header.Add("Referer", params.GetReferer())
+	var body io.Reader
+	if p, ok := params.(JSONParams); ok {
+		data, err := json.Marshal(p.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
 	}
-
-	resp, err := client.Do(req)
+	req, err := http.NewRequestWithContext(ctx, t, params.GetURL(), body)
 	if err != nil {
-		return
+		return nil, err
 	}
-
-	if resp.StatusCode != 200 {
-		err = fmt.Errorf("received %d response code", resp.StatusCode)
-		return
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
-
-	body = resp.Body
-	return
+	if params.GetReferer() != "" {
+		req.Header.Add("Referer", params.GetReferer())
+	}
+	if config.UserAgent != "" {
+		req.Header.Set("User-Agent", config.UserAgent)
+	}
+	clearance.apply(req)
+	return client.Do(req)
 }
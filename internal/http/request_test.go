@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPostJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		if body["success"] != true || body["bytes"] != float64(42) {
+			t.Errorf("body = %v", body)
+		}
+	}))
+	defer srv.Close()
+
+	body, err := PostJSON(JSONParams{
+		RequestParams: RequestParams{URL: srv.URL},
+		Body:          map[string]interface{}{"success": true, "bytes": 42},
+	})
+	if err != nil {
+		t.Fatalf("PostJSON: %v", err)
+	}
+	body.Close()
+}
+
+func TestGetResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Cache", "HIT")
+		_, _ = io.WriteString(w, "image")
+	}))
+	defer srv.Close()
+
+	resp, err := GetResponse(RequestParams{URL: srv.URL + "/page.png"})
+	if err != nil {
+		t.Fatalf("GetResponse: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if string(data) != "image" || resp.Header.Get("X-Cache") != "HIT" {
+		t.Errorf("got %q with X-Cache %q", data, resp.Header.Get("X-Cache"))
+	}
+
+	if _, err := GetResponse(RequestParams{URL: srv.URL + "/missing"}); !IsStatus(err, http.StatusNotFound) {
+		t.Errorf("GetResponse(/missing) = %v, want a 404 StatusError", err)
+	}
+}
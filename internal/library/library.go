@@ -0,0 +1,364 @@
+// Package library keeps a catalog of every series and chapter downloaded, in a bbolt database.
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/grabber"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	seriesBucket   = []byte("series")
+	chaptersBucket = []byte("chapters")
+)
+
+// Series is a downloaded series.
+type Series struct {
+	// URL is the series index URL, used as its key
+	URL string `json:"url"`
+	// Site is the site host (e.g. "mangadex.org")
+	Site string `json:"site"`
+	// Title is the series title
+	Title string `json:"title"`
+	// Added is the first download time
+	Added time.Time `json:"added"`
+	// Updated is the last download time
+	Updated time.Time `json:"updated"`
+}
+
+// Chapter is a downloaded chapter.
+type Chapter struct {
+	// SeriesURL is the URL of the chapter series
+	SeriesURL string  `json:"series_url"`
+	Number    float64 `json:"number"`
+	Title     string  `json:"title"`
+	Volume    string  `json:"volume,omitempty"`
+	Language  string  `json:"language,omitempty"`
+	Group     string  `json:"group,omitempty"`
+	// Unnumbered tells the chapter has no number (oneshot, special...), Ref telling it apart
+	Unnumbered bool `json:"unnumbered,omitempty"`
+	// Ref identifies an unnumbered chapter among those of its series: its site ID, or its title
+	Ref string `json:"ref,omitempty"`
+	// Path is the file (or folder) the chapter was written to; bundled chapters share it
+	Path string `json:"path"`
+	// Pages is the number of pages downloaded
+	Pages int64 `json:"pages"`
+	// Checksum is the SHA-256 of the written file, empty for folders
+	Checksum string `json:"checksum,omitempty"`
+	// Downloaded is the download time
+	Downloaded time.Time `json:"downloaded"`
+}
+
+// key returns the database key of the chapter.
+func (c Chapter) key() []byte {
+	ref := ""
+	if c.Unnumbered {
+		ref = c.Ref
+	}
+	return chapterKey(c.SeriesURL, c.Number, c.Language, ref)
+}
+
+// ChapterOf returns the library chapter of a listed chapter, without its download details.
+func ChapterOf(c grabber.Filterable) Chapter {
+	ch := Chapter{
+		Number:     c.GetNumber(),
+		Title:      c.GetTitle(),
+		Volume:     c.GetVolume(),
+		Group:      c.GetGroup(),
+		Unnumbered: grabber.Unnumbered(c),
+	}
+	if l, ok := c.(interface{ GetLanguage() string }); ok {
+		ch.Language = l.GetLanguage()
+	}
+	if ch.Unnumbered {
+		ch.Ref = grabber.ChapterID(c)
+		if ch.Ref == "" {
+			ch.Ref = ch.Title
+		}
+	}
+	return ch
+}
+
+// chapterKey returns the database key of a chapter; keys of a series share its URL as prefix.
+// Unnumbered chapters all share the number 0, so ref tells them apart.
+func chapterKey(url string, number float64, language, ref string) []byte {
+	key := url + "\x00" + strconv.FormatFloat(number, 'f', -1, 64) + "\x00" + language
+	if ref != "" {
+		key += "\x00" + ref
+	}
+	return []byte(key)
+}
+
+// Library is a catalog of downloaded series and chapters.
+// The database is only opened for the duration of each operation, so several
+// processes (e.g. the server and the command line) can share it.
+type Library struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the default library path, next to the configuration file.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "comic-downloader", "library.db")
+}
+
+// New returns the library stored at path.
+func New(path string) *Library {
+	return &Library{path: path}
+}
+
+// Path returns the library database path.
+func (l *Library) Path() string {
+	return l.path
+}
+
+// open opens the database and runs fn in a transaction.
+func (l *Library) open(writable bool, fn func(tx *bolt.Tx) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !writable {
+		if _, err := os.Stat(l.path); os.IsNotExist(err) {
+			// Nothing downloaded yet: behave as an empty library without creating it.
+			return nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("error creating library directory: %w", err)
+	}
+	db, err := bolt.Open(l.path, 0644, &bolt.Options{Timeout: 10 * time.Second, ReadOnly: !writable})
+	if err != nil {
+		return fmt.Errorf("error opening library %s: %w", l.path, err)
+	}
+	defer db.Close()
+
+	if writable {
+		return db.Update(func(tx *bolt.Tx) error {
+			for _, b := range [][]byte{seriesBucket, chaptersBucket} {
+				if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+					return err
+				}
+			}
+			return fn(tx)
+		})
+	}
+	return db.View(fn)
+}
+
+// Record records chapters written to path, updating the series.
+// The path is made absolute and its checksum is computed when it is a file.
+func (l *Library) Record(series Series, path string, chapters []Chapter) error {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	sum, err := checksum(path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return l.open(true, func(tx *bolt.Tx) error {
+		sb := tx.Bucket(seriesBucket)
+		var cur Series
+		if data := sb.Get([]byte(series.URL)); data != nil {
+			if err := json.Unmarshal(data, &cur); err != nil {
+				return err
+			}
+			series.Added = cur.Added
+		} else {
+			series.Added = now
+		}
+		series.Updated = now
+		if err := put(sb, []byte(series.URL), series); err != nil {
+			return err
+		}
+
+		cb := tx.Bucket(chaptersBucket)
+		for _, c := range chapters {
+			c.SeriesURL, c.Path, c.Checksum, c.Downloaded = series.URL, path, sum, now
+			if err := put(cb, c.key(), c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Series returns every series sorted by title.
+func (l *Library) Series() ([]Series, error) {
+	series := []Series{}
+	err := l.open(false, func(tx *bolt.Tx) error {
+		b := tx.Bucket(seriesBucket)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var s Series
+			if err := json.Unmarshal(v, &s); err != nil {
+				return err
+			}
+			series = append(series, s)
+			return nil
+		})
+	})
+	sort.Slice(series, func(i, j int) bool {
+		return strings.ToLower(series[i].Title) < strings.ToLower(series[j].Title)
+	})
+	return series, err
+}
+
+// Chapters returns the chapters of the series with the given URL (every chapter when url is
+// empty), sorted by series and number.
+func (l *Library) Chapters(url string) ([]Chapter, error) {
+	chapters := []Chapter{}
+	err := l.open(false, func(tx *bolt.Tx) error {
+		b := tx.Bucket(chaptersBucket)
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		prefix := []byte{}
+		if url != "" {
+			prefix = []byte(url + "\x00")
+		}
+		for k, v := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, v = c.Next() {
+			var ch Chapter
+			if err := json.Unmarshal(v, &ch); err != nil {
+				return err
+			}
+			chapters = append(chapters, ch)
+		}
+		return nil
+	})
+	sort.SliceStable(chapters, func(i, j int) bool {
+		if chapters[i].SeriesURL != chapters[j].SeriesURL {
+			return chapters[i].SeriesURL < chapters[j].SeriesURL
+		}
+		return chapters[i].Number < chapters[j].Number
+	})
+	return chapters, err
+}
+
+// Search returns the chapters whose series or chapter title contains query, ignoring case.
+func (l *Library) Search(query string) ([]Chapter, error) {
+	series, err := l.Series()
+	if err != nil {
+		return nil, err
+	}
+	titles := map[string]string{}
+	for _, s := range series {
+		titles[s.URL] = s.Title
+	}
+	chapters, err := l.Chapters("")
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	found := []Chapter{}
+	for _, c := range chapters {
+		if strings.Contains(strings.ToLower(titles[c.SeriesURL]), query) || strings.Contains(strings.ToLower(c.Title), query) {
+			found = append(found, c)
+		}
+	}
+	return found, nil
+}
+
+// Delete removes chapters from the library, and the series left without chapters.
+func (l *Library) Delete(chapters []Chapter) error {
+	return l.open(true, func(tx *bolt.Tx) error {
+		cb := tx.Bucket(chaptersBucket)
+		urls := map[string]bool{}
+		for _, c := range chapters {
+			if err := cb.Delete(c.key()); err != nil {
+				return err
+			}
+			urls[c.SeriesURL] = true
+		}
+		for url := range urls {
+			if k, _ := cb.Cursor().Seek([]byte(url + "\x00")); k == nil || !strings.HasPrefix(string(k), url+"\x00") {
+				if err := tx.Bucket(seriesBucket).Delete([]byte(url)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Has reports whether the listed chapter of the series has been downloaded.
+func (l *Library) Has(url string, chapter grabber.Filterable) (bool, error) {
+	c := ChapterOf(chapter)
+	c.SeriesURL = url
+	found := false
+	err := l.open(false, func(tx *bolt.Tx) error {
+		if b := tx.Bucket(chaptersBucket); b != nil {
+			found = b.Get(c.key()) != nil
+		}
+		return nil
+	})
+	return found, err
+}
+
+// Filter drops the chapters of the series already in the library, so updates only
+// download new chapters.
+func (l *Library) Filter(url string, chapters grabber.Filterables) (grabber.Filterables, error) {
+	downloaded, err := l.Chapters(url)
+	if err != nil {
+		return chapters, err
+	}
+	have := map[string]bool{}
+	for _, c := range downloaded {
+		have[string(c.key())] = true
+	}
+	return chapters.Filter(func(c grabber.Filterable) bool {
+		ch := ChapterOf(c)
+		ch.SeriesURL = url
+		return !have[string(ch.key())]
+	}), nil
+}
+
+// put stores v as JSON.
+func put(b *bolt.Bucket, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+// checksum returns the SHA-256 of the file at path, or "" if path is a folder.
+func checksum(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
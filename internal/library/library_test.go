@@ -0,0 +1,200 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/NorkzYT/comic-downloader/internal/grabber"
+)
+
+const seriesURL = "https://mangadex.org/title/1"
+
+// newTestLibrary returns an empty library and a file to record chapters to.
+func newTestLibrary(t *testing.T) (*Library, string) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chapter.cbz")
+	if err := os.WriteFile(path, []byte("cbz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return New(filepath.Join(dir, "library.db")), path
+}
+
+// record records the listed chapters in the library.
+func record(t *testing.T, l *Library, path, title string, chapters ...grabber.Filterable) {
+	t.Helper()
+	entries := make([]Chapter, len(chapters))
+	for i, c := range chapters {
+		entries[i] = ChapterOf(c)
+	}
+	if err := l.Record(Series{URL: seriesURL, Title: title}, path, entries); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// chapterTitles returns the titles of the chapters.
+func chapterTitles(chapters []Chapter) []string {
+	titles := []string{}
+	for _, c := range chapters {
+		titles = append(titles, c.Title)
+	}
+	return titles
+}
+
+func TestRecord(t *testing.T) {
+	l, path := newTestLibrary(t)
+	record(t, l, path, "One Piece",
+		&grabber.Chapter{Number: 1, Title: "Romance Dawn", Language: "en"},
+		&grabber.Chapter{Number: 1, Title: "El amanecer", Language: "es"},
+		&grabber.Chapter{Number: 0, Title: "Prologue", Language: "en"},
+	)
+	// Recording a chapter again replaces it.
+	record(t, l, path, "One Piece", &grabber.Chapter{Number: 1, Title: "Romance Dawn (v2)", Language: "en"})
+
+	chapters, err := l.Chapters(seriesURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := chapterTitles(chapters), []string{"Prologue", "Romance Dawn (v2)", "El amanecer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("chapters = %v, want %v", got, want)
+	}
+	abs, _ := filepath.Abs(path)
+	if c := chapters[0]; c.Path != abs || c.Checksum == "" || c.Downloaded.IsZero() {
+		t.Errorf("chapter = %+v, want its path, checksum and download time", c)
+	}
+	series, err := l.Series()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || series[0].Title != "One Piece" || series[0].Added.After(series[0].Updated) {
+		t.Errorf("series = %+v", series)
+	}
+}
+
+func TestRecordUnnumbered(t *testing.T) {
+	l, path := newTestLibrary(t)
+	record(t, l, path, "One Piece",
+		&grabber.Chapter{Title: "Oneshot A", Unnumbered: true},
+		&grabber.Chapter{Title: "Special B", Unnumbered: true},
+		&grabber.MangadexChapter{Chapter: grabber.Chapter{Unnumbered: true}, Id: "c1"},
+		&grabber.MangadexChapter{Chapter: grabber.Chapter{Unnumbered: true}, Id: "c2"},
+	)
+	chapters, err := l.Chapters(seriesURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chapters) != 4 {
+		t.Errorf("recorded %d chapters, want every unnumbered chapter", len(chapters))
+	}
+	for _, c := range []grabber.Filterable{
+		&grabber.Chapter{Title: "Special B", Unnumbered: true},
+		&grabber.MangadexChapter{Chapter: grabber.Chapter{Unnumbered: true}, Id: "c2"},
+	} {
+		if ok, err := l.Has(seriesURL, c); err != nil || !ok {
+			t.Errorf("Has(%+v) = %v, %v, want true", c, ok, err)
+		}
+	}
+	if ok, _ := l.Has(seriesURL, &grabber.Chapter{Title: "Extra C", Unnumbered: true}); ok {
+		t.Error("Has(Extra C) = true, want false")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	l, path := newTestLibrary(t)
+	listed := grabber.Filterables{
+		&grabber.Chapter{Number: 1, Title: "1 en", Language: "en"},
+		&grabber.Chapter{Number: 1, Title: "1 es", Language: "es"},
+		&grabber.Chapter{Number: 2, Title: "2 en", Language: "en"},
+		&grabber.Chapter{Title: "Oneshot A", Unnumbered: true},
+		&grabber.Chapter{Title: "Extra C", Unnumbered: true},
+	}
+
+	// An empty library keeps every chapter, without creating the database.
+	got, err := l.Filter(seriesURL, listed)
+	if err != nil || len(got) != len(listed) {
+		t.Fatalf("Filter = %d chapters, %v, want all of them", len(got), err)
+	}
+	if _, err := os.Stat(l.Path()); !os.IsNotExist(err) {
+		t.Errorf("Filter created the library: %v", err)
+	}
+
+	record(t, l, path, "One Piece", listed[0], listed[3], &grabber.Chapter{Number: 2, Title: "2 by another group", Language: "en", Group: "B"})
+	got, err = l.Filter(seriesURL, listed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1 es", "Extra C"}
+	var titles []string
+	for _, c := range got {
+		titles = append(titles, c.GetTitle())
+	}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("Filter kept %v, want %v", titles, want)
+	}
+	if got, _ := l.Filter("https://mangadex.org/title/2", listed); len(got) != len(listed) {
+		t.Errorf("Filter of another series kept %d chapters, want all of them", len(got))
+	}
+}
+
+func TestDelete(t *testing.T) {
+	l, path := newTestLibrary(t)
+	record(t, l, path, "One Piece",
+		&grabber.Chapter{Number: 1, Title: "Romance Dawn"},
+		&grabber.Chapter{Title: "Oneshot A", Unnumbered: true},
+		&grabber.Chapter{Title: "Special B", Unnumbered: true},
+	)
+	chapters, err := l.Chapters(seriesURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Delete(chapters[:1]); err != nil {
+		t.Fatal(err)
+	}
+	left, err := l.Chapters(seriesURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := chapterTitles(left); len(got) != 2 {
+		t.Errorf("chapters left = %v, want the other 2", got)
+	}
+
+	// The series goes with its last chapter.
+	if err := l.Delete(left); err != nil {
+		t.Fatal(err)
+	}
+	series, err := l.Series()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 0 {
+		t.Errorf("series = %+v, want none", series)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	l, path := newTestLibrary(t)
+	record(t, l, path, "One Piece",
+		&grabber.Chapter{Number: 1, Title: "Romance Dawn"},
+		&grabber.Chapter{Number: 2, Title: "Straw Hat Luffy"},
+	)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"piece", []string{"Romance Dawn", "Straw Hat Luffy"}},
+		{"LUFFY", []string{"Straw Hat Luffy"}},
+		{"naruto", []string{}},
+	}
+	for _, tt := range tests {
+		found, err := l.Search(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := chapterTitles(found); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
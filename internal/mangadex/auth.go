@@ -0,0 +1,192 @@
+// Package mangadex is a client of the authenticated MangaDex API: login with a personal API
+// client, and the followed manga feed, reading statuses and read markers of the user.
+package mangadex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	ihttp "github.com/NorkzYT/comic-downloader/internal/http"
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+)
+
+const (
+	// API is the MangaDex API base URL.
+	API = "https://api.mangadex.org"
+	// TokenURL is the MangaDex OAuth token endpoint.
+	TokenURL = "https://auth.mangadex.org/realms/mangadex/protocol/openid-connect/token"
+)
+
+// ErrNotLoggedIn is returned when there are no credentials.
+var ErrNotLoggedIn = errors.New("not logged in to MangaDex: run comic-downloader mangadex login")
+
+// Credentials are the personal API client of a MangaDex user and the tokens it was granted.
+type Credentials struct {
+	// ClientID is the personal API client ID
+	ClientID string `json:"client_id"`
+	// ClientSecret is the personal API client secret
+	ClientSecret string `json:"client_secret"`
+	// Username is the MangaDex user name
+	Username string `json:"username"`
+	// AccessToken is the current access token
+	AccessToken string `json:"access_token"`
+	// RefreshToken is used to get a new access token once it expired
+	RefreshToken string `json:"refresh_token"`
+	// Expiry is the access token expiry time
+	Expiry time.Time `json:"expiry"`
+	// LastSync is the time of the last followed feed download
+	LastSync time.Time `json:"last_sync,omitempty"`
+}
+
+// DefaultPath returns the default credentials file path, next to the configuration file.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "comic-downloader", "mangadex.json")
+}
+
+// Client is an authenticated MangaDex API client. Its credentials are saved to its file
+// every time the tokens are refreshed.
+type Client struct {
+	// API is the API base URL
+	API string
+	// TokenURL is the OAuth token endpoint
+	TokenURL string
+
+	path  string
+	mu    sync.Mutex
+	creds Credentials
+}
+
+// Open returns a client with the credentials of the file at path.
+func Open(path string) (*Client, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotLoggedIn
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{API: API, TokenURL: TokenURL, path: path}
+	if err := json.Unmarshal(data, &c.creds); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", path, err)
+	}
+	if c.creds.RefreshToken == "" {
+		return nil, ErrNotLoggedIn
+	}
+	return c, nil
+}
+
+// Login logs in with the personal API client and the user password, and saves the
+// credentials to the file at path.
+func Login(ctx context.Context, path, tokenURL string, creds Credentials, password string) (*Client, error) {
+	if tokenURL == "" {
+		tokenURL = TokenURL
+	}
+	c := &Client{API: API, TokenURL: tokenURL, path: path, creds: creds}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.grant(ctx, url.Values{
+		"grant_type": {"password"},
+		"username":   {creds.Username},
+		"password":   {password},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Credentials returns the current credentials.
+func (c *Client) Credentials() Credentials {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.creds
+}
+
+// SetLastSync records the time of the last followed feed download.
+func (c *Client) SetLastSync(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.creds.LastSync = t
+	return c.save()
+}
+
+// token returns a valid access token, refreshing it when it expires in less than a minute.
+func (c *Client) token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.creds.AccessToken != "" && time.Until(c.creds.Expiry) > time.Minute {
+		return c.creds.AccessToken, nil
+	}
+	logger.Debug("mangadex.Client.token: Refreshing the access token")
+	err := c.grant(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {c.creds.RefreshToken},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error refreshing the MangaDex token (log in again): %w", err)
+	}
+	return c.creds.AccessToken, nil
+}
+
+// grant requests tokens to the token endpoint and saves them, with c.mu held.
+func (c *Client) grant(ctx context.Context, form url.Values) error {
+	form.Set("client_id", c.creds.ClientID)
+	form.Set("client_secret", c.creds.ClientSecret)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := ihttp.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s: %s %s", c.TokenURL, res.Status, strings.TrimSpace(string(msg)))
+	}
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tokens); err != nil {
+		return fmt.Errorf("%s: error decoding response: %w", c.TokenURL, err)
+	}
+	c.creds.AccessToken = tokens.AccessToken
+	if tokens.RefreshToken != "" {
+		c.creds.RefreshToken = tokens.RefreshToken
+	}
+	c.creds.Expiry = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+	return c.save()
+}
+
+// save writes the credentials to the file, readable by the user only, with c.mu held.
+func (c *Client) save() error {
+	if c.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c.creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0600)
+}
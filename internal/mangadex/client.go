@@ -0,0 +1,162 @@
+package mangadex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	ihttp "github.com/NorkzYT/comic-downloader/internal/http"
+)
+
+// feedLimit is the number of chapters requested per feed page, the API maximum.
+const feedLimit = 500
+
+// Reading statuses of the manga in the user library.
+const (
+	StatusReading    = "reading"
+	StatusOnHold     = "on_hold"
+	StatusPlanToRead = "plan_to_read"
+	StatusDropped    = "dropped"
+	StatusReReading  = "re_reading"
+	StatusCompleted  = "completed"
+)
+
+// FeedChapter is a chapter of the followed manga feed.
+type FeedChapter struct {
+	// ID is the chapter ID
+	ID string
+	// MangaID is the ID of the manga of the chapter
+	MangaID string
+	// Chapter is the chapter number, empty for oneshots
+	Chapter string
+	// Language is the translated language code
+	Language string
+	// PublishAt is the chapter publication time
+	PublishAt time.Time
+}
+
+// FeedOptions filters the followed manga feed.
+type FeedOptions struct {
+	// Since only returns the chapters published after it, when set
+	Since time.Time
+	// Languages only returns the chapters translated in these languages, when set
+	Languages []string
+	// ContentRatings only returns the chapters of manga with these content ratings, when set
+	ContentRatings []string
+}
+
+// FollowsFeed returns the chapters of the manga followed by the user, oldest first.
+func (c *Client) FollowsFeed(ctx context.Context, opts FeedOptions) ([]FeedChapter, error) {
+	var chapters []FeedChapter
+	for offset := 0; ; offset += feedLimit {
+		q := url.Values{
+			"limit":                {fmt.Sprint(feedLimit)},
+			"offset":               {fmt.Sprint(offset)},
+			"order[publishAt]":     {"asc"},
+			"includeFutureUpdates": {"0"},
+		}
+		if !opts.Since.IsZero() {
+			q.Set("publishAtSince", opts.Since.UTC().Format("2006-01-02T15:04:05"))
+		}
+		for _, l := range opts.Languages {
+			q.Add("translatedLanguage[]", l)
+		}
+		for _, r := range opts.ContentRatings {
+			q.Add("contentRating[]", r)
+		}
+		var page struct {
+			Data []struct {
+				ID         string `json:"id"`
+				Attributes struct {
+					Chapter            string    `json:"chapter"`
+					TranslatedLanguage string    `json:"translatedLanguage"`
+					PublishAt          time.Time `json:"publishAt"`
+				} `json:"attributes"`
+				Relationships []struct {
+					ID   string `json:"id"`
+					Type string `json:"type"`
+				} `json:"relationships"`
+			} `json:"data"`
+			Total int `json:"total"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/user/follows/manga/feed?"+q.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, d := range page.Data {
+			fc := FeedChapter{ID: d.ID, Chapter: d.Attributes.Chapter, Language: d.Attributes.TranslatedLanguage, PublishAt: d.Attributes.PublishAt}
+			for _, r := range d.Relationships {
+				if r.Type == "manga" {
+					fc.MangaID = r.ID
+				}
+			}
+			chapters = append(chapters, fc)
+		}
+		if len(page.Data) == 0 || offset+len(page.Data) >= page.Total {
+			return chapters, nil
+		}
+	}
+}
+
+// Statuses returns the reading status of every manga in the user library, by manga ID.
+func (c *Client) Statuses(ctx context.Context) (map[string]string, error) {
+	var res struct {
+		Statuses map[string]string `json:"statuses"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/manga/status", nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Statuses, nil
+}
+
+// MarkRead marks the chapters of the manga as read.
+func (c *Client) MarkRead(ctx context.Context, mangaID string, chapterIDs []string) error {
+	body := map[string][]string{"chapterIdsRead": chapterIDs, "chapterIdsUnread": {}}
+	return c.do(ctx, http.MethodPost, "/manga/"+mangaID+"/read", body, nil)
+}
+
+// do sends an authenticated request to the API, encoding body and decoding the response into out.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	token, err := c.token(ctx)
+	if err != nil {
+		return err
+	}
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	u := strings.TrimSuffix(c.API, "/") + path
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := ihttp.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s %s: %s %s", method, u, res.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return fmt.Errorf("%s %s: error decoding response: %w", method, u, err)
+		}
+	}
+	return nil
+}
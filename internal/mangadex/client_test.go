@@ -0,0 +1,228 @@
+package mangadex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer returns a token endpoint granting numbered access tokens, and the number of grants.
+func tokenServer(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	t.Helper()
+	var grants int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parsing form: %v", err)
+		}
+		if r.Form.Get("client_id") != "client" || r.Form.Get("client_secret") != "secret" {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+		switch r.Form.Get("grant_type") {
+		case "password":
+			if r.Form.Get("password") != "hunter2" {
+				http.Error(w, "invalid password", http.StatusUnauthorized)
+				return
+			}
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh" {
+				http.Error(w, "invalid refresh token", http.StatusBadRequest)
+				return
+			}
+		default:
+			t.Errorf("unexpected grant type %q", r.Form.Get("grant_type"))
+		}
+		n := atomic.AddInt32(&grants, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("access-%d", n),
+			"refresh_token": "refresh",
+			"expires_in":    expiresIn,
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &grants
+}
+
+var testCreds = Credentials{ClientID: "client", ClientSecret: "secret", Username: "me"}
+
+func TestLoginSavesCredentials(t *testing.T) {
+	srv, _ := tokenServer(t, 900)
+	path := filepath.Join(t.TempDir(), "sub", "mangadex.json")
+
+	if _, err := Login(context.Background(), path, srv.URL, testCreds, "wrong"); err == nil {
+		t.Fatal("expected an error with a wrong password")
+	}
+	if _, err := Login(context.Background(), path, srv.URL, testCreds, "hunter2"); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("credentials not saved: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("credentials file mode = %v, want 0600", perm)
+	}
+
+	c, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	creds := c.Credentials()
+	if creds.AccessToken != "access-1" || creds.RefreshToken != "refresh" || creds.Username != "me" {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+	if time.Until(creds.Expiry) < 10*time.Minute {
+		t.Errorf("expiry %v too early", creds.Expiry)
+	}
+}
+
+func TestOpenNotLoggedIn(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, ErrNotLoggedIn) {
+		t.Errorf("Open = %v, want ErrNotLoggedIn", err)
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	tokens, grants := tokenServer(t, 30)
+	var auth []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"statuses":{}}`))
+	}))
+	defer api.Close()
+
+	path := filepath.Join(t.TempDir(), "mangadex.json")
+	c, err := Login(context.Background(), path, tokens.URL, testCreds, "hunter2")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	c.API = api.URL
+	// The token expires in 30s, so each request refreshes it first.
+	for i := 0; i < 2; i++ {
+		if _, err := c.Statuses(context.Background()); err != nil {
+			t.Fatalf("Statuses: %v", err)
+		}
+	}
+	if *grants != 3 {
+		t.Errorf("got %d grants, want 3", *grants)
+	}
+	if len(auth) != 2 || auth[0] != "Bearer access-2" || auth[1] != "Bearer access-3" {
+		t.Errorf("unexpected Authorization headers %q", auth)
+	}
+	saved, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got := saved.Credentials().AccessToken; got != "access-3" {
+		t.Errorf("saved access token = %q, want access-3", got)
+	}
+}
+
+// newTestClient returns a client with a valid token, sending its requests to the handler.
+func newTestClient(t *testing.T, h http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	creds := testCreds
+	creds.AccessToken = "token"
+	creds.RefreshToken = "refresh"
+	creds.Expiry = time.Now().Add(time.Hour)
+	return &Client{API: srv.URL, TokenURL: srv.URL + "/token", creds: creds}
+}
+
+func TestFollowsFeed(t *testing.T) {
+	since := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	var offsets []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user/follows/manga/feed" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		q := r.URL.Query()
+		if q.Get("publishAtSince") != "2026-10-01T12:00:00" {
+			t.Errorf("publishAtSince = %q", q.Get("publishAtSince"))
+		}
+		if langs := q["translatedLanguage[]"]; len(langs) != 2 || langs[0] != "en" || langs[1] != "fr" {
+			t.Errorf("translatedLanguage[] = %q", langs)
+		}
+		if ratings := q["contentRating[]"]; len(ratings) != 1 || ratings[0] != "safe" {
+			t.Errorf("contentRating[] = %q", ratings)
+		}
+		offsets = append(offsets, q.Get("offset"))
+		// Two pages: the first is full, the second holds the last chapter.
+		var data []map[string]interface{}
+		n := feedLimit
+		if q.Get("offset") != "0" {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			data = append(data, map[string]interface{}{
+				"id": fmt.Sprintf("%s-%d", q.Get("offset"), i),
+				"attributes": map[string]interface{}{
+					"chapter":            fmt.Sprint(i + 1),
+					"translatedLanguage": "en",
+					"publishAt":          "2026-10-02T00:00:00+00:00",
+				},
+				"relationships": []map[string]string{
+					{"id": "group", "type": "scanlation_group"},
+					{"id": "manga-a", "type": "manga"},
+				},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data, "total": feedLimit + 1})
+	})
+
+	feed, err := c.FollowsFeed(context.Background(), FeedOptions{Since: since, Languages: []string{"en", "fr"}, ContentRatings: []string{"safe"}})
+	if err != nil {
+		t.Fatalf("FollowsFeed: %v", err)
+	}
+	if len(offsets) != 2 || offsets[1] != fmt.Sprint(feedLimit) {
+		t.Errorf("requested offsets %q", offsets)
+	}
+	if len(feed) != feedLimit+1 {
+		t.Fatalf("got %d chapters, want %d", len(feed), feedLimit+1)
+	}
+	last := feed[len(feed)-1]
+	if last.ID != fmt.Sprintf("%d-0", feedLimit) || last.MangaID != "manga-a" || last.Chapter != "1" || last.Language != "en" {
+		t.Errorf("unexpected chapter %+v", last)
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	var body map[string][]string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/manga/manga-a/read" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"result":"ok"}`))
+	})
+
+	if err := c.MarkRead(context.Background(), "manga-a", []string{"c1", "c2"}); err != nil {
+		t.Fatalf("MarkRead: %v", err)
+	}
+	if read := body["chapterIdsRead"]; len(read) != 2 || read[0] != "c1" || read[1] != "c2" {
+		t.Errorf("chapterIdsRead = %q", read)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"result":"error"}`, http.StatusForbidden)
+	})
+	if _, err := c.Statuses(context.Background()); err == nil {
+		t.Error("expected an error on a 403 response")
+	}
+}
@@ -0,0 +1,60 @@
+package mediaserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Kavita is a Kavita server client.
+type Kavita struct {
+	Server `yaml:",inline"`
+	// APIKey is the Kavita user API key (User Settings > 3rd Party Clients)
+	APIKey string `yaml:"api-key"`
+}
+
+// Validate checks the server URL and the API key are set.
+func (k *Kavita) Validate() error {
+	if k.URL == "" {
+		return errors.New("kavita: url is required")
+	}
+	if k.APIKey == "" {
+		return errors.New("kavita: api-key is required")
+	}
+	return nil
+}
+
+// Scan scans the library containing p, a file or folder written on this machine.
+func (k *Kavita) Scan(ctx context.Context, p string) error {
+	var auth struct {
+		Token string `json:"token"`
+	}
+	q := url.Values{"apiKey": {k.APIKey}, "pluginName": {"comic-downloader"}}
+	if err := do(ctx, http.MethodPost, k.endpoint("/api/Plugin/authenticate?"+q.Encode()), nil, nil, &auth); err != nil {
+		return err
+	}
+	headers := map[string]string{"Authorization": "Bearer " + auth.Token}
+
+	id := k.Library
+	if id == "" {
+		var libraries []struct {
+			ID      int      `json:"id"`
+			Folders []string `json:"folders"`
+		}
+		if err := do(ctx, http.MethodGet, k.endpoint("/api/Library/libraries"), headers, nil, &libraries); err != nil {
+			return err
+		}
+		ls := make([]library, len(libraries))
+		for i, l := range libraries {
+			ls[i] = library{id: strconv.Itoa(l.ID), folders: l.Folders}
+		}
+		var err error
+		if id, err = findLibrary(ls, k.ServerPath(p)); err != nil {
+			return err
+		}
+	}
+	q = url.Values{"libraryId": {id}}
+	return do(ctx, http.MethodPost, k.endpoint("/api/Library/scan?"+q.Encode()), headers, nil, nil)
+}
@@ -0,0 +1,63 @@
+package mediaserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKavitaScan(t *testing.T) {
+	var scanned []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/Plugin/authenticate", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("apiKey") != "key" || r.URL.Query().Get("pluginName") != "comic-downloader" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "jwt"})
+	})
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") != "Bearer jwt" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+	mux.HandleFunc("GET /api/Library/libraries", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"id": 1, "folders": []string{"/comics"}},
+				{"id": 2, "folders": []string{"/books", "/comics/manga"}},
+			})
+		}
+	})
+	mux.HandleFunc("POST /api/Library/scan", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			scanned = append(scanned, r.URL.Query().Get("libraryId"))
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	k := &Kavita{Server: Server{URL: srv.URL, PathMap: map[string]string{"/downloads": "/comics/manga"}}, APIKey: "key"}
+	if err := k.Scan(context.Background(), "/downloads/One Piece"); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	k.Library = "7"
+	if err := k.Scan(context.Background(), "/downloads/One Piece"); err != nil {
+		t.Fatalf("Scan of a set library: %v", err)
+	}
+	if len(scanned) != 2 || scanned[0] != "2" || scanned[1] != "7" {
+		t.Errorf("scanned libraries %v, want [2 7]", scanned)
+	}
+
+	k = &Kavita{Server: Server{URL: srv.URL}, APIKey: "wrong"}
+	if err := k.Scan(context.Background(), "/comics/1.cbz"); err == nil {
+		t.Error("Scan succeeded with a wrong API key")
+	}
+	if len(scanned) != 2 {
+		t.Errorf("scanned %v after a failed authentication", scanned)
+	}
+}
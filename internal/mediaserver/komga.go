@@ -0,0 +1,63 @@
+package mediaserver
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// Komga is a Komga server client.
+type Komga struct {
+	Server `yaml:",inline"`
+	// APIKey is a Komga API key, preferred over the username and password
+	APIKey string `yaml:"api-key"`
+	// Username is the Komga user email
+	Username string `yaml:"username"`
+	// Password is the Komga user password
+	Password string `yaml:"password"`
+}
+
+// Validate checks the server URL and the credentials are set.
+func (k *Komga) Validate() error {
+	if k.URL == "" {
+		return errors.New("komga: url is required")
+	}
+	if k.APIKey == "" && (k.Username == "" || k.Password == "") {
+		return errors.New("komga: api-key or username and password are required")
+	}
+	return nil
+}
+
+// Scan scans the library containing p, a file or folder written on this machine.
+func (k *Komga) Scan(ctx context.Context, p string) error {
+	id := k.Library
+	if id == "" {
+		var libraries []struct {
+			ID   string `json:"id"`
+			Root string `json:"root"`
+		}
+		if err := do(ctx, http.MethodGet, k.endpoint("/api/v1/libraries"), k.headers(), nil, &libraries); err != nil {
+			return err
+		}
+		ls := make([]library, len(libraries))
+		for i, l := range libraries {
+			ls[i] = library{id: l.ID, folders: []string{l.Root}}
+		}
+		var err error
+		if id, err = findLibrary(ls, k.ServerPath(p)); err != nil {
+			return err
+		}
+	}
+	return do(ctx, http.MethodPost, k.endpoint("/api/v1/libraries/"+url.PathEscape(id)+"/scan"), k.headers(), nil, nil)
+}
+
+// headers returns the authentication headers.
+func (k *Komga) headers() map[string]string {
+	if k.APIKey != "" {
+		return map[string]string{"X-API-Key": k.APIKey}
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte(k.Username + ":" + k.Password))
+	return map[string]string{"Authorization": "Basic " + auth}
+}
@@ -0,0 +1,58 @@
+package mediaserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newKomga returns a Komga stub with a library per root, recording the scanned library IDs.
+func newKomga(t *testing.T, roots map[string]string) (*httptest.Server, *[]string) {
+	var scanned []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/libraries", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "key" && r.Header.Get("Authorization") != "Basic dXNlckBleGFtcGxlLmNvbTpzZWNyZXQ=" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		libraries := []map[string]string{}
+		for id, root := range roots {
+			libraries = append(libraries, map[string]string{"id": id, "root": root})
+		}
+		_ = json.NewEncoder(w).Encode(libraries)
+	})
+	mux.HandleFunc("POST /api/v1/libraries/{id}/scan", func(w http.ResponseWriter, r *http.Request) {
+		scanned = append(scanned, r.PathValue("id"))
+		w.WriteHeader(http.StatusAccepted)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &scanned
+}
+
+func TestKomgaScan(t *testing.T) {
+	srv, scanned := newKomga(t, map[string]string{"comics": "/data", "manga": "/data/manga"})
+
+	k := &Komga{Server: Server{URL: srv.URL + "/", PathMap: map[string]string{"/downloads": "/data/manga"}}, APIKey: "key"}
+	if err := k.Scan(context.Background(), "/downloads/One Piece/1.cbz"); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	k = &Komga{Server: Server{URL: srv.URL}, Username: "user@example.com", Password: "secret"}
+	if err := k.Scan(context.Background(), "/data/Batman/1.cbz"); err != nil {
+		t.Fatalf("Scan with a password: %v", err)
+	}
+	k = &Komga{Server: Server{URL: srv.URL, Library: "fixed"}, APIKey: "key"}
+	if err := k.Scan(context.Background(), "/anywhere/1.cbz"); err != nil {
+		t.Fatalf("Scan of a set library: %v", err)
+	}
+	if want := []string{"manga", "comics", "fixed"}; len(*scanned) != 3 || (*scanned)[0] != want[0] || (*scanned)[1] != want[1] || (*scanned)[2] != want[2] {
+		t.Errorf("scanned %v, want %v", *scanned, want)
+	}
+
+	k = &Komga{Server: Server{URL: srv.URL}, APIKey: "wrong"}
+	if err := k.Scan(context.Background(), "/data/1.cbz"); err == nil {
+		t.Error("Scan succeeded with a wrong API key")
+	}
+}
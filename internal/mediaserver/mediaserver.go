@@ -0,0 +1,115 @@
+// Package mediaserver asks media servers (Komga, Kavita) to rescan the library new chapters
+// were written to, so they show up without waiting for a periodic scan.
+package mediaserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Server holds the settings shared by the media server clients.
+type Server struct {
+	// URL is the server base URL (e.g. http://komga:25600)
+	URL string `yaml:"url"`
+	// Library is the ID of the library to scan; empty finds the library containing the written files
+	Library string `yaml:"library"`
+	// PathMap maps local path prefixes to the server ones, when the server sees the output
+	// directory under another path (e.g. another Docker mount point)
+	PathMap map[string]string `yaml:"path-map"`
+}
+
+// ServerPath returns the path as seen by the server, mapped with the longest matching PathMap prefix.
+// Paths are made absolute and use forward slashes.
+func (s Server) ServerPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	p = filepath.ToSlash(p)
+	best, mapped := -1, p
+	for local, remote := range s.PathMap {
+		l := strings.TrimSuffix(filepath.ToSlash(local), "/")
+		if hasPathPrefix(p, l) && len(l) > best {
+			best, mapped = len(l), path.Join(remote, strings.TrimPrefix(p, l))
+		}
+	}
+	return mapped
+}
+
+// endpoint returns the URL of an API path.
+func (s Server) endpoint(p string) string {
+	return strings.TrimSuffix(s.URL, "/") + p
+}
+
+// library is a media server library and its root folders.
+type library struct {
+	id      string
+	folders []string
+}
+
+// findLibrary returns the ID of the library whose folder is the longest prefix of the server path.
+func findLibrary(libraries []library, serverPath string) (string, error) {
+	id, best := "", -1
+	for _, l := range libraries {
+		for _, f := range l.folders {
+			f = strings.TrimSuffix(filepath.ToSlash(f), "/")
+			if hasPathPrefix(serverPath, f) && len(f) > best {
+				id, best = l.id, len(f)
+			}
+		}
+	}
+	if id == "" {
+		return "", fmt.Errorf("no library contains %s; set the library ID or a path map", serverPath)
+	}
+	return id, nil
+}
+
+// hasPathPrefix reports whether p is prefix or a path under it; an empty prefix is the root.
+func hasPathPrefix(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/") || prefix == ""
+}
+
+// do sends a request with a JSON body (when body is not nil) and decodes the JSON response
+// into out (when out is not nil).
+func do(ctx context.Context, method, url string, headers map[string]string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s %s: %s %s", method, url, res.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return fmt.Errorf("%s %s: error decoding response: %w", method, url, err)
+		}
+	}
+	return nil
+}
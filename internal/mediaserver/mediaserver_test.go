@@ -0,0 +1,51 @@
+package mediaserver
+
+import (
+	"testing"
+)
+
+func TestServerPath(t *testing.T) {
+	s := Server{PathMap: map[string]string{
+		"/downloads":        "/data",
+		"/downloads/manga/": "/manga",
+	}}
+	tests := []struct {
+		path, want string
+	}{
+		{"/downloads/comics/a.cbz", "/data/comics/a.cbz"},
+		{"/downloads/manga/One Piece/1.cbz", "/manga/One Piece/1.cbz"},
+		{"/downloads", "/data"},
+		{"/downloads-old/a.cbz", "/downloads-old/a.cbz"},
+		{"/other/a.cbz", "/other/a.cbz"},
+	}
+	for _, tt := range tests {
+		if got := s.ServerPath(tt.path); got != tt.want {
+			t.Errorf("ServerPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFindLibrary(t *testing.T) {
+	libraries := []library{
+		{id: "root", folders: []string{"/data"}},
+		{id: "manga", folders: []string{"/books", "/data/manga/"}},
+		{id: "prefix", folders: []string{"/data/man"}},
+	}
+	tests := []struct {
+		path, want string
+	}{
+		{"/data/comics/a.cbz", "root"},
+		{"/data/manga/One Piece/1.cbz", "manga"},
+		{"/data/manga", "manga"},
+		{"/data/mangas/1.cbz", "root"},
+		{"/books/1.cbz", "manga"},
+	}
+	for _, tt := range tests {
+		if got, err := findLibrary(libraries, tt.path); err != nil || got != tt.want {
+			t.Errorf("findLibrary(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+	if _, err := findLibrary(libraries, "/elsewhere/1.cbz"); err == nil {
+		t.Error("findLibrary found a library for a path outside every library")
+	}
+}
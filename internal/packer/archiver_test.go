@@ -0,0 +1,153 @@
+package packer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/NorkzYT/comic-downloader/internal/downloader"
+)
+
+// testFiles returns n fake page files.
+func testFiles(n int) []*downloader.File {
+	files := make([]*downloader.File, n)
+	for i := range files {
+		files[i] = &downloader.File{Data: []byte(fmt.Sprintf("page %d", i+1)), Page: uint(i + 1)}
+	}
+	return files
+}
+
+// assertPagesWritten checks the entries are the files, in order, named 000.jpg, 001.jpg...
+func assertPagesWritten(t *testing.T, entries map[string][]byte, files []*downloader.File) {
+	t.Helper()
+	if len(entries) != len(files) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(files))
+	}
+	for i, f := range files {
+		name := fmt.Sprintf("%03d.jpg", i)
+		if !bytes.Equal(entries[name], f.Data) {
+			t.Errorf("%s = %q, want %q", name, entries[name], f.Data)
+		}
+	}
+}
+
+// readZip returns the entries of a zip archive.
+func readZip(t *testing.T, path string) map[string][]byte {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	entries := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[f.Name] = buf.Bytes()
+	}
+	return entries
+}
+
+func TestNewArchiver(t *testing.T) {
+	for format, want := range map[string]string{"cbz": "cbz", "zip": "zip", "raw": "raw"} {
+		a, err := NewArchiver(format)
+		if err != nil {
+			t.Fatalf("NewArchiver(%q): %v", format, err)
+		}
+		if a.Extension() != want {
+			t.Errorf("NewArchiver(%q).Extension() = %q, want %q", format, a.Extension(), want)
+		}
+	}
+	if _, err := NewArchiver("rar"); err == nil {
+		t.Error("NewArchiver(\"rar\") succeeded")
+	}
+}
+
+func TestZipArchivers(t *testing.T) {
+	for _, a := range []Archiver{&CBZArchiver{}, &ZIPArchiver{}} {
+		dir := t.TempDir()
+		files := testFiles(3)
+		pages := 0
+		path, err := a.Archive(dir, "Comic 001", files, func(page, _ int) { pages += page })
+		if err != nil {
+			t.Fatalf("%T.Archive: %v", a, err)
+		}
+		if want := filepath.Join(dir, "Comic 001."+a.Extension()); path != want {
+			t.Errorf("%T.Archive() = %q, want %q", a, path, want)
+		}
+		if pages != len(files) {
+			t.Errorf("%T reported %d pages, want %d", a, pages, len(files))
+		}
+		assertPagesWritten(t, readZip(t, path), files)
+
+		if _, err := a.Archive(dir, "empty", nil, func(int, int) {}); err == nil {
+			t.Errorf("%T.Archive() without files succeeded", a)
+		}
+	}
+}
+
+func TestRAWArchiver(t *testing.T) {
+	dir := t.TempDir()
+	files := testFiles(2)
+	pages := 0
+	path, err := (&RAWArchiver{}).Archive(dir, "Comic 001", files, func(page, _ int) { pages += page })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "Comic 001_raw"); path != want {
+		t.Errorf("Archive() = %q, want %q", path, want)
+	}
+	if pages != len(files) {
+		t.Errorf("reported %d pages, want %d", pages, len(files))
+	}
+	entries := map[string][]byte{}
+	dirEntries, err := os.ReadDir(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range dirEntries {
+		if entries[e.Name()], err = os.ReadFile(filepath.Join(path, e.Name())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertPagesWritten(t, entries, files)
+}
+
+func TestEPUBArchiver(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake go-comic-converter is a shell script")
+	}
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	dir := t.TempDir()
+	if _, err := (&EPUBArchiver{}).Archive(dir, "Comic 001", testFiles(1), func(int, int) {}); err == nil {
+		t.Fatal("Archive() without go-comic-converter succeeded")
+	}
+
+	// The fake converter copies its -input CBZ to its -output path.
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do case $1 in -input) in=$2;; -output) out=$2;; esac; shift; done\nexec /bin/cp \"$in\" \"$out\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "go-comic-converter"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := testFiles(2)
+	path, err := (&EPUBArchiver{}).Archive(dir, "Comic 001", files, func(int, int) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "Comic 001.epub"); path != want {
+		t.Errorf("Archive() = %q, want %q", path, want)
+	}
+	assertPagesWritten(t, readZip(t, path), files)
+}
@@ -3,8 +3,12 @@ package packer
 import (
 	"bytes"
 	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/NorkzYT/comic-downloader/internal/grabber"
 )
@@ -15,16 +19,57 @@ type FilenameTemplateParts struct {
 	Series string
 	// Number represents the chapter number (e.g. "1.0")
 	Number string
+	// PaddedNumber represents the chapter number zero-padded to the configured width (e.g. "001.5")
+	PaddedNumber string
 	// Title represents the chapter title (e.g. "The Beginning")
 	Title string
+	// Volume represents the chapter volume (e.g. "3"), empty if unknown
+	Volume string
+	// Language represents the chapter language (e.g. "es-la"), empty if unknown
+	Language string
+	// Group represents the scanlation group (e.g. "Group A"), empty if unknown
+	Group string
+	// Site represents the site host (e.g. "mangadex.org")
+	Site string
+	// Date represents the chapter upload date as YYYY-MM-DD, empty if unknown
+	Date string
+	// PageCount represents the number of pages
+	PageCount int64
 }
 
 // FilenameTemplateDefault is the default filename template
 const FilenameTemplateDefault = "{{.Series}} {{.Number}} - {{.Title}}"
 
-// NewFilenameFromTemplate returns a new filename from a series title, a chapter and a template
+// ChapterFolderTemplateDefault is the default template for chapter folders inside bundles
+const ChapterFolderTemplateDefault = "Chapter {{.PaddedNumber}}"
+
+// PadWidthDefault is the default width of PaddedNumber
+const PadWidthDefault = 3
+
+// templateFuncs are the functions available in filename templates.
+var templateFuncs = template.FuncMap{
+	// pad zero-pads the integer part of a number: {{pad 4 .Number}} -> "0012.5"
+	"pad": func(width int, number string) string {
+		return padNumber(number, width)
+	},
+	// lower lowercases a string: {{lower .Series}}
+	"lower": strings.ToLower,
+	// slug turns a string into a lowercase, dash-separated slug: {{slug .Title}}
+	"slug": slugify,
+	// truncate shortens a string to at most n characters: {{truncate 20 .Title}}
+	"truncate": func(n int, s string) string {
+		r := []rune(s)
+		if n < 0 || len(r) <= n {
+			return s
+		}
+		return strings.TrimSpace(string(r[:n]))
+	},
+}
+
+// NewFilenameFromTemplate returns a new filename from a series title, a chapter and a template.
+// The template may contain "/" to place the file in subdirectories.
 func NewFilenameFromTemplate(templ string, parts FilenameTemplateParts) (string, error) {
-	tmpl, err := template.New("filename").Parse(templ)
+	tmpl, err := template.New("filename").Funcs(templateFuncs).Parse(templ)
 	if err != nil {
 		return "", err
 	}
@@ -32,16 +77,75 @@ func NewFilenameFromTemplate(templ string, parts FilenameTemplateParts) (string,
 	buffer := new(bytes.Buffer)
 	err = tmpl.Execute(buffer, parts)
 
-	return buffer.String(), err
+	return filepath.FromSlash(buffer.String()), err
 }
 
-// NewChapterFileTemplateParts returns a new FilenameTemplateParts from a title and a chapter
-func NewChapterFileTemplateParts(title string, chapter *grabber.Chapter) FilenameTemplateParts {
+// NewChapterFileTemplateParts returns a new FilenameTemplateParts from a site, a title and a chapter
+func NewChapterFileTemplateParts(s grabber.Site, title string, chapter *grabber.Chapter) FilenameTemplateParts {
+	number := formatNumber(chapter.GetNumber())
+	date := ""
+	if !chapter.GetDate().IsZero() {
+		date = chapter.GetDate().Format("2006-01-02")
+	}
 	return FilenameTemplateParts{
-		Series: SanitizeFilename(title),
-		Number: strings.Replace(fmt.Sprintf("%.1f", chapter.GetNumber()), ".0", "", 1),
-		Title:  SanitizeFilename(chapter.GetTitle()),
+		Series:       SanitizeFilename(title),
+		Number:       number,
+		PaddedNumber: padNumber(number, siteSettings(s).PadWidth),
+		Title:        SanitizeFilename(chapter.GetTitle()),
+		Volume:       SanitizeFilename(chapter.GetVolume()),
+		Language:     SanitizeFilename(chapter.GetLanguage()),
+		Group:        SanitizeFilename(chapter.GetGroup()),
+		Site:         siteHost(s),
+		Date:         date,
+		PageCount:    chapter.PagesCount,
+	}
+}
+
+// formatNumber formats a chapter number without a trailing ".0" (e.g. 12 -> "12", 12.5 -> "12.5").
+func formatNumber(n float64) string {
+	return strings.Replace(fmt.Sprintf("%.1f", n), ".0", "", 1)
+}
+
+// padNumber zero-pads the integer part of a formatted number to width digits,
+// keeping its decimal part (e.g. "5.5" with width 3 -> "005.5").
+// Non-numeric values are returned unchanged.
+func padNumber(number string, width int) string {
+	intPart, decPart, hasDec := strings.Cut(number, ".")
+	if _, err := strconv.ParseUint(intPart, 10, 64); err != nil {
+		return number
+	}
+	if len(intPart) < width {
+		intPart = strings.Repeat("0", width-len(intPart)) + intPart
+	}
+	if hasDec {
+		return intPart + "." + decPart
+	}
+	return intPart
+}
+
+// slugify lowercases s and replaces every run of non-alphanumeric characters with a dash.
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// siteHost returns the host of the site base URL without the "www." prefix.
+func siteHost(s grabber.Site) string {
+	u, err := url.Parse(s.BaseUrl())
+	if err != nil {
+		return ""
 	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
 }
 
 // SanitizeFilename sanitizes a filename
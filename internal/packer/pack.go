@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/NorkzYT/comic-downloader/internal/downloader"
 	"github.com/NorkzYT/comic-downloader/internal/grabber"
@@ -17,6 +20,44 @@ type DownloadedChapter struct {
 	Files []*downloader.File
 }
 
+// Bundle grouping modes.
+const (
+	// BundleByRange creates a single bundle for the whole requested range.
+	BundleByRange = "range"
+	// BundleByVolume creates one bundle per volume.
+	BundleByVolume = "volume"
+)
+
+// siteSettings returns the site settings, or empty settings if the site does not expose them.
+// Unset template settings are filled with their defaults.
+func siteSettings(s grabber.Site) grabber.Settings {
+	type settingsGetter interface {
+		GetSettings() *grabber.Settings
+	}
+	settings := grabber.Settings{}
+	if sg, ok := s.(settingsGetter); ok && sg.GetSettings() != nil {
+		settings = *sg.GetSettings()
+	}
+	if settings.BundleBy == "" {
+		settings.BundleBy = BundleByRange
+	}
+	if settings.ChapterFolderTemplate == "" {
+		settings.ChapterFolderTemplate = ChapterFolderTemplateDefault
+	}
+	if settings.PadWidth <= 0 {
+		settings.PadWidth = PadWidthDefault
+	}
+	if settings.Sanitize == "" {
+		settings.Sanitize = SanitizeDefault
+	}
+	return settings
+}
+
+// siteSanitizer returns the Sanitizer for the site sanitization profile.
+func siteSanitizer(s grabber.Site) (*Sanitizer, error) {
+	return NewSanitizer(siteSettings(s).Sanitize)
+}
+
 // getSiteFormat extracts the archive format from the site's settings.
 // It expects the site to implement a GetFormat() string method.
 func getSiteFormat(s grabber.Site) (string, error) {
@@ -33,11 +74,14 @@ func getSiteFormat(s grabber.Site) (string, error) {
 // It uses the filename template from the Site settings.
 func PackSingle(outputDir string, s grabber.Site, chapter *DownloadedChapter, progress func(page, progress int)) (string, error) {
 	title, _ := s.FetchTitle()
-	parts := NewChapterFileTemplateParts(title, chapter.Chapter)
+	parts := NewChapterFileTemplateParts(s, title, chapter.Chapter)
 	filename, err := NewFilenameFromTemplate(s.GetFilenameTemplate(), parts)
 	if err != nil {
 		return "", fmt.Errorf("- error creating filename for chapter %s: %s", title, err.Error())
 	}
+	if siteSettings(s).MultiLanguage {
+		filename = tagLanguage(s.GetFilenameTemplate(), filename, parts.Language)
+	}
 	// Retrieve the desired format from settings.
 	format, err := getSiteFormat(s)
 	if err != nil {
@@ -47,12 +91,57 @@ func PackSingle(outputDir string, s grabber.Site, chapter *DownloadedChapter, pr
 	if err != nil {
 		return "", err
 	}
+	sanitizer, err := siteSanitizer(s)
+	if err != nil {
+		return "", err
+	}
+	filename = sanitizer.Path(filename, archiveSuffix(archiver))
 	return pack(outputDir, filename, chapter.Files, progress, archiver)
 }
 
-// PackBundle packages multiple downloaded chapters into a single archive (bundle)
-// with each chapter placed in its own folder inside the archive.
-func PackBundle(outputDir string, s grabber.Site, chapters []*DownloadedChapter, rng string, progress func(page, progress int)) (string, error) {
+// Bundle is a created bundle.
+type Bundle struct {
+	// Path is the bundle path
+	Path string
+	// Chapters are the chapters in the bundle
+	Chapters []*DownloadedChapter
+}
+
+// PackBundle packages multiple downloaded chapters into bundles with each chapter
+// placed in its own folder inside the archive.
+// By default a single bundle is created for the whole range; when the site is set
+// to bundle by volume, one bundle is created per volume instead. In multi-language
+// mode, bundles are additionally split by language.
+// It returns the created bundles.
+func PackBundle(outputDir string, s grabber.Site, chapters []*DownloadedChapter, rng string, progress func(page, progress int)) ([]Bundle, error) {
+	format, err := getSiteFormat(s)
+	if err != nil {
+		return nil, err
+	}
+	if !siteSettings(s).MultiLanguage {
+		return packBundleGroup(outputDir, s, chapters, rng, "", progress, format)
+	}
+
+	bundles := []Bundle{}
+	langs, byLang := groupChapters(chapters, func(c *DownloadedChapter) string {
+		return c.Language
+	})
+	for _, lang := range langs {
+		b, err := packBundleGroup(outputDir, s, byLang[lang], rng, lang, progress, format)
+		bundles = append(bundles, b...)
+		if err != nil {
+			return bundles, err
+		}
+	}
+	return bundles, nil
+}
+
+// packBundleGroup bundles chapters by range or by volume; lang, when set, tags the filenames.
+func packBundleGroup(outputDir string, s grabber.Site, chapters []*DownloadedChapter, rng, lang string, progress func(page, progress int), format string) ([]Bundle, error) {
+	if siteSettings(s).BundleBy == BundleByVolume {
+		return packVolumes(outputDir, s, chapters, lang, progress, format)
+	}
+
 	title, _ := s.FetchTitle()
 	// Determine appropriate prefix based on the range.
 	// For a single chapter, use "Chapter "; for multiple, use "Chapters ".
@@ -62,29 +151,179 @@ func PackBundle(outputDir string, s grabber.Site, chapters []*DownloadedChapter,
 	} else {
 		prefix = "Chapter "
 	}
-	parts := FilenameTemplateParts{
-		Series: title,
-		Number: prefix + rng,
-		Title:  "bundle",
-	}
+	parts := newBundleTemplateParts(s, title, prefix+rng, chapters)
 	filename, err := NewFilenameFromTemplate(s.GetFilenameTemplate(), parts)
 	if err != nil {
-		return "", fmt.Errorf("- error creating bundle filename for %s: %s", title, err.Error())
+		return nil, fmt.Errorf("- error creating bundle filename for %s: %s", title, err.Error())
 	}
-	format, err := getSiteFormat(s)
+	filename = tagLanguage(s.GetFilenameTemplate(), filename, lang)
+	path, err := packBundleChapters(outputDir, filename, s, chapters, progress, format)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return []Bundle{{Path: path, Chapters: chapters}}, nil
+}
+
+// packVolumes creates one bundle per volume, in volume order.
+// Chapters without a volume are bundled together under "No Volume".
+func packVolumes(outputDir string, s grabber.Site, chapters []*DownloadedChapter, lang string, progress func(page, progress int), format string) ([]Bundle, error) {
+	title, _ := s.FetchTitle()
+	volumes, byVolume := groupChapters(chapters, func(c *DownloadedChapter) string {
+		return c.GetVolume()
+	})
+	sort.SliceStable(volumes, func(i, j int) bool {
+		return volumeLess(volumes[i], volumes[j])
+	})
+
+	bundles := make([]Bundle, 0, len(volumes))
+	for _, vol := range volumes {
+		number := "Volume " + vol
+		if vol == "" {
+			number = "No Volume"
+		}
+		parts := newBundleTemplateParts(s, title, number, byVolume[vol])
+		filename, err := NewFilenameFromTemplate(s.GetFilenameTemplate(), parts)
+		if err != nil {
+			return bundles, fmt.Errorf("- error creating volume %s filename for %s: %s", vol, title, err.Error())
+		}
+		filename = tagLanguage(s.GetFilenameTemplate(), filename, lang)
+		path, err := packBundleChapters(outputDir, filename, s, byVolume[vol], progress, format)
+		if err != nil {
+			return bundles, err
+		}
+		bundles = append(bundles, Bundle{Path: path, Chapters: byVolume[vol]})
+	}
+	return bundles, nil
+}
+
+// newBundleTemplateParts returns the FilenameTemplateParts of a bundle. Title is always "bundle";
+// volume, language and group are only filled in when every chapter shares the same value.
+func newBundleTemplateParts(s grabber.Site, title, number string, chapters []*DownloadedChapter) FilenameTemplateParts {
+	parts := FilenameTemplateParts{
+		Series:       SanitizeFilename(title),
+		Number:       number,
+		PaddedNumber: number,
+		Title:        "bundle",
+		Site:         siteHost(s),
+	}
+	common := func(get func(*DownloadedChapter) string) string {
+		if len(chapters) == 0 {
+			return ""
+		}
+		v := get(chapters[0])
+		for _, c := range chapters[1:] {
+			if get(c) != v {
+				return ""
+			}
+		}
+		return SanitizeFilename(v)
+	}
+	parts.Volume = common(func(c *DownloadedChapter) string { return c.GetVolume() })
+	parts.Language = common(func(c *DownloadedChapter) string { return c.GetLanguage() })
+	parts.Group = common(func(c *DownloadedChapter) string { return c.GetGroup() })
+
+	var latest time.Time
+	for _, c := range chapters {
+		parts.PageCount += int64(len(c.Files))
+		if c.GetDate().After(latest) {
+			latest = c.GetDate()
+		}
+	}
+	if !latest.IsZero() {
+		parts.Date = latest.Format("2006-01-02")
+	}
+	return parts
+}
+
+// chapterFolders returns the folder name of each chapter inside a bundle, built from the
+// chapter folder template. Colliding names are disambiguated with a " (n)" suffix.
+func chapterFolders(s grabber.Site, chapters []*DownloadedChapter) ([]string, error) {
+	title, _ := s.FetchTitle()
+	templ := siteSettings(s).ChapterFolderTemplate
+	sanitizer, err := siteSanitizer(s)
+	if err != nil {
+		return nil, err
+	}
+	folders := make([]string, len(chapters))
+	seen := map[string]int{}
+	for i, chapter := range chapters {
+		raw, err := NewFilenameFromTemplate(templ, NewChapterFileTemplateParts(s, title, chapter.Chapter))
+		if err != nil {
+			return nil, fmt.Errorf("- error creating chapter folder name for %s: %s", chapter.GetTitle(), err.Error())
+		}
+		name := sanitizer.Path(raw, "")
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			suffix := fmt.Sprintf(" (%d)", n)
+			name = sanitizer.Path(raw, suffix) + suffix
+		}
+		folders[i] = name
+	}
+	return folders, nil
+}
+
+// groupChapters groups chapters by the given key, returning the keys in order of first appearance.
+func groupChapters(chapters []*DownloadedChapter, key func(*DownloadedChapter) string) ([]string, map[string][]*DownloadedChapter) {
+	keys := []string{}
+	groups := map[string][]*DownloadedChapter{}
+	for _, chapter := range chapters {
+		k := key(chapter)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], chapter)
+	}
+	return keys, groups
+}
+
+// tagLanguage appends a language tag (e.g. " [es-la]") to the filename, unless lang is empty
+// or the template already places the language itself.
+func tagLanguage(template, filename, lang string) string {
+	if lang == "" || strings.Contains(template, ".Language") {
+		return filename
+	}
+	return filename + " [" + SanitizeFilename(lang) + "]"
+}
+
+// volumeLess orders volumes numerically, placing unknown or non-numeric volumes last.
+func volumeLess(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return fa < fb
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
+	default:
+		return a < b
 	}
-	return packBundleChapters(outputDir, filename, chapters, progress, format)
 }
 
 // packBundleChapters selects the bundling method based on the archive format.
-func packBundleChapters(outputDir, filename string, chapters []*DownloadedChapter, progress func(page, progress int), format string) (string, error) {
+func packBundleChapters(outputDir, filename string, s grabber.Site, chapters []*DownloadedChapter, progress func(page, progress int), format string) (string, error) {
+	folders, err := chapterFolders(s, chapters)
+	if err != nil {
+		return "", err
+	}
+	sanitizer, err := siteSanitizer(s)
+	if err != nil {
+		return "", err
+	}
+	suffix := "." + format
+	if format == "raw" {
+		suffix = "_bundle"
+	}
+	filename = sanitizer.Path(filename, suffix)
+	if err := makeParentDirs(outputDir, filename); err != nil {
+		return "", err
+	}
 	switch format {
 	case "cbz", "zip":
-		return packBundleToZip(outputDir, filename, chapters, progress, format)
+		return packBundleToZip(outputDir, filename, chapters, folders, progress, format)
 	case "raw":
-		return packBundleToRaw(outputDir, filename, chapters, progress)
+		return packBundleToRaw(outputDir, filename, chapters, folders, progress)
 	default:
 		return "", fmt.Errorf("unsupported bundle format: %s", format)
 	}
@@ -93,15 +332,17 @@ func packBundleChapters(outputDir, filename string, chapters []*DownloadedChapte
 // packBundleToZip creates a CBZ or ZIP archive where each chapter is placed in its own folder.
 // For example, the archive structure will be:
 //
-//	Chapter 01/
+//	Chapter 001/
 //	    001.jpg
 //	    002.jpg
 //	    ...
-//	Chapter 02/
+//	Chapter 002/
 //	    001.jpg
 //	    002.jpg
 //	    ...
-func packBundleToZip(outputDir, filename string, chapters []*DownloadedChapter, progress func(page, progress int), format string) (string, error) {
+//
+// folders holds the folder name of each chapter.
+func packBundleToZip(outputDir, filename string, chapters []*DownloadedChapter, folders []string, progress func(page, progress int), format string) (string, error) {
 	ext := format // "cbz" or "zip"
 	fullPath := filepath.Join(outputDir, filename+"."+ext)
 	outFile, err := os.Create(fullPath)
@@ -111,12 +352,11 @@ func packBundleToZip(outputDir, filename string, chapters []*DownloadedChapter,
 	defer outFile.Close()
 
 	zipWriter := zip.NewWriter(outFile)
-	for _, chapter := range chapters {
-		chapNum := int(chapter.Number)
-		// Format chapter folder name (e.g., "Chapter 05")
-		folderName := fmt.Sprintf("Chapter %02d", chapNum)
+	for c, chapter := range chapters {
+		// Zip entries always use forward slashes.
+		folderName := filepath.ToSlash(folders[c])
 		for i, file := range chapter.Files {
-			// Create entry path inside the zip archive: e.g., "Chapter 05/001.jpg"
+			// Create entry path inside the zip archive: e.g., "Chapter 005/001.jpg"
 			entryName := fmt.Sprintf("%s/%03d.jpg", folderName, i)
 			writer, err := zipWriter.Create(entryName)
 			if err != nil {
@@ -138,20 +378,21 @@ func packBundleToZip(outputDir, filename string, chapters []*DownloadedChapter,
 // packBundleToRaw creates a directory structure for raw output where each chapter gets its own subfolder.
 // The resulting folder will contain subfolders like:
 //
-//	Chapter 01/
+//	Chapter 001/
 //	    001.jpg
 //	    002.jpg
-//	Chapter 02/
+//	Chapter 002/
 //	    001.jpg
 //	    002.jpg
-func packBundleToRaw(outputDir, filename string, chapters []*DownloadedChapter, progress func(page, progress int)) (string, error) {
+//
+// folders holds the folder name of each chapter.
+func packBundleToRaw(outputDir, filename string, chapters []*DownloadedChapter, folders []string, progress func(page, progress int)) (string, error) {
 	bundleFolder := filepath.Join(outputDir, filename+"_bundle")
 	if err := os.MkdirAll(bundleFolder, 0755); err != nil {
 		return "", err
 	}
-	for _, chapter := range chapters {
-		chapNum := int(chapter.Number)
-		chapFolder := filepath.Join(bundleFolder, fmt.Sprintf("Chapter %02d", chapNum))
+	for c, chapter := range chapters {
+		chapFolder := filepath.Join(bundleFolder, folders[c])
 		if err := os.MkdirAll(chapFolder, 0755); err != nil {
 			return "", err
 		}
@@ -168,5 +409,26 @@ func packBundleToRaw(outputDir, filename string, chapters []*DownloadedChapter,
 
 // pack is a helper that uses the given Archiver to package the files.
 func pack(outputDir, filename string, files []*downloader.File, progress func(page, progress int), archiver Archiver) (string, error) {
+	if err := makeParentDirs(outputDir, filename); err != nil {
+		return "", err
+	}
 	return archiver.Archive(outputDir, filename, files, progress)
 }
+
+// archiveSuffix returns what the archiver appends to the filename (e.g. ".cbz" or "_raw").
+func archiveSuffix(a Archiver) string {
+	if _, ok := a.(*RAWArchiver); ok {
+		return "_raw"
+	}
+	return "." + a.Extension()
+}
+
+// makeParentDirs creates the directories a templated filename places its file in
+// (e.g. "One Piece/Vol 1/One Piece 1").
+func makeParentDirs(outputDir, filename string) error {
+	dir := filepath.Dir(filepath.Join(outputDir, filename))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return nil
+}
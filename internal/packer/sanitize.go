@@ -0,0 +1,125 @@
+package packer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Sanitization profiles.
+const (
+	// SanitizePOSIX only removes what POSIX filesystems reject (plus the historical replacements)
+	SanitizePOSIX = "posix"
+	// SanitizeWindows also removes what Windows, NTFS and SMB shares reject
+	SanitizeWindows = "windows"
+	// SanitizePortable produces names that are safe everywhere, including as hidden or option-like names
+	SanitizePortable = "portable"
+)
+
+// SanitizeDefault is the default sanitization profile
+const SanitizeDefault = SanitizePortable
+
+// MaxNameBytes is the maximum length in bytes of a single path component
+const MaxNameBytes = 255
+
+// windowsReserved are the device names Windows refuses as file names, with or without extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// windowsReplacer replaces the characters Windows rejects that SanitizeFilename keeps.
+var windowsReplacer = strings.NewReplacer("*", "_", "<", "(", ">", ")", "|", "_")
+
+// Sanitizer makes paths safe for a target filesystem.
+type Sanitizer struct {
+	// Profile is the sanitization profile (SanitizePOSIX, SanitizeWindows or SanitizePortable)
+	Profile string
+	// MaxBytes is the maximum length in bytes of a path component, extension included
+	MaxBytes int
+}
+
+// NewSanitizer returns a Sanitizer for the given profile.
+func NewSanitizer(profile string) (*Sanitizer, error) {
+	switch profile {
+	case "":
+		profile = SanitizeDefault
+	case SanitizePOSIX, SanitizeWindows, SanitizePortable:
+	default:
+		return nil, fmt.Errorf("unsupported sanitization profile: %s", profile)
+	}
+	return &Sanitizer{Profile: profile, MaxBytes: MaxNameBytes}, nil
+}
+
+// Path sanitizes every component of a relative path built from a template.
+// suffix is the extension or suffix that will be appended to the last component
+// (e.g. ".cbz" or "_raw"); the last component is truncated so that it still fits with it.
+func (s *Sanitizer) Path(p, suffix string) string {
+	components := strings.FieldsFunc(filepath.ToSlash(p), func(r rune) bool { return r == '/' })
+	for i, c := range components {
+		if i == len(components)-1 {
+			components[i] = s.Name(c, suffix)
+		} else {
+			components[i] = s.Name(c, "")
+		}
+	}
+	if len(components) == 0 {
+		return s.Name("", suffix)
+	}
+	return filepath.Join(components...)
+}
+
+// Name sanitizes a single path component, truncating it so that name+suffix fits in MaxBytes.
+func (s *Sanitizer) Name(name, suffix string) string {
+	name = norm.NFC.String(SanitizeFilename(name))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+
+	if s.Profile != SanitizePOSIX {
+		name = windowsReplacer.Replace(name)
+	}
+	if s.Profile == SanitizePortable {
+		name = strings.TrimLeft(name, " .-")
+	}
+
+	name = truncateBytes(name, s.MaxBytes-len(suffix))
+
+	if s.Profile != SanitizePOSIX {
+		// Windows silently strips trailing dots and spaces, which breaks round trips.
+		name = strings.TrimRight(name, " .")
+		base, _, _ := strings.Cut(name, ".")
+		if windowsReserved[strings.ToUpper(strings.TrimSpace(base))] {
+			// The prefix counts towards the limit too.
+			name = "_" + strings.TrimRight(truncateBytes(name, s.MaxBytes-len(suffix)-1), " .")
+		}
+	}
+
+	if name == "" || name == "." || name == ".." {
+		name = "_"
+	}
+	return name
+}
+
+// truncateBytes shortens s to at most max bytes without splitting a UTF-8 sequence.
+func truncateBytes(s string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return strings.TrimSpace(s[:cut])
+}
@@ -0,0 +1,55 @@
+package packer
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizerName(t *testing.T) {
+	tests := []struct {
+		profile, name, suffix, want string
+	}{
+		{SanitizePOSIX, "CON", "", "CON"},
+		{SanitizeWindows, "CON", "", "_CON"},
+		{SanitizeWindows, "con.txt", "", "_con.txt"},
+		{SanitizeWindows, "Lpt1 ", ".cbz", "_Lpt1"},
+		{SanitizeWindows, "CONSOLE", "", "CONSOLE"},
+		{SanitizeWindows, "Chapter 1.", ".cbz", "Chapter 1"},
+		{SanitizePortable, ".hidden", "", "hidden"},
+		{SanitizePortable, "", ".cbz", "_"},
+	}
+	for _, tt := range tests {
+		s, err := NewSanitizer(tt.profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Name(tt.name, tt.suffix); got != tt.want {
+			t.Errorf("%s: Name(%q, %q) = %q, want %q", tt.profile, tt.name, tt.suffix, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizerNameLength(t *testing.T) {
+	tests := []struct {
+		profile, name, suffix, prefix string
+	}{
+		{SanitizePOSIX, strings.Repeat("a", 300), ".cbz", "aaa"},
+		{SanitizePortable, strings.Repeat("é", 200), "_raw", "éé"},
+		{SanitizeWindows, "CON." + strings.Repeat("a", 300), ".cbz", "_CON.a"},
+		{SanitizeWindows, "nul" + strings.Repeat(" ", 300), "", "_nul"},
+	}
+	for _, tt := range tests {
+		s, err := NewSanitizer(tt.profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := s.Name(tt.name, tt.suffix)
+		if len(got)+len(tt.suffix) > MaxNameBytes {
+			t.Errorf("%s: Name(%.10q…, %q) is %d bytes long with the suffix, want at most %d", tt.profile, tt.name, tt.suffix, len(got)+len(tt.suffix), MaxNameBytes)
+		}
+		if !utf8.ValidString(got) || !strings.HasPrefix(got, tt.prefix) {
+			t.Errorf("%s: Name(%.10q…, %q) = %q, want a valid name starting with %q", tt.profile, tt.name, tt.suffix, got, tt.prefix)
+		}
+	}
+}
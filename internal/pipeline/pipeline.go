@@ -0,0 +1,334 @@
+// Package pipeline downloads and packs the chapters of a comic, reporting their progress.
+// It is shared by the command line and the server mode.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/NorkzYT/comic-downloader/internal/downloader"
+	"github.com/NorkzYT/comic-downloader/internal/grabber"
+	"github.com/NorkzYT/comic-downloader/internal/hooks"
+	"github.com/NorkzYT/comic-downloader/internal/library"
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+	"github.com/NorkzYT/comic-downloader/internal/packer"
+	"github.com/NorkzYT/comic-downloader/internal/reporter"
+)
+
+// Pipeline downloads chapters from a site and packs them according to its settings.
+type Pipeline struct {
+	// Site is the site the chapters are downloaded from
+	Site grabber.Site
+	// Settings are the site settings
+	Settings *grabber.Settings
+	// Reporter receives the progress of every chapter
+	Reporter reporter.Reporter
+	// Label returns the progress label of a chapter; defaults to "<title> - <chapter title>"
+	Label func(title string, chapter grabber.Filterable) string
+	// URL is the comic index URL, recorded in the library
+	URL string
+	// Library, when set, records every packed chapter
+	Library *library.Library
+	// Hooks, when set, are fired when chapters and the run complete or fail
+	Hooks *hooks.Runner
+	// Scheduler downloads the pages; defaults to one with the page concurrency of the site,
+	// set it to share the workers with other pipelines
+	Scheduler *downloader.Scheduler
+}
+
+// Result is the outcome of a pipeline run.
+type Result struct {
+	// Paths are the written files, in the order they were written
+	Paths []string
+	// Packed are the chapters written to Paths, in the order they were written
+	Packed grabber.Filterables
+	// Failed holds the error of every chapter that could not be downloaded or packed
+	Failed []error
+	// Skipped holds the reason of every chapter left out without failing, such as the chapters
+	// hosted on external sites
+	Skipped []error
+}
+
+// Validate checks the settings values that flags can not check by themselves.
+// Bundling by volume enables bundling.
+func Validate(settings *grabber.Settings) error {
+	switch settings.Selection.Prefer {
+	case grabber.PreferPages, grabber.PreferLatest:
+	default:
+		return fmt.Errorf("--prefer: unsupported value %q", settings.Selection.Prefer)
+	}
+
+	switch settings.BundleBy {
+	case packer.BundleByRange:
+	case packer.BundleByVolume:
+		settings.Bundle = true
+	default:
+		return fmt.Errorf("--bundle-by: unsupported value %q", settings.BundleBy)
+	}
+
+	if _, err := packer.NewArchiver(settings.Format); err != nil {
+		return fmt.Errorf("--format: %w", err)
+	}
+
+	if _, err := packer.NewSanitizer(settings.Sanitize); err != nil {
+		return fmt.Errorf("--sanitize: %w", err)
+	}
+
+	switch settings.Quality {
+	case grabber.QualityData, grabber.QualityDataSaver:
+	default:
+		return fmt.Errorf("--quality: unsupported value %q", settings.Quality)
+	}
+
+	for _, r := range settings.ContentRatings {
+		if !slices.Contains(grabber.ContentRatings, r) {
+			return fmt.Errorf("--content-rating: unsupported value %q", r)
+		}
+	}
+	return nil
+}
+
+// Run downloads the chapters and packs them, one file per chapter or in bundles.
+// Chapters failing to download are reported in the Result and left out of bundles;
+// the returned error is only set when bundling fails or ctx is canceled.
+// Cancellation takes effect between chapters and between the download and packing steps.
+// Run returns once the fired hooks have finished.
+func (p *Pipeline) Run(ctx context.Context, title string, chapters grabber.Filterables) (Result, error) {
+	res, err := p.run(ctx, title, chapters)
+	if err != nil {
+		p.Hooks.Fire(hooks.Event{Type: hooks.EventFailure, Series: title, URL: p.URL, Error: err.Error()})
+	} else {
+		p.Hooks.Fire(hooks.Event{Type: hooks.EventSeries, Series: title, URL: p.URL, Paths: res.Paths, Failed: len(res.Failed)})
+	}
+	p.Hooks.Wait()
+	return res, err
+}
+
+// run downloads and packs the chapters.
+func (p *Pipeline) run(ctx context.Context, title string, chapters grabber.Filterables) (Result, error) {
+	label := p.Label
+	if label == nil {
+		label = func(title string, chapter grabber.Filterable) string {
+			return fmt.Sprintf("%s - %s", title, chapter.GetTitle())
+		}
+	}
+
+	trackers := make([]reporter.Tracker, len(chapters))
+	for i, chap := range chapters {
+		trackers[i] = p.Reporter.Track(label(title, chap))
+	}
+
+	var (
+		mu      sync.Mutex
+		res     Result
+		bundled []*packer.DownloadedChapter
+		// origin is the chapter each bundled chapter was fetched from
+		origin = map[*packer.DownloadedChapter]grabber.Filterable{}
+	)
+	fail := func(tracker reporter.Tracker, chap grabber.Filterable, err error) {
+		tracker.MarkAsErrored(err)
+		p.Hooks.Fire(hooks.Event{
+			Type:    hooks.EventFailure,
+			Series:  title,
+			URL:     p.URL,
+			Chapter: chap.GetTitle(),
+			Number:  strconv.FormatFloat(chap.GetNumber(), 'f', -1, 64),
+			Error:   err.Error(),
+		})
+		mu.Lock()
+		res.Failed = append(res.Failed, err)
+		mu.Unlock()
+	}
+
+	sched := p.Scheduler
+	if sched == nil {
+		mc := p.Site.GetMaxConcurrency()
+		sched = downloader.NewScheduler(int(mc.Pages), int(mc.PerHost))
+	}
+
+	wg := sync.WaitGroup{}
+	guard := make(chan struct{}, max(1, int(p.Site.GetMaxConcurrency().Chapters)))
+	for i, chap := range chapters {
+		select {
+		case guard <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if u := grabber.ExternalURL(chap); u != "" {
+			logger.Info("Pipeline.Run: Skipping chapter %s, hosted at %s", chap.GetTitle(), u)
+			trackers[i].SetStatus("Skipped: hosted at " + u)
+			trackers[i].MarkAsDone()
+			mu.Lock()
+			res.Skipped = append(res.Skipped, fmt.Errorf("chapter %s: %w: read it at %s", chap.GetTitle(), grabber.ErrExternalChapter, u))
+			mu.Unlock()
+			<-guard
+			continue
+		}
+		wg.Add(1)
+		go func(position int, chap grabber.Filterable, tracker reporter.Tracker) {
+			defer wg.Done()
+			defer func() { <-guard }()
+
+			chapter, err := p.fetchChapter(chap, tracker)
+			if err != nil {
+				logger.Error("Pipeline.Run: Error fetching chapter %s: %v", chap.GetTitle(), err)
+				fail(tracker, chap, fmt.Errorf("chapter %s: %w", chap.GetTitle(), err))
+				return
+			}
+			if ctx.Err() != nil {
+				fail(tracker, chap, ctx.Err())
+				return
+			}
+
+			total := int64(80) + chapter.PagesCount
+			if !p.Settings.Bundle {
+				total += chapter.PagesCount
+			}
+			tracker.SetTotal(total)
+
+			tracker.SetStatus("Downloading")
+			files, err := sched.FetchChapter(ctx, p.Site, chapter, position, func(page int, progressValue int, err error) {
+				if err != nil {
+					tracker.SetStatus("Downloading: Error " + err.Error())
+				} else {
+					tracker.Increment(1)
+				}
+			})
+			if ctx.Err() != nil {
+				fail(tracker, chap, ctx.Err())
+				return
+			}
+			if err != nil {
+				logger.Error("Pipeline.Run: Error downloading chapter %s: %v", chapter.GetTitle(), err)
+				fail(tracker, chap, fmt.Errorf("chapter %s: %w", chapter.GetTitle(), err))
+				return
+			}
+
+			d := &packer.DownloadedChapter{
+				Chapter: chapter,
+				Files:   files,
+			}
+			if p.Settings.Bundle {
+				mu.Lock()
+				bundled = append(bundled, d)
+				origin[d] = chap
+				mu.Unlock()
+				tracker.MarkAsDone()
+				return
+			}
+
+			tracker.SetStatus("Archiving")
+			filename, err := packer.PackSingle(p.Settings.OutputDir, p.Site, d, func(page, _ int) {
+				tracker.Increment(1)
+			})
+			if err != nil {
+				logger.Error("Pipeline.Run: Error archiving chapter: %v", err)
+				fail(tracker, chap, fmt.Errorf("chapter %s: %w", chapter.GetTitle(), err))
+				return
+			}
+			p.Reporter.Saved(filename)
+			p.record(title, filename, []*packer.DownloadedChapter{d}, grabber.Filterables{chap})
+			p.Hooks.Fire(hooks.Event{
+				Type:    hooks.EventChapter,
+				Series:  title,
+				URL:     p.URL,
+				Chapter: chapter.GetTitle(),
+				Number:  strconv.FormatFloat(chapter.GetNumber(), 'f', -1, 64),
+				Path:    filename,
+			})
+			mu.Lock()
+			res.Paths = append(res.Paths, filename)
+			res.Packed = append(res.Packed, chap)
+			mu.Unlock()
+			tracker.MarkAsDone()
+		}(i, chap, trackers[i])
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	if !p.Settings.Bundle || len(bundled) == 0 {
+		if p.Settings.Bundle {
+			return res, errors.New("no chapters to bundle")
+		}
+		return res, nil
+	}
+
+	sort.SliceStable(bundled, func(i, j int) bool {
+		return bundled[i].Chapter.Number < bundled[j].Chapter.Number
+	})
+	totalPages := int64(0)
+	for _, d := range bundled {
+		totalPages += d.Chapter.PagesCount
+	}
+	bundleTracker := p.Reporter.Track("Bundle")
+	bundleTracker.SetTotal(totalPages)
+	bundleTracker.SetStatus("Archiving All Chapters")
+
+	bundles, err := packer.PackBundle(p.Settings.OutputDir, p.Site, bundled, p.Settings.Range, func(page, _ int) {
+		bundleTracker.Increment(1)
+	})
+	for _, b := range bundles {
+		listed := make(grabber.Filterables, len(b.Chapters))
+		for i, c := range b.Chapters {
+			listed[i] = origin[c]
+		}
+		p.Reporter.Saved(b.Path)
+		p.record(title, b.Path, b.Chapters, listed)
+		p.Hooks.Fire(hooks.Event{Type: hooks.EventChapter, Series: title, URL: p.URL, Path: b.Path})
+		res.Paths = append(res.Paths, b.Path)
+		res.Packed = append(res.Packed, listed...)
+	}
+	if err != nil {
+		logger.Error("Pipeline.Run: Error bundling chapters: %v", err)
+		bundleTracker.MarkAsErrored(err)
+		return res, err
+	}
+	bundleTracker.MarkAsDone()
+	return res, nil
+}
+
+// record records the chapters written to path in the library, if any; listed are the
+// chapters they were fetched from, which identify them. Failures are logged: the files
+// are already written.
+func (p *Pipeline) record(title, path string, chapters []*packer.DownloadedChapter, listed grabber.Filterables) {
+	if p.Library == nil {
+		return
+	}
+	site := p.Site.BaseUrl()
+	if u, err := url.Parse(site); err == nil && u.Hostname() != "" {
+		site = strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	entries := make([]library.Chapter, len(chapters))
+	for i, c := range chapters {
+		entries[i] = library.ChapterOf(listed[i])
+		entries[i].Title = c.GetTitle()
+		entries[i].Pages = int64(len(c.Files))
+	}
+	series := library.Series{URL: p.URL, Site: site, Title: title}
+	if err := p.Library.Record(series, path, entries); err != nil {
+		logger.Error("Pipeline.record: Error recording %s in the library: %v", path, err)
+	}
+}
+
+// fetchChapter fetches the chapter details, reporting progress when the site supports it.
+func (p *Pipeline) fetchChapter(chap grabber.Filterable, tracker reporter.Tracker) (*grabber.Chapter, error) {
+	if fetcher, ok := p.Site.(interface {
+		FetchChapterWithProgress(grabber.Filterable, func()) (*grabber.Chapter, error)
+	}); ok {
+		return fetcher.FetchChapterWithProgress(chap, func() {
+			tracker.Increment(1)
+		})
+	}
+	return p.Site.FetchChapter(chap)
+}
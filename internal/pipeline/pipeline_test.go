@@ -0,0 +1,166 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NorkzYT/comic-downloader/internal/grabber"
+	"github.com/NorkzYT/comic-downloader/internal/packer"
+	"github.com/NorkzYT/comic-downloader/internal/reporter"
+)
+
+// testSite is a site whose chapters have pages already downloaded, failing the chapters of fail.
+type testSite struct {
+	*grabber.Grabber
+	fail map[float64]bool
+}
+
+func newTestSite(t *testing.T, fail ...float64) *testSite {
+	s := &testSite{
+		Grabber: &grabber.Grabber{URL: "https://comics.example/series", Settings: &grabber.Settings{
+			OutputDir:        t.TempDir(),
+			Format:           "zip",
+			FilenameTemplate: packer.FilenameTemplateDefault,
+			Sanitize:         packer.SanitizePortable,
+			MaxConcurrency:   grabber.MaxConcurrency{Chapters: 2, Pages: 4},
+		}},
+		fail: map[float64]bool{},
+	}
+	for _, n := range fail {
+		s.fail[n] = true
+	}
+	return s
+}
+
+func (s *testSite) Test() (bool, error) { return true, nil }
+
+func (s *testSite) FetchChapters() (grabber.Filterables, []error) { return nil, nil }
+
+func (s *testSite) FetchTitle() (string, error) { return "Comic", nil }
+
+func (s *testSite) FetchChapter(f grabber.Filterable) (*grabber.Chapter, error) {
+	if s.fail[f.GetNumber()] {
+		return nil, errors.New("chapter removed")
+	}
+	return &grabber.Chapter{
+		Title:      f.GetTitle(),
+		Number:     f.GetNumber(),
+		PagesCount: 2,
+		Pages: []grabber.Page{
+			{Number: 1, Data: []byte("page 1")},
+			{Number: 2, Data: []byte("page 2")},
+		},
+	}, nil
+}
+
+// chapters returns chapters with the given numbers.
+func chapters(numbers ...float64) grabber.Filterables {
+	var f grabber.Filterables
+	for _, n := range numbers {
+		f = append(f, &grabber.Chapter{Title: "Chapter", Number: n})
+	}
+	return f
+}
+
+func TestRunPacksChapters(t *testing.T) {
+	site := newTestSite(t, 2)
+	p := &Pipeline{Site: site, Settings: site.Settings, Reporter: reporter.NewFunc(func(reporter.Event) {})}
+
+	res, err := p.Run(context.Background(), "Comic", chapters(1, 2, 3))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Paths) != 2 || len(res.Failed) != 1 {
+		t.Fatalf("got paths %v and failures %v, want 2 paths and 1 failure", res.Paths, res.Failed)
+	}
+	if len(res.Packed) != 2 || res.Packed[0].GetNumber()+res.Packed[1].GetNumber() != 4 {
+		t.Errorf("packed %v, want chapters 1 and 3", res.Packed)
+	}
+	for _, path := range res.Paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("written file: %v", err)
+		}
+		if filepath.Dir(path) != site.Settings.OutputDir {
+			t.Errorf("%s written outside the output directory", path)
+		}
+	}
+}
+
+func TestRunBundles(t *testing.T) {
+	site := newTestSite(t)
+	site.Settings.Bundle = true
+	site.Settings.Range = "1-3"
+	p := &Pipeline{Site: site, Settings: site.Settings, Reporter: reporter.NewFunc(func(reporter.Event) {})}
+
+	res, err := p.Run(context.Background(), "Comic", chapters(1, 2, 3))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Paths) != 1 || len(res.Packed) != 3 {
+		t.Errorf("got paths %v with %d chapters, want a single bundle of 3", res.Paths, len(res.Packed))
+	}
+}
+
+func TestRunSkipsExternalChapters(t *testing.T) {
+	site := newTestSite(t)
+	p := &Pipeline{Site: site, Settings: site.Settings, Reporter: reporter.NewFunc(func(reporter.Event) {})}
+
+	external := &grabber.MangadexChapter{Chapter: grabber.Chapter{Title: "Chapter", Number: 2}, ExternalURL: "https://mangaplus.example/2"}
+	res, err := p.Run(context.Background(), "Comic", append(chapters(1), external))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Paths) != 1 || len(res.Skipped) != 1 || !errors.Is(res.Skipped[0], grabber.ErrExternalChapter) {
+		t.Errorf("got paths %v and skipped %v, want 1 of each", res.Paths, res.Skipped)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	site := newTestSite(t)
+	p := &Pipeline{Site: site, Settings: site.Settings, Reporter: reporter.NewFunc(func(reporter.Event) {})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Run(ctx, "Comic", chapters(1, 2)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v, want context.Canceled", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *grabber.Settings {
+		return &grabber.Settings{
+			Selection: grabber.SelectionPolicy{Prefer: grabber.PreferPages},
+			BundleBy:  packer.BundleByRange,
+			Format:    "cbz",
+			Sanitize:  packer.SanitizePortable,
+			Quality:   grabber.QualityData,
+		}
+	}
+	if err := Validate(valid()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	s := valid()
+	s.BundleBy = packer.BundleByVolume
+	if err := Validate(s); err != nil || !s.Bundle {
+		t.Errorf("Validate(--bundle-by volume) = %v, bundle %v; want bundling enabled", err, s.Bundle)
+	}
+
+	for name, change := range map[string]func(*grabber.Settings){
+		"prefer":         func(s *grabber.Settings) { s.Selection.Prefer = "size" },
+		"bundle-by":      func(s *grabber.Settings) { s.BundleBy = "year" },
+		"format":         func(s *grabber.Settings) { s.Format = "epub" },
+		"sanitize":       func(s *grabber.Settings) { s.Sanitize = "dos" },
+		"quality":        func(s *grabber.Settings) { s.Quality = "hd" },
+		"content-rating": func(s *grabber.Settings) { s.ContentRatings = []string{"adult"} },
+	} {
+		s := valid()
+		change(s)
+		if err := Validate(s); err == nil {
+			t.Errorf("Validate accepted an invalid --%s", name)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package ranges
 
 import (
+	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -9,60 +11,171 @@ import (
 type Range struct {
 	Begin float64
 	End   float64
+	// Exclude marks the range as an exclusion (e.g. "!12" or "^10-12")
+	Exclude bool
+	// Volume marks Begin and End as volume numbers instead of chapter numbers (e.g. "v3")
+	Volume bool
+	// Last, when greater than zero, selects the last Last chapters (e.g. "-5" or "latest")
+	// and Begin and End are ignored
+	Last int
+}
+
+// Contains reports whether n falls within the range bounds.
+// It does not take Exclude or Last into account.
+func (r Range) Contains(n float64) bool {
+	return n >= r.Begin && n <= r.End
+}
+
+// IsOpen reports whether the range has no upper bound (e.g. "10-").
+func (r Range) IsOpen() bool {
+	return math.IsInf(r.End, 1)
+}
+
+// ParseError is returned by Parse when a range expression is malformed.
+type ParseError struct {
+	// Input is the whole range expression
+	Input string
+	// Pos is the 1-based position of the offending character in Input
+	Pos int
+	// Msg describes the problem
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid range %q at position %d: %s", e.Input, e.Pos, e.Msg)
 }
 
 // Parse parses a string representation of chapter ranges and returns a slice of Range.
 // The expected formats are:
 //   - A single chapter: "107.5"
 //   - A range of chapters: "99-107.5"
-//   - Multiple ranges separated by commas, e.g.: "1-10, 12, 15.5-20"
+//   - An open-ended range, from a chapter to the last one: "10-"
+//   - The last chapter: "last" or "latest"
+//   - The last N chapters: "-5"
+//   - A volume or a range of volumes: "v3", "v1-2", "v4-"
+//   - An exclusion of any of the above: "!12" or "^12"
+//   - Multiple ranges separated by commas, e.g.: "1-10, 12, 15.5-20, !13"
 //
 // Supports decimal chapter numbers.
 func Parse(rnge string) (rngs []Range, err error) {
-	// Split the input by commas to allow multiple ranges or individual chapters.
-	parts := strings.Split(rnge, ",")
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			continue // Skip empty parts.
-		}
-		// If the part contains a dash, it denotes a range.
-		if strings.Contains(part, "-") {
-			// Split the part into beginning and ending chapter numbers.
-			bounds := strings.Split(part, "-")
-			if len(bounds) != 2 {
-				// If we don't get exactly two parts, skip this part.
-				continue
-			}
-			// Parse the beginning of the range.
-			begin, err := strconv.ParseFloat(strings.TrimSpace(bounds[0]), 64)
-			if err != nil {
-				return nil, err
-			}
-			// Parse the end of the range.
-			end, err := strconv.ParseFloat(strings.TrimSpace(bounds[1]), 64)
-			if err != nil {
-				return nil, err
-			}
-			// Ensure the range is valid (end should not be less than begin).
-			if end < begin {
-				end = begin
-			}
-			rngs = append(rngs, Range{
-				Begin: begin,
-				End:   end,
-			})
-		} else {
-			// Otherwise, treat the part as a single chapter.
-			val, err := strconv.ParseFloat(part, 64)
-			if err != nil {
-				return nil, err
-			}
-			rngs = append(rngs, Range{
-				Begin: val,
-				End:   val,
-			})
+	if strings.TrimSpace(rnge) == "" {
+		return nil, &ParseError{Input: rnge, Pos: 1, Msg: "empty range"}
+	}
+
+	offset := 0
+	for _, part := range strings.Split(rnge, ",") {
+		// pos is the 0-based position of the trimmed part within the whole input.
+		pos := offset + len(part) - len(strings.TrimLeft(part, " \t"))
+		offset += len(part) + 1
+
+		p := &parser{input: rnge, part: strings.TrimSpace(part), pos: pos}
+		r, err := p.parse()
+		if err != nil {
+			return nil, err
 		}
+		rngs = append(rngs, r)
 	}
 	return rngs, nil
 }
+
+// parser parses a single comma-separated part of a range expression.
+type parser struct {
+	input string
+	part  string
+	pos   int
+}
+
+// errorf returns a ParseError pointing at the given offset within the part.
+func (p *parser) errorf(at int, format string, args ...interface{}) error {
+	return &ParseError{Input: p.input, Pos: p.pos + at + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parse parses the part into a Range.
+func (p *parser) parse() (Range, error) {
+	s := p.part
+	at := 0
+	if s == "" {
+		return Range{}, p.errorf(0, "empty range")
+	}
+
+	var r Range
+	if s[0] == '!' || s[0] == '^' {
+		r.Exclude = true
+		s = s[1:]
+		at++
+		if s == "" {
+			return Range{}, p.errorf(at, "missing chapter after exclusion")
+		}
+	}
+
+	switch {
+	case strings.EqualFold(s, "last") || strings.EqualFold(s, "latest"):
+		r.Last = 1
+		return r, nil
+	case s[0] == '-':
+		n, err := strconv.Atoi(s[1:])
+		if err != nil || n <= 0 {
+			return Range{}, p.errorf(at+1, "expected a positive number of last chapters, got %q", s[1:])
+		}
+		r.Last = n
+		return r, nil
+	case s[0] == 'v' || s[0] == 'V':
+		r.Volume = true
+		s = s[1:]
+		at++
+		if s == "" {
+			return Range{}, p.errorf(at, "missing volume number")
+		}
+	}
+
+	begin, end, err := p.bounds(s, at)
+	if err != nil {
+		return Range{}, err
+	}
+	r.Begin, r.End = begin, end
+	return r, nil
+}
+
+// bounds parses "a", "a-b" or "a-" starting at the given offset within the part.
+func (p *parser) bounds(s string, at int) (float64, float64, error) {
+	idx := strings.Index(s, "-")
+	if idx < 0 {
+		n, err := p.number(s, at)
+		return n, n, err
+	}
+
+	begin, err := p.number(s[:idx], at)
+	if err != nil {
+		return 0, 0, err
+	}
+	rest := s[idx+1:]
+	if strings.TrimSpace(rest) == "" {
+		return begin, math.Inf(1), nil
+	}
+	if j := strings.Index(rest, "-"); j >= 0 {
+		return 0, 0, p.errorf(at+idx+1+j, "unexpected %q", "-")
+	}
+	end, err := p.number(rest, at+idx+1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < begin {
+		return 0, 0, p.errorf(at+idx+1, "range end %g is lower than its beginning %g", end, begin)
+	}
+	return begin, end, nil
+}
+
+// number parses a non-negative chapter or volume number starting at the given offset within the part.
+func (p *parser) number(s string, at int) (float64, error) {
+	trimmed := strings.TrimSpace(s)
+	at += len(s) - len(strings.TrimLeft(s, " \t"))
+	if trimmed == "" {
+		return 0, p.errorf(at, "missing number")
+	}
+	n, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, p.errorf(at, "invalid number %q", trimmed)
+	}
+	return n, nil
+}
@@ -0,0 +1,94 @@
+package ranges
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		in   string
+		want []Range
+	}{
+		{"5", []Range{{Begin: 5, End: 5}}},
+		{"107.5", []Range{{Begin: 107.5, End: 107.5}}},
+		{"99-107.5", []Range{{Begin: 99, End: 107.5}}},
+		{"10-", []Range{{Begin: 10, End: inf}}},
+		{"last", []Range{{Last: 1}}},
+		{"Latest", []Range{{Last: 1}}},
+		{"-5", []Range{{Last: 5}}},
+		{"v3", []Range{{Begin: 3, End: 3, Volume: true}}},
+		{"V1-2", []Range{{Begin: 1, End: 2, Volume: true}}},
+		{"v4-", []Range{{Begin: 4, End: inf, Volume: true}}},
+		{"!12", []Range{{Begin: 12, End: 12, Exclude: true}}},
+		{"^10-12", []Range{{Begin: 10, End: 12, Exclude: true}}},
+		{"!v2", []Range{{Begin: 2, End: 2, Volume: true, Exclude: true}}},
+		{"1-10, 12, 15.5-20, !13", []Range{
+			{Begin: 1, End: 10},
+			{Begin: 12, End: 12},
+			{Begin: 15.5, End: 20},
+			{Begin: 13, End: 13, Exclude: true},
+		}},
+		{" 1 - 2 ", []Range{{Begin: 1, End: 2}}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		pos int
+	}{
+		{"", 1},
+		{"   ", 1},
+		{"1,,2", 3},
+		{"abc", 1},
+		{"1-x", 3},
+		{"10-5", 4},
+		{"1-2-3", 4},
+		{"!", 2},
+		{"v", 2},
+		{"-0", 2},
+		{"-x", 2},
+		{"1, -1.5", 5},
+		{"-1e999", 2},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.in)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("Parse(%q) error = %v, want a *ParseError", tt.in, err)
+			continue
+		}
+		if pe.Pos != tt.pos || pe.Input != tt.in {
+			t.Errorf("Parse(%q) error at %d (%v), want position %d", tt.in, pe.Pos, pe, tt.pos)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	r := Range{Begin: 2, End: 4}
+	for n, want := range map[float64]bool{1.9: false, 2: true, 3.5: true, 4: true, 4.1: false} {
+		if got := r.Contains(n); got != want {
+			t.Errorf("Contains(%g) = %v, want %v", n, got, want)
+		}
+	}
+	if r.IsOpen() {
+		t.Error("IsOpen() of a closed range = true")
+	}
+	if !(Range{Begin: 2, End: math.Inf(1)}).IsOpen() {
+		t.Error("IsOpen() of an open range = false")
+	}
+}
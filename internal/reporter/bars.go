@@ -0,0 +1,89 @@
+package reporter
+
+import (
+	"io"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/progress"
+)
+
+// bars renders interactive progress bars using go-pretty.
+type bars struct {
+	pw progress.Writer
+}
+
+// newBars creates and starts a progress bars renderer.
+func newBars(w io.Writer) *bars {
+	pw := progress.NewWriter()
+	pw.SetOutputWriter(w)
+	pw.SetAutoStop(false)
+	pw.SetUpdateFrequency(100 * time.Millisecond)
+	pw.SetStyle(progress.StyleBlocks)
+	pw.Style().Colors = progress.StyleColorsExample
+	pw.Style().Visibility.ETA = true
+	pw.Style().Visibility.ETAOverall = true
+	pw.Style().Visibility.Percentage = true
+	pw.Style().Visibility.Speed = true
+	pw.Style().Visibility.SpeedOverall = true
+	pw.Style().Visibility.Time = true
+	pw.Style().Visibility.Tracker = true
+	pw.Style().Visibility.TrackerOverall = true
+	pw.Style().Visibility.Value = true
+
+	pw.SetSortBy(progress.SortByMessage)
+
+	go pw.Render()
+
+	return &bars{pw: pw}
+}
+
+// Track appends a new progress bar.
+func (b *bars) Track(title string) Tracker {
+	t := &barTracker{
+		title: title,
+		tracker: &progress.Tracker{
+			Message:            title + " [Fetching]",
+			Total:              80,
+			RemoveOnCompletion: false,
+		},
+	}
+	b.pw.AppendTracker(t.tracker)
+	return t
+}
+
+// Saved logs the saved file path above the progress bars.
+func (b *bars) Saved(path string) {
+	b.pw.Log("- %s %s", color.GreenString("saved file"), color.HiBlackString(path))
+}
+
+// Stop stops rendering the progress bars.
+func (b *bars) Stop() {
+	b.pw.Stop()
+}
+
+// barTracker wraps a go-pretty tracker.
+type barTracker struct {
+	title   string
+	tracker *progress.Tracker
+}
+
+func (t *barTracker) SetTotal(total int64) {
+	t.tracker.UpdateTotal(total)
+}
+
+func (t *barTracker) Increment(n int64) {
+	t.tracker.Increment(n)
+}
+
+func (t *barTracker) SetStatus(status string) {
+	t.tracker.UpdateMessage(t.title + " [" + status + "]")
+}
+
+func (t *barTracker) MarkAsDone() {
+	t.tracker.MarkAsDone()
+}
+
+func (t *barTracker) MarkAsErrored(err error) {
+	t.tracker.UpdateMessage(t.title + " [Download Failed]")
+}
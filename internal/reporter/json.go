@@ -0,0 +1,119 @@
+package reporter
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event is a single JSON progress event.
+type Event struct {
+	// Time is the moment the event was emitted
+	Time time.Time `json:"time"`
+	// Type is the event type: "status", "progress", "done", "error" or "saved"
+	Type string `json:"type"`
+	// Chapter is the chapter (or bundle) title, empty for "saved" events
+	Chapter string `json:"chapter,omitempty"`
+	// Status is the current status of the chapter
+	Status string `json:"status,omitempty"`
+	// Value is the number of progress units completed
+	Value int64 `json:"value"`
+	// Total is the total number of progress units
+	Total int64 `json:"total"`
+	// Error is the error message for "error" events
+	Error string `json:"error,omitempty"`
+	// Path is the written file path for "saved" events
+	Path string `json:"path,omitempty"`
+}
+
+// jsonReporter passes every event to a function; in ModeJSON it prints one JSON object per line.
+type jsonReporter struct {
+	mu   sync.Mutex
+	send func(Event)
+}
+
+// newJSON creates a JSON lines reporter.
+func newJSON(w io.Writer) *jsonReporter {
+	enc := json.NewEncoder(w)
+	return &jsonReporter{send: func(e Event) {
+		_ = enc.Encode(e)
+	}}
+}
+
+// NewFunc returns a Reporter calling fn with every event, as printed in ModeJSON.
+// Calls to fn are serialized.
+func NewFunc(fn func(Event)) Reporter {
+	return &jsonReporter{send: fn}
+}
+
+// emit sends an event.
+func (j *jsonReporter) emit(e Event) {
+	e.Time = time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.send(e)
+}
+
+// Track registers a chapter.
+func (j *jsonReporter) Track(title string) Tracker {
+	return &jsonTracker{j: j, title: title}
+}
+
+// Saved emits a "saved" event.
+func (j *jsonReporter) Saved(path string) {
+	j.emit(Event{Type: "saved", Path: path})
+}
+
+// Stop is a no-op for JSON output.
+func (j *jsonReporter) Stop() {}
+
+// jsonTracker keeps the counters of a chapter and emits its events.
+type jsonTracker struct {
+	j      *jsonReporter
+	title  string
+	mu     sync.Mutex
+	status string
+	value  int64
+	total  int64
+}
+
+// event builds an event with the current tracker state.
+func (t *jsonTracker) event(typ string) Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Event{Type: typ, Chapter: t.title, Status: t.status, Value: t.value, Total: t.total}
+}
+
+func (t *jsonTracker) SetTotal(total int64) {
+	t.mu.Lock()
+	t.total = total
+	t.mu.Unlock()
+}
+
+func (t *jsonTracker) Increment(n int64) {
+	t.mu.Lock()
+	t.value += n
+	t.mu.Unlock()
+	t.j.emit(t.event("progress"))
+}
+
+func (t *jsonTracker) SetStatus(status string) {
+	t.mu.Lock()
+	changed := t.status != status
+	t.status = status
+	t.mu.Unlock()
+	if changed {
+		t.j.emit(t.event("status"))
+	}
+}
+
+func (t *jsonTracker) MarkAsDone() {
+	t.j.emit(t.event("done"))
+}
+
+func (t *jsonTracker) MarkAsErrored(err error) {
+	e := t.event("error")
+	e.Error = err.Error()
+	t.j.emit(e)
+}
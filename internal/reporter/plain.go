@@ -0,0 +1,79 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// plain prints one line per status change, suitable for logs, cron and pipes.
+type plain struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// newPlain creates a plain line reporter.
+func newPlain(w io.Writer) *plain {
+	return &plain{out: w}
+}
+
+// printf writes a timestamped line.
+func (p *plain) printf(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.out, "%s %s\n", time.Now().Format("2006/01/02 15:04:05"), fmt.Sprintf(format, args...))
+}
+
+// Track registers a chapter and prints its initial status.
+func (p *plain) Track(title string) Tracker {
+	return &plainTracker{p: p, title: title}
+}
+
+// Saved prints the saved file path.
+func (p *plain) Saved(path string) {
+	p.printf("saved file %s", path)
+}
+
+// Stop is a no-op for plain output.
+func (p *plain) Stop() {}
+
+// plainTracker keeps the counters of a chapter and prints its status changes.
+type plainTracker struct {
+	p      *plain
+	title  string
+	mu     sync.Mutex
+	status string
+	value  int64
+	total  int64
+}
+
+func (t *plainTracker) SetTotal(total int64) {
+	t.mu.Lock()
+	t.total = total
+	t.mu.Unlock()
+}
+
+func (t *plainTracker) Increment(n int64) {
+	t.mu.Lock()
+	t.value += n
+	t.mu.Unlock()
+}
+
+func (t *plainTracker) SetStatus(status string) {
+	t.mu.Lock()
+	changed := t.status != status
+	t.status = status
+	t.mu.Unlock()
+	if changed {
+		t.p.printf("%s [%s]", t.title, status)
+	}
+}
+
+func (t *plainTracker) MarkAsDone() {
+	t.p.printf("%s [Done]", t.title)
+}
+
+func (t *plainTracker) MarkAsErrored(err error) {
+	t.p.printf("%s [Download Failed] %v", t.title, err)
+}
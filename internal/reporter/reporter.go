@@ -0,0 +1,81 @@
+// Package reporter renders download progress either as interactive progress bars,
+// as plain log lines or as JSON events, depending on where the output is going.
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// Supported reporter modes.
+const (
+	// ModeAuto selects ModeBars when stdout is a terminal and ModePlain otherwise.
+	ModeAuto = "auto"
+	// ModeBars renders interactive progress bars.
+	ModeBars = "bars"
+	// ModePlain prints one log line per status change.
+	ModePlain = "plain"
+	// ModeJSON prints one JSON object per event.
+	ModeJSON = "json"
+)
+
+// Reporter tracks the progress of a set of chapters.
+type Reporter interface {
+	// Track registers a new chapter with the given title and returns its tracker
+	Track(title string) Tracker
+	// Saved reports that a file has been written to the given path
+	Saved(path string)
+	// Stop flushes and stops the reporter
+	Stop()
+}
+
+// Tracker tracks the progress of a single chapter (or bundle).
+type Tracker interface {
+	// SetTotal sets the total number of progress units
+	SetTotal(total int64)
+	// Increment advances the progress by n units
+	Increment(n int64)
+	// SetStatus updates the current status (e.g. "Downloading")
+	SetStatus(status string)
+	// MarkAsDone marks the tracker as completed
+	MarkAsDone()
+	// MarkAsErrored marks the tracker as failed with the given error
+	MarkAsErrored(err error)
+}
+
+// New returns a Reporter for the given mode writing to w.
+func New(mode string, w io.Writer) (Reporter, error) {
+	switch mode {
+	case ModeAuto, "":
+		if IsTerminal(os.Stdout) {
+			return newBars(w), nil
+		}
+		return newPlain(w), nil
+	case ModeBars:
+		return newBars(w), nil
+	case ModePlain:
+		return newPlain(w), nil
+	case ModeJSON:
+		return newJSON(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported progress mode: %s", mode)
+	}
+}
+
+// IsTerminal reports whether f is attached to a terminal.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// TerminalWidth returns the width of the terminal attached to stdout, or 80 if
+// stdout is not a terminal.
+func TerminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return 80
+	}
+	return width
+}
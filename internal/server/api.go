@@ -0,0 +1,206 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+)
+
+// Handler returns the web UI and REST API handler:
+//
+//	GET    /                     serves the web UI
+//	GET    /api/chapters?url=    lists the chapters of a comic (optional "profile" and "language")
+//	POST   /api/jobs             queues a job (a Request)
+//	GET    /api/jobs             lists the jobs
+//	GET    /api/jobs/{id}        returns a job
+//	DELETE /api/jobs/{id}        cancels a queued or running job
+//	GET    /api/jobs/{id}/events streams the job progress as server-sent events
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(webFS()))
+	mux.HandleFunc("GET /api/chapters", s.handleChapters)
+	mux.HandleFunc("POST /api/jobs", s.handleSubmit)
+	mux.HandleFunc("GET /api/jobs", s.handleList)
+	mux.HandleFunc("GET /api/jobs/{id}", s.handleGet)
+	mux.HandleFunc("DELETE /api/jobs/{id}", s.handleCancel)
+	mux.HandleFunc("GET /api/jobs/{id}/events", s.handleEvents)
+	return s.authorize(mux)
+}
+
+// authorize rejects API requests without the server token, sent either as a bearer token
+// or, for EventSource clients which can not set headers, as the "token" query parameter.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	if s.opts.Token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleChapters(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Chapters == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("chapter listing unsupported"))
+		return
+	}
+	q := r.URL.Query()
+	req := Request{URL: q.Get("url"), Profile: q.Get("profile")}
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing url"))
+		return
+	}
+	if l := q.Get("language"); l != "" {
+		req.Options = map[string]interface{}{"language": l}
+	}
+	series, err := s.opts.Chapters(r.Context(), req)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, series)
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	// Browsers send cross-site text/plain POSTs without a CORS preflight: only accept
+	// JSON from the web UI or from clients that are not browsers.
+	if !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, errors.New("cross-origin requests are not allowed"))
+		return
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("job requests must be application/json"))
+		return
+	}
+	var req Request
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid job request: %w", err))
+		return
+	}
+	j, err := s.Submit(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, j)
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Jobs())
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.Job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, j)
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, errors.New("cross-origin requests are not allowed"))
+		return
+	}
+	j, err := s.Cancel(r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeJSON(w, http.StatusAccepted, j)
+	}
+}
+
+// handleEvents streams "job" events with the job state and "progress" events with the
+// chapter progress (see reporter.Event) until the job finishes or the client disconnects.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	msgs, unsubscribe, err := s.Subscribe(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case m, ok := <-msgs:
+			if !ok {
+				return
+			}
+			var data interface{} = m.Event
+			if m.Type == "job" {
+				data = m.Job
+			}
+			b, err := json.Marshal(data)
+			if err != nil {
+				logger.Error("Server.handleEvents: Error encoding event: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", m.Type, b); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// sameOrigin reports whether the request has no Origin, as sent by clients other than
+// browsers, or comes from a page served by this server.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && strings.EqualFold(u.Host, r.Host)
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("Server.writeJSON: Error encoding response: %v", err)
+	}
+}
+
+// writeError writes an error as a JSON response.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NorkzYT/comic-downloader/internal/reporter"
+)
+
+// newTestServer returns an API test server for a server whose jobs do nothing.
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := New(Options{Run: func(context.Context, Request, reporter.Reporter) error { return nil }})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func TestSubmitRejectsCrossSiteRequests(t *testing.T) {
+	s, srv := newTestServer(t)
+	body := `{"url":"https://example.com/comic"}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		origin      string
+		want        int
+	}{
+		{"text/plain", "text/plain", "", http.StatusUnsupportedMediaType},
+		{"form", "application/x-www-form-urlencoded", "", http.StatusUnsupportedMediaType},
+		{"no content type", "", "", http.StatusUnsupportedMediaType},
+		{"foreign origin", "application/json", "https://evil.example", http.StatusForbidden},
+		{"foreign origin text/plain", "text/plain", "https://evil.example", http.StatusForbidden},
+		{"same origin", "application/json; charset=utf-8", srv.URL, http.StatusCreated},
+		{"no origin", "application/json", "", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/jobs", strings.NewReader(body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+	if n := len(s.Jobs()); n != 2 {
+		t.Errorf("%d jobs queued, want 2", n)
+	}
+}
+
+func TestCancelRejectsForeignOrigin(t *testing.T) {
+	s, srv := newTestServer(t)
+	j, err := s.Submit(Request{URL: "https://example.com/comic"})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/api/jobs/"+j.ID, nil)
+	req.Header.Set("Origin", "https://evil.example")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	if j, _ := s.Job(j.ID); j.State != StateQueued {
+		t.Errorf("job %s, want queued", j.State)
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/reporter"
+)
+
+// State is the state of a job.
+type State string
+
+// Job states.
+const (
+	// StateQueued jobs are waiting for a worker
+	StateQueued State = "queued"
+	// StateRunning jobs are being downloaded
+	StateRunning State = "running"
+	// StateDone jobs completed successfully
+	StateDone State = "done"
+	// StateFailed jobs completed with errors
+	StateFailed State = "failed"
+	// StateCanceled jobs were canceled before completing
+	StateCanceled State = "canceled"
+)
+
+// Finished reports whether the state is final.
+func (s State) Finished() bool {
+	return s == StateDone || s == StateFailed || s == StateCanceled
+}
+
+// Request is a download job request.
+type Request struct {
+	// URL is the comic index URL
+	URL string `json:"url"`
+	// Range is the chapter range (see ranges.Parse); empty downloads every chapter
+	Range string `json:"range,omitempty"`
+	// Profile is the configuration profile; empty uses the server profile
+	Profile string `json:"profile,omitempty"`
+	// Options are flag values keyed by long flag name (e.g. "format", "bundle", "language")
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// Job is a queued download job.
+type Job struct {
+	// ID identifies the job
+	ID string `json:"id"`
+	// Request is the job request
+	Request
+	// State is the job state
+	State State `json:"state"`
+	// Error is the error message of failed jobs
+	Error string `json:"error,omitempty"`
+	// Paths are the files written so far
+	Paths []string `json:"paths,omitempty"`
+	// Progress holds the latest progress event of every chapter, keyed by chapter
+	Progress map[string]reporter.Event `json:"progress,omitempty"`
+	// Created is the submission time
+	Created time.Time `json:"created"`
+	// Started is the time the job last started running
+	Started *time.Time `json:"started,omitempty"`
+	// Finished is the time the job reached a final state
+	Finished *time.Time `json:"finished,omitempty"`
+
+	cancel   context.CancelFunc
+	canceled bool
+	subs     map[chan Message]struct{}
+}
+
+// snapshot returns a copy of the job safe to use without the server lock.
+func (j *Job) snapshot() Job {
+	c := Job{
+		ID:       j.ID,
+		Request:  j.Request,
+		State:    j.State,
+		Error:    j.Error,
+		Paths:    append([]string(nil), j.Paths...),
+		Created:  j.Created,
+		Started:  j.Started,
+		Finished: j.Finished,
+	}
+	if len(j.Progress) > 0 {
+		c.Progress = make(map[string]reporter.Event, len(j.Progress))
+		for k, v := range j.Progress {
+			c.Progress[k] = v
+		}
+	}
+	return c
+}
+
+// newID returns a random job ID.
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// store persists the jobs to a JSON file.
+type store struct {
+	path string
+}
+
+// load reads the persisted jobs; a missing file is an empty queue.
+func (s store) load() ([]*Job, error) {
+	if s.path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading queue file: %w", err)
+	}
+	jobs := []*Job{}
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("error parsing queue file %s: %w", s.path, err)
+	}
+	return jobs, nil
+}
+
+// save writes the jobs atomically.
+func (s store) save(jobs []Job) error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
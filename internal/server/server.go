@@ -0,0 +1,356 @@
+// Package server runs download jobs from a persistent queue and exposes them through a REST API
+// and an embedded web UI.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/logger"
+	"github.com/NorkzYT/comic-downloader/internal/reporter"
+)
+
+// RunFunc runs a job request, reporting its progress to rep.
+type RunFunc func(ctx context.Context, req Request, rep reporter.Reporter) error
+
+// Options are the server options.
+type Options struct {
+	// Workers is the number of jobs run concurrently
+	Workers int
+	// QueueFile is where the job queue is persisted; empty keeps it in memory
+	QueueFile string
+	// Token, when set, must be sent as a bearer token with every API request
+	Token string
+	// Run runs a job
+	Run RunFunc
+	// Validate checks a job request before it is queued
+	Validate func(Request) error
+	// Chapters lists the chapters of the comic of a request, for the web UI
+	Chapters func(ctx context.Context, req Request) (Series, error)
+}
+
+// Series is a comic and its chapters, as listed for the web UI.
+type Series struct {
+	// Title is the comic title
+	Title string `json:"title"`
+	// Chapters are the comic chapters sorted by number
+	Chapters []Chapter `json:"chapters"`
+}
+
+// Chapter is a chapter of a Series.
+type Chapter struct {
+	Number   float64 `json:"number"`
+	Title    string  `json:"title"`
+	Volume   string  `json:"volume,omitempty"`
+	Language string  `json:"language,omitempty"`
+	Group    string  `json:"group,omitempty"`
+}
+
+// Message is a job event sent to subscribers.
+type Message struct {
+	// Type is "job" for job state changes and "progress" for chapter progress
+	Type string
+	// Job is the job snapshot of "job" messages
+	Job *Job
+	// Event is the progress event of "progress" messages
+	Event *reporter.Event
+}
+
+// Server runs queued jobs with a pool of workers.
+type Server struct {
+	opts  Options
+	store store
+
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	order []string
+
+	wake chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a server and loads the persisted queue. Jobs that were running when the
+// server stopped are queued again.
+func New(opts Options) (*Server, error) {
+	if opts.Run == nil {
+		return nil, errors.New("missing job runner")
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	s := &Server{
+		opts:  opts,
+		store: store{path: opts.QueueFile},
+		jobs:  map[string]*Job{},
+		wake:  make(chan struct{}, 1),
+	}
+
+	jobs, err := s.store.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range jobs {
+		if j.State == StateRunning {
+			j.State = StateQueued
+			j.Started = nil
+		}
+		s.jobs[j.ID] = j
+		s.order = append(s.order, j.ID)
+	}
+	return s, nil
+}
+
+// Start starts the workers. They stop when ctx is canceled; running jobs are then
+// canceled and queued again.
+func (s *Server) Start(ctx context.Context) {
+	for i := 0; i < s.opts.Workers; i++ {
+		s.wg.Add(1)
+		go s.work(ctx)
+	}
+	s.notify()
+}
+
+// Wait waits for the workers to stop.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// Submit validates and queues a job request.
+func (s *Server) Submit(req Request) (Job, error) {
+	if req.URL == "" {
+		return Job{}, errors.New("missing url")
+	}
+	if s.opts.Validate != nil {
+		if err := s.opts.Validate(req); err != nil {
+			return Job{}, err
+		}
+	}
+
+	s.mu.Lock()
+	j := &Job{
+		ID:      newID(),
+		Request: req,
+		State:   StateQueued,
+		Created: time.Now(),
+	}
+	s.jobs[j.ID] = j
+	s.order = append(s.order, j.ID)
+	snap := j.snapshot()
+	s.persist()
+	s.mu.Unlock()
+
+	logger.Info("Server.Submit: Queued job %s for %s", j.ID, req.URL)
+	s.notify()
+	return snap, nil
+}
+
+// Jobs returns every job in submission order.
+func (s *Server) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, 0, len(s.order))
+	for _, id := range s.order {
+		jobs = append(jobs, s.jobs[id].snapshot())
+	}
+	return jobs
+}
+
+// Job returns the job with the given ID.
+func (s *Server) Job(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return j.snapshot(), true
+}
+
+// ErrNotFound is returned for unknown job IDs.
+var ErrNotFound = errors.New("job not found")
+
+// Cancel cancels a queued or running job. Canceling a finished job is an error.
+func (s *Server) Cancel(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	switch j.State {
+	case StateQueued:
+		s.finish(j, StateCanceled, nil)
+		s.persist()
+	case StateRunning:
+		// The worker marks the job as canceled once the pipeline returns.
+		j.canceled = true
+		j.cancel()
+	default:
+		return j.snapshot(), fmt.Errorf("job is already %s", j.State)
+	}
+	return j.snapshot(), nil
+}
+
+// Subscribe returns a channel receiving the job messages, starting with the current job
+// state, and a function to unsubscribe. The channel is closed when the job finishes.
+func (s *Server) Subscribe(id string) (<-chan Message, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+
+	ch := make(chan Message, 64)
+	snap := j.snapshot()
+	ch <- Message{Type: "job", Job: &snap}
+	if j.State.Finished() {
+		close(ch)
+		return ch, func() {}, nil
+	}
+	if j.subs == nil {
+		j.subs = map[chan Message]struct{}{}
+	}
+	j.subs[ch] = struct{}{}
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := j.subs[ch]; ok {
+			delete(j.subs, ch)
+			close(ch)
+		}
+	}, nil
+}
+
+// notify wakes up an idle worker.
+func (s *Server) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work runs queued jobs until ctx is canceled.
+func (s *Server) work(ctx context.Context) {
+	defer s.wg.Done()
+	for {
+		j, jobCtx := s.next(ctx)
+		if j == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+				continue
+			}
+		}
+		// More jobs may be waiting for another worker.
+		s.notify()
+		s.run(ctx, jobCtx, j)
+	}
+}
+
+// next marks the oldest queued job as running and returns it with its context.
+func (s *Server) next(ctx context.Context) (*Job, context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+	for _, id := range s.order {
+		j := s.jobs[id]
+		if j.State != StateQueued {
+			continue
+		}
+		jobCtx, cancel := context.WithCancel(ctx)
+		now := time.Now()
+		j.State, j.Started, j.cancel = StateRunning, &now, cancel
+		j.Error, j.Paths, j.Progress = "", nil, nil
+		s.broadcast(j, Message{Type: "job", Job: ptr(j.snapshot())})
+		s.persist()
+		return j, jobCtx
+	}
+	return nil, nil
+}
+
+// run runs a job and records its outcome.
+func (s *Server) run(ctx, jobCtx context.Context, j *Job) {
+	logger.Info("Server.run: Starting job %s", j.ID)
+	rep := reporter.NewFunc(func(e reporter.Event) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if e.Type == "saved" {
+			j.Paths = append(j.Paths, e.Path)
+		} else {
+			if j.Progress == nil {
+				j.Progress = map[string]reporter.Event{}
+			}
+			j.Progress[e.Chapter] = e
+		}
+		s.broadcast(j, Message{Type: "progress", Event: &e})
+	})
+	err := s.opts.Run(jobCtx, j.Request, rep)
+	rep.Stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.cancel()
+	switch {
+	case j.canceled:
+		s.finish(j, StateCanceled, nil)
+	case ctx.Err() != nil:
+		// The server is shutting down: run the job again on next start.
+		logger.Info("Server.run: Job %s interrupted, queued again", j.ID)
+		j.State, j.Started = StateQueued, nil
+	case err != nil:
+		s.finish(j, StateFailed, err)
+	default:
+		s.finish(j, StateDone, nil)
+	}
+	s.persist()
+}
+
+// finish moves the job to a final state and closes its subscriptions.
+// It must be called with the lock held.
+func (s *Server) finish(j *Job, state State, err error) {
+	now := time.Now()
+	j.State, j.Finished, j.canceled = state, &now, false
+	if err != nil {
+		j.Error = err.Error()
+	}
+	logger.Info("Server.finish: Job %s %s", j.ID, state)
+	s.broadcast(j, Message{Type: "job", Job: ptr(j.snapshot())})
+	for ch := range j.subs {
+		close(ch)
+	}
+	j.subs = nil
+}
+
+// broadcast sends a message to the job subscribers, dropping it for the slow ones.
+// It must be called with the lock held.
+func (s *Server) broadcast(j *Job, m Message) {
+	for ch := range j.subs {
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+}
+
+// persist saves the queue, logging failures. It must be called with the lock held.
+func (s *Server) persist() {
+	jobs := make([]Job, 0, len(s.order))
+	for _, id := range s.order {
+		jobs = append(jobs, s.jobs[id].snapshot())
+	}
+	if err := s.store.save(jobs); err != nil {
+		logger.Error("Server.persist: Error saving queue: %v", err)
+	}
+}
+
+// ptr returns a pointer to a copy of v.
+func ptr[T any](v T) *T {
+	return &v
+}
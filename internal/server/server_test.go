@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NorkzYT/comic-downloader/internal/reporter"
+)
+
+// waitState waits until the job reaches the state.
+func waitState(t *testing.T, s *Server, id string, state State) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		j, _ := s.Job(id)
+		if j.State == state {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s, want %s", j.State, state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestServerRunsJobs(t *testing.T) {
+	s, err := New(Options{Run: func(ctx context.Context, req Request, rep reporter.Reporter) error {
+		if req.Range == "fail" {
+			return errors.New("no chapters")
+		}
+		rep.Saved("/out/" + req.URL)
+		return nil
+	}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() { cancel(); s.Wait() }()
+	s.Start(ctx)
+
+	ok, _ := s.Submit(Request{URL: "comic"})
+	failed, _ := s.Submit(Request{URL: "other", Range: "fail"})
+	if j := waitState(t, s, ok.ID, StateDone); len(j.Paths) != 1 || j.Paths[0] != "/out/comic" {
+		t.Errorf("job paths %v, want the saved file", j.Paths)
+	}
+	if j := waitState(t, s, failed.ID, StateFailed); j.Error != "no chapters" {
+		t.Errorf("job error %q", j.Error)
+	}
+	if _, err := s.Submit(Request{}); err == nil {
+		t.Error("Submit accepted a request without URL")
+	}
+}
+
+func TestServerCancel(t *testing.T) {
+	started := make(chan struct{})
+	s, _ := New(Options{Run: func(ctx context.Context, req Request, rep reporter.Reporter) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() { cancel(); s.Wait() }()
+	s.Start(ctx)
+
+	running, _ := s.Submit(Request{URL: "running"})
+	queued, _ := s.Submit(Request{URL: "queued"})
+	<-started
+	msgs, unsubscribe, err := s.Subscribe(running.ID)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer unsubscribe()
+
+	if _, err := s.Cancel(queued.ID); err != nil {
+		t.Fatalf("Cancel(queued): %v", err)
+	}
+	if _, err := s.Cancel(running.ID); err != nil {
+		t.Fatalf("Cancel(running): %v", err)
+	}
+	waitState(t, s, running.ID, StateCanceled)
+	waitState(t, s, queued.ID, StateCanceled)
+	if _, err := s.Cancel(running.ID); err == nil {
+		t.Error("canceled a finished job")
+	}
+	if _, err := s.Cancel("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Cancel(unknown) = %v, want ErrNotFound", err)
+	}
+
+	// The subscription ends with the final state.
+	var last Message
+	for m := range msgs {
+		last = m
+	}
+	if last.Type != "job" || last.Job.State != StateCanceled {
+		t.Errorf("last message %+v, want the canceled job", last)
+	}
+}
+
+func TestServerResumesInterruptedJobs(t *testing.T) {
+	queue := filepath.Join(t.TempDir(), "jobs.json")
+	started := make(chan struct{})
+	s, _ := New(Options{QueueFile: queue, Run: func(ctx context.Context, req Request, rep reporter.Reporter) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	j, _ := s.Submit(Request{URL: "comic"})
+	<-started
+	// Shutting down queues the running job again.
+	cancel()
+	s.Wait()
+
+	s, err := New(Options{QueueFile: queue, Run: func(context.Context, Request, reporter.Reporter) error { return nil }})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if j, ok := s.Job(j.ID); !ok || j.State != StateQueued || j.Started != nil {
+		t.Fatalf("reloaded job %+v, want it queued", j)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	defer func() { cancel(); s.Wait() }()
+	s.Start(ctx)
+	waitState(t, s, j.ID, StateDone)
+}
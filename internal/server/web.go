@@ -0,0 +1,18 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed web
+var web embed.FS
+
+// webFS returns the web UI files.
+func webFS() fs.FS {
+	sub, err := fs.Sub(web, "web")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
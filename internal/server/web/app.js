@@ -0,0 +1,226 @@
+"use strict";
+
+// State of the page: the loaded series, the known jobs and their event streams.
+const state = {
+  series: null,
+  jobs: new Map(),
+  streams: new Map(),
+  token: localStorage.getItem("token") || "",
+};
+
+const $ = (id) => document.getElementById(id);
+
+// el creates an element with the given class and text.
+function el(tag, className, text) {
+  const e = document.createElement(tag);
+  if (className) e.className = className;
+  if (text !== undefined) e.textContent = text;
+  return e;
+}
+
+// api calls the REST API, asking for the token when the server requires one.
+async function api(path, options = {}) {
+  const headers = Object.assign({}, options.headers);
+  if (state.token) headers.Authorization = "Bearer " + state.token;
+  const res = await fetch(path, Object.assign({}, options, { headers }));
+  if (res.status === 401) {
+    $("token-button").hidden = false;
+    throw new Error("The server requires a token: use \"Set token\".");
+  }
+  const body = await res.json();
+  if (!res.ok) throw new Error(body.error || res.statusText);
+  return body;
+}
+
+// loadSeries lists the chapters of the series URL.
+async function loadSeries(event) {
+  event.preventDefault();
+  const status = $("series-status");
+  status.className = "status";
+  status.textContent = "Loading chapters…";
+  $("job-form").hidden = true;
+
+  const params = new URLSearchParams({ url: $("url").value });
+  if ($("language").value) params.set("language", $("language").value);
+  try {
+    state.series = await api("/api/chapters?" + params);
+  } catch (err) {
+    status.className = "status error";
+    status.textContent = err.message;
+    return;
+  }
+  status.textContent = state.series.chapters.length + " chapters";
+  renderSeries();
+}
+
+function renderSeries() {
+  $("series-title").textContent = state.series.title;
+  const body = $("chapters");
+  body.replaceChildren();
+  state.series.chapters.forEach((c, i) => {
+    const row = el("tr");
+    const check = el("input");
+    check.type = "checkbox";
+    check.checked = true;
+    check.dataset.index = i;
+    const cell = el("td");
+    cell.append(check);
+    row.append(cell, el("td", "", c.number), el("td", "", c.title), el("td", "", c.volume || ""),
+      el("td", "", c.language || ""), el("td", "", c.group || ""));
+    body.append(row);
+  });
+  $("job-form").hidden = false;
+}
+
+function selectAll(checked) {
+  for (const check of $("chapters").querySelectorAll("input")) check.checked = checked;
+}
+
+// selectedRange builds a range expression from the checked chapters, merging runs of
+// consecutive rows into "begin-end" ranges. It returns "" when every chapter is checked.
+function selectedRange() {
+  const checks = [...$("chapters").querySelectorAll("input")];
+  if (checks.every((c) => c.checked)) return "";
+  const chapters = state.series.chapters;
+  const parts = [];
+  let start = -1;
+  checks.forEach((check, i) => {
+    if (check.checked && start < 0) start = i;
+    const last = i === checks.length - 1;
+    if (start >= 0 && (!check.checked || last)) {
+      const end = check.checked ? i : i - 1;
+      const b = chapters[start].number, e = chapters[end].number;
+      parts.push(b === e ? String(b) : b + "-" + e);
+      start = -1;
+    }
+  });
+  return parts.join(",");
+}
+
+// submitJob queues a download of the selected chapters.
+async function submitJob(event) {
+  event.preventDefault();
+  const range = $("range").value.trim() || selectedRange();
+  if (range === "" && ![...$("chapters").querySelectorAll("input")].some((c) => c.checked)) {
+    alert("No chapters selected.");
+    return;
+  }
+  const options = { format: $("format").value, bundle: $("bundle").checked };
+  if ($("bundle-volume").checked) options["bundle-by"] = "volume";
+  if ($("language").value) options.language = $("language").value;
+  try {
+    const job = await api("/api/jobs", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ url: $("url").value, range, options }),
+    });
+    state.jobs.set(job.id, job);
+    renderJobs();
+    watch(job);
+  } catch (err) {
+    alert(err.message);
+  }
+}
+
+async function cancelJob(id) {
+  try {
+    state.jobs.set(id, await api("/api/jobs/" + id, { method: "DELETE" }));
+    renderJobs();
+  } catch (err) {
+    alert(err.message);
+  }
+}
+
+// refreshJobs polls the job list and streams the progress of unfinished jobs.
+async function refreshJobs() {
+  try {
+    const jobs = await api("/api/jobs");
+    state.jobs = new Map(jobs.map((j) => [j.id, j]));
+    renderJobs();
+    jobs.forEach(watch);
+  } catch (err) {
+    console.error(err);
+  }
+}
+
+// watch opens the event stream of an unfinished job.
+function watch(job) {
+  if (["done", "failed", "canceled"].includes(job.state) || state.streams.has(job.id)) return;
+  const params = state.token ? "?token=" + encodeURIComponent(state.token) : "";
+  const stream = new EventSource("/api/jobs/" + job.id + "/events" + params);
+  state.streams.set(job.id, stream);
+
+  stream.addEventListener("job", (e) => {
+    const j = JSON.parse(e.data);
+    state.jobs.set(j.id, j);
+    renderJobs();
+    if (["done", "failed", "canceled"].includes(j.state)) {
+      stream.close();
+      state.streams.delete(j.id);
+    }
+  });
+  stream.addEventListener("progress", (e) => {
+    const ev = JSON.parse(e.data);
+    const j = state.jobs.get(job.id);
+    if (!j) return;
+    if (ev.type === "saved") {
+      j.paths = (j.paths || []).concat(ev.path);
+    } else {
+      j.progress = Object.assign({}, j.progress, { [ev.chapter]: ev });
+    }
+    renderJobs();
+  });
+  stream.onerror = () => {
+    stream.close();
+    state.streams.delete(job.id);
+  };
+}
+
+function renderJobs() {
+  const list = $("jobs");
+  list.replaceChildren();
+  const jobs = [...state.jobs.values()].reverse();
+  if (jobs.length === 0) list.append(el("p", "status", "No jobs yet."));
+  for (const job of jobs) {
+    const item = el("div", "job");
+    const header = el("div", "job-header");
+    const title = el("div", "job-url", job.url + (job.range ? " [" + job.range + "]" : ""));
+    title.title = job.url;
+    header.append(title, el("span", "state state-" + job.state, job.state));
+    item.append(header);
+
+    for (const [chapter, ev] of Object.entries(job.progress || {})) {
+      const row = el("div", "chapter-progress");
+      const label = ev.type === "error" ? chapter + ": " + ev.error : chapter + (ev.status ? " [" + ev.status + "]" : "");
+      row.append(el("div", ev.type === "error" ? "error" : "", label));
+      const bar = el("progress");
+      bar.max = ev.total || 1;
+      bar.value = ev.type === "done" ? bar.max : ev.value;
+      row.append(bar);
+      item.append(row);
+    }
+
+    if (job.error) item.append(el("div", "error", job.error));
+    for (const path of job.paths || []) item.append(el("div", "paths", path));
+    if (job.state === "queued" || job.state === "running") {
+      const cancel = el("button", "", "Cancel");
+      cancel.type = "button";
+      cancel.onclick = () => cancelJob(job.id);
+      item.append(cancel);
+    }
+    list.append(item);
+  }
+}
+
+$("series-form").addEventListener("submit", loadSeries);
+$("job-form").addEventListener("submit", submitJob);
+$("select-all").onclick = () => selectAll(true);
+$("select-none").onclick = () => selectAll(false);
+$("token-button").onclick = () => {
+  state.token = prompt("API token", state.token) || "";
+  localStorage.setItem("token", state.token);
+  refreshJobs();
+};
+
+refreshJobs();
+setInterval(refreshJobs, 5000);